@@ -21,6 +21,11 @@ import (
 	_ "net/http/pprof"
 
 	_ "github.com/apache/servicecomb-mesher/proxy/resolver/authority"
+	_ "github.com/apache/servicecomb-mesher/proxy/resolver/header"
+	_ "github.com/apache/servicecomb-mesher/proxy/resolver/kubernetes"
+	_ "github.com/apache/servicecomb-mesher/proxy/resolver/pathprefix"
+	_ "github.com/apache/servicecomb-mesher/proxy/resolver/peercert"
+	_ "github.com/apache/servicecomb-mesher/proxy/resolver/trustedheader"
 
 	_ "github.com/apache/servicecomb-mesher/proxy/handler"
 	//protocols
@@ -28,14 +33,23 @@ import (
 	_ "github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/server"
 	_ "github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/simpleRegistry"
 	_ "github.com/apache/servicecomb-mesher/proxy/protocol/grpc"
+	_ "github.com/apache/servicecomb-mesher/proxy/protocol/highway"
 	_ "github.com/apache/servicecomb-mesher/proxy/protocol/http"
+	_ "github.com/apache/servicecomb-mesher/proxy/protocol/redis"
+	_ "github.com/apache/servicecomb-mesher/proxy/protocol/tcp"
+	_ "github.com/apache/servicecomb-mesher/proxy/protocol/thrift"
+	_ "github.com/apache/servicecomb-mesher/proxy/protocol/triple"
 	//ingress rule fetcher
+	_ "github.com/apache/servicecomb-mesher/proxy/ingress/kubernetes"
 	_ "github.com/apache/servicecomb-mesher/proxy/ingress/servicecomb"
 	"github.com/apache/servicecomb-mesher/proxy/server"
 
 	_ "github.com/apache/servicecomb-mesher/proxy/pkg/egress/archaius"
 
+	_ "github.com/apache/servicecomb-mesher/proxy/handler/apikey"
+	_ "github.com/apache/servicecomb-mesher/proxy/handler/jwt"
 	_ "github.com/apache/servicecomb-mesher/proxy/handler/oauth2"
+	_ "github.com/apache/servicecomb-mesher/proxy/handler/oidc"
 
 	//middle wares
 	_ "github.com/go-chassis/go-chassis/v2/middleware/circuit"
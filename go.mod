@@ -1,12 +1,16 @@
 module github.com/apache/servicecomb-mesher
 
 require (
+	github.com/emicklei/go-restful v2.12.0+incompatible
 	github.com/ghodss/yaml v1.0.0
 	github.com/go-chassis/foundation v0.3.0
 	github.com/go-chassis/go-archaius v1.5.1
 	github.com/go-chassis/go-chassis/v2 v2.3.1-0.20210918023417-c31b5972f022
 	github.com/go-chassis/gohessian v0.0.0-20180702061429-e5130c25af55
 	github.com/go-chassis/openlog v1.1.2
+	github.com/go-chassis/sc-client v0.6.1-0.20210917073514-7078937694c7
+	github.com/golang-jwt/jwt v3.2.1+incompatible
+	github.com/gorilla/websocket v1.4.3-0.20210424162022-e8629af678b7
 	github.com/patrickmn/go-cache v2.1.0+incompatible
 	github.com/prometheus/client_golang v1.11.1
 	github.com/prometheus/client_model v0.2.0
@@ -14,9 +18,12 @@ require (
 	github.com/tetratelabs/go2sky v0.1.1-0.20190703154722-1eaab8035277
 	github.com/urfave/cli v1.20.1-0.20181029213200-b67dcf995b6a
 	golang.org/x/net v0.0.0-20201209123823-ac852fbbde11
-	golang.org/x/oauth2 v0.0.0-20190604053449-0f29369cfe45
-	google.golang.org/grpc v1.27.0
+	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
+	google.golang.org/grpc v1.27.1
 	gopkg.in/yaml.v2 v2.4.0
+	k8s.io/api v0.20.0
+	k8s.io/apimachinery v0.20.0
+	k8s.io/client-go v0.20.0
 )
 
 replace (
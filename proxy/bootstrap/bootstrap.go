@@ -25,10 +25,12 @@ import (
 	"github.com/apache/servicecomb-mesher/proxy/cmd"
 	"github.com/apache/servicecomb-mesher/proxy/common"
 	"github.com/apache/servicecomb-mesher/proxy/config"
+	mhandler "github.com/apache/servicecomb-mesher/proxy/handler"
 	"github.com/apache/servicecomb-mesher/proxy/register"
 	"github.com/apache/servicecomb-mesher/proxy/resolver"
 
 	"github.com/apache/servicecomb-mesher/proxy/control"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/accesslog"
 	"github.com/apache/servicecomb-mesher/proxy/pkg/egress"
 	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
 	"github.com/apache/servicecomb-mesher/proxy/pkg/runtime"
@@ -49,6 +51,9 @@ func Start() error {
 	if err := config.Init(); err != nil {
 		return err
 	}
+	if err := mhandler.BuildConfigured(); err != nil {
+		return err
+	}
 	if err := resolver.Init(); err != nil {
 		return err
 	}
@@ -58,6 +63,7 @@ func Start() error {
 	if err := metrics.Init(); err != nil {
 		openlog.Info("metrics init error", openlog.WithTags(openlog.Tags{"err": err}))
 	}
+	accesslog.Init()
 	if err := v1.Init(); err != nil {
 		log.Println("Error occurred in starting admin server", err)
 	}
@@ -18,10 +18,12 @@
 package config
 
 import (
+	"fmt"
 	"github.com/go-chassis/openlog"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/apache/servicecomb-mesher/proxy/cmd"
 	"github.com/apache/servicecomb-mesher/proxy/common"
@@ -132,6 +134,20 @@ func GetConfigContents(key string) (string, error) {
 	return contents, nil
 }
 
+//GetDuration returns duration of key in archaius, def is returned if it is not set or invalid
+func GetDuration(key string, def time.Duration) time.Duration {
+	v := archaius.GetString(key, "")
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		openlog.Warn(fmt.Sprintf("invalid duration [%s] of %s, use default %s", v, key, def))
+		return def
+	}
+	return d
+}
+
 //SetKeyValueByFile reads mesher.yaml and gets key and value
 func SetKeyValueByFile(key, f string) string {
 	var contents string
@@ -31,6 +31,7 @@ import (
 	//	"os"
 	//	"path/filepath"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -38,7 +39,7 @@ func init() {
 }
 func TestGetConfigFilePath(t *testing.T) {
 	var key = "mesher.yaml"
-	archaius.Init(archaius.WithENVSource())
+	archaius.Init(archaius.WithENVSource(), archaius.WithMemorySource())
 	cmd.Init()
 	err := config.Init()
 	assert.Error(t, err)
@@ -88,3 +89,13 @@ func TestGetEgressEndpoints(t *testing.T) {
 
 	assert.Equal(t, "http://istio-pilot.istio-system:15010", c.Egress.Address)
 }
+
+func TestGetDuration(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	defer archaius.Delete("mesher.test.timeout")
+	assert.Equal(t, time.Second, config.GetDuration("mesher.test.timeout", time.Second))
+	archaius.Set("mesher.test.timeout", "1m")
+	assert.Equal(t, time.Minute, config.GetDuration("mesher.test.timeout", time.Second))
+	archaius.Set("mesher.test.timeout", "1x")
+	assert.Equal(t, time.Second, config.GetDuration("mesher.test.timeout", time.Second))
+}
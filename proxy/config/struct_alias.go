@@ -0,0 +1,36 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"github.com/go-chassis/foundation/stringutil"
+	"gopkg.in/yaml.v2"
+)
+
+//Alias maps a host name to a micro service, app and version are optional
+type Alias struct {
+	Service string `yaml:"service"`
+	App     string `yaml:"app"`
+	Version string `yaml:"version"`
+}
+
+//NewAliases create aliases by raw data, the key is host name
+func NewAliases(raw string) (map[string]*Alias, error) {
+	m := make(map[string]*Alias)
+	err := yaml.Unmarshal(stringutil.Str2bytes(raw), &m)
+	return m, err
+}
@@ -0,0 +1,59 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+//APIKey holds settings of apikey handler
+type APIKey struct {
+	//Header holds the key, default is X-API-Key
+	Header string `yaml:"header"`
+	//Query is the query parameter holding the key when header is absent, default is api_key
+	Query string `yaml:"query"`
+	//File stores keys, if it is empty, keys are read from mesher.apikey.keys of config
+	File string `yaml:"file"`
+	//Forward is the header with consumer name sent to backend, default is X-Consumer
+	Forward string `yaml:"forward"`
+}
+
+//APIKeyEntry is a key of a consumer
+type APIKeyEntry struct {
+	//ID identifies the key in admin api
+	ID string `yaml:"id" json:"id"`
+	//Key is the plain key, it is only returned when the key is created
+	Key string `yaml:"key,omitempty" json:"key,omitempty"`
+	//Hash is the hex encoded sha256 of key, it is saved instead of key
+	Hash     string `yaml:"hash,omitempty" json:"-"`
+	Consumer string `yaml:"consumer" json:"consumer"`
+	//Routes are allowed routes of key, empty means all
+	Routes []APIKeyRoute `yaml:"routes,omitempty" json:"routes,omitempty"`
+	Quota  APIKeyQuota   `yaml:"quota,omitempty" json:"quota"`
+}
+
+//APIKeyRoute is a route allowed for a key, empty condition matches all
+type APIKeyRoute struct {
+	Host    string   `yaml:"host,omitempty" json:"host,omitempty"`
+	Methods []string `yaml:"methods,omitempty" json:"methods,omitempty"`
+	//APIPath is a regular expression of request path
+	APIPath string `yaml:"apiPath,omitempty" json:"apiPath,omitempty"`
+}
+
+//APIKeyQuota limits requests per second of a key, 0 means no limit
+type APIKeyQuota struct {
+	Limit int `yaml:"limit,omitempty" json:"limit,omitempty"`
+	//Burst is the max requests allowed at once, default is Limit
+	Burst int `yaml:"burst,omitempty" json:"burst,omitempty"`
+}
@@ -18,6 +18,8 @@
 package config
 
 import (
+	"regexp"
+
 	"github.com/go-chassis/foundation/stringutil"
 	"gopkg.in/yaml.v2"
 )
@@ -25,6 +27,10 @@ import (
 //Mesher is prefix
 type Mesher struct {
 	Ingress Ingress `yaml:"ingress"`
+	TCP     TCP     `yaml:"tcp"`
+	JWT     JWT     `yaml:"jwt"`
+	OIDC    OIDC    `yaml:"oidc"`
+	APIKey  APIKey  `yaml:"apikey"`
 }
 
 //Ingress hold rules and other settings
@@ -56,18 +62,77 @@ func NewRules(raw string) (*IngressRules, error) {
 
 //IngressRule is a ingress rule
 type IngressRule struct {
-	Host    string  `yaml:"host"`
-	Limit   int     `yaml:"limit"`
-	APIPath string  `yaml:"apiPath"`
-	Service Service `yaml:"service"`
+	//Host is exact host or wildcard host like *.example.com
+	Host    string       `yaml:"host"`
+	Methods []string     `yaml:"methods"`
+	Headers []ValueMatch `yaml:"headers"`
+	Query   []ValueMatch `yaml:"query"`
+	//Priority decides the match order, rule with higher priority is matched first
+	Priority int `yaml:"priority"`
+	//Limit is the max requests per second of this rule, 0 means no limit
+	Limit     int       `yaml:"limit"`
+	RateLimit RateLimit `yaml:"rateLimit"`
+	APIPath   string    `yaml:"apiPath"`
+	//GRPC matches gRPC service and method, it is used instead of APIPath in grpc rules
+	GRPC    GRPCMatch `yaml:"grpc"`
+	Service Service   `yaml:"service"`
+	//Services splits traffic to several services by weight, service is ignored if it is set
+	Services []Service `yaml:"services"`
+	Sticky   Sticky    `yaml:"sticky"`
+	//PathRegexp is compiled from the api path when rules are loaded, capture groups of it are used in redirect path
+	PathRegexp *regexp.Regexp `yaml:"-"`
+}
+
+//RateLimit decides how requests share the Limit of a rule
+type RateLimit struct {
+	//Key is global, ip, header, apiKey or consumer, default is global which means all requests share one limit
+	Key string `yaml:"key"`
+	//Header holds the key when key is header or apiKey, default of apiKey is X-API-Key
+	Header string `yaml:"header"`
+	//Burst is the max requests allowed at once, default is Limit
+	Burst int `yaml:"burst"`
+}
+
+//Sticky makes requests with same header or cookie value go to the same service of Services,
+//header is used first if both are set
+type Sticky struct {
+	Header string `yaml:"header"`
+	Cookie string `yaml:"cookie"`
+}
+
+//ValueMatch is condition of a header or query parameter,
+//only one of exact, prefix, regex, present and absent can be set, value must be present if none is set
+type ValueMatch struct {
+	Name    string `yaml:"name"`
+	Exact   string `yaml:"exact"`
+	Prefix  string `yaml:"prefix"`
+	Regex   string `yaml:"regex"`
+	Present bool   `yaml:"present"`
+	Absent  bool   `yaml:"absent"`
+}
+
+//GRPCMatch is condition of gRPC request, service is the full name like helloworld.Greeter,
+//method is the method name, empty method matches all methods of service
+type GRPCMatch struct {
+	Service string `yaml:"service"`
+	Method  string `yaml:"method"`
 }
 
 //Service is upstream info
 type Service struct {
-	Name         string            `yaml:"name"`
-	Tags         map[string]string `yaml:"tags"`
-	RedirectPath string            `yaml:"redirectPath"`
-	Port         Port              `yaml:"port"`
+	Name string            `yaml:"name"`
+	Tags map[string]string `yaml:"tags"`
+	//RedirectPath replaces the request path, it can refer capture groups of APIPath like /users/$1
+	RedirectPath string `yaml:"redirectPath"`
+	//StripPrefix is removed from the path if the path starts with it
+	StripPrefix string `yaml:"stripPrefix"`
+	//AddPrefix is added to the path after rewriting
+	AddPrefix string `yaml:"addPrefix"`
+	//DropQuery removes the query string of request, query string is kept by default
+	DropQuery bool `yaml:"dropQuery"`
+	Port      Port `yaml:"port"`
+	//Weight is the traffic weight among Services of rule
+	Weight int `yaml:"weight"`
 }
 
 //Port is service port information
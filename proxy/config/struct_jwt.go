@@ -0,0 +1,64 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+//JWT holds settings of jwt handler
+type JWT struct {
+	//Issuer is the expected iss claim, empty means iss is not checked
+	Issuer string `yaml:"issuer"`
+	//Audiences are accepted aud claims, token must have one of them, empty means aud is not checked
+	Audiences []string `yaml:"audiences"`
+	//JWKS is a file path or http url of json web key set
+	JWKS string `yaml:"jwks"`
+	//JWKSCacheTime is how long keys fetched from JWKS url are used, default is 5m
+	JWKSCacheTime string `yaml:"jwksCacheTime"`
+	//Keys are paths of PEM encoded RSA or ECDSA public keys
+	Keys []string `yaml:"keys"`
+	//Secret is the key of HMAC signed tokens
+	Secret string `yaml:"secret"`
+	//Leeway is the allowed clock skew when checking exp and nbf
+	Leeway string `yaml:"leeway"`
+	//Header holds the bearer token, default is Authorization
+	Header string `yaml:"header"`
+	//Realm is written in WWW-Authenticate header, default is mesher
+	Realm string `yaml:"realm"`
+	//Forward maps claims to headers sent to backend
+	Forward map[string]string `yaml:"forward"`
+	//Policies are checked in order, the first matching one applies
+	Policies []*JWTPolicy `yaml:"policies"`
+}
+
+//JWTPolicy decides claims required by a service or route, empty condition matches all
+type JWTPolicy struct {
+	Service string   `yaml:"service"`
+	Host    string   `yaml:"host"`
+	Methods []string `yaml:"methods"`
+	//APIPath is a regular expression of request path
+	APIPath string `yaml:"apiPath"`
+	//Anonymous allows requests without token, a token is still validated if it is present
+	Anonymous bool         `yaml:"anonymous"`
+	Claims    []ClaimMatch `yaml:"claims"`
+}
+
+//ClaimMatch requires a claim, if values are set, the claim must be one of them,
+//for array claims like roles, one of its elements must be in values,
+//space separated claims like scope are treated as arrays
+type ClaimMatch struct {
+	Name   string   `yaml:"name"`
+	Values []string `yaml:"values"`
+}
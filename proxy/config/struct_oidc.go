@@ -0,0 +1,57 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+//OIDC holds settings of oidc handler, which logs in browser users at the edge
+type OIDC struct {
+	//Issuer is the url of identity provider, endpoints are discovered from it
+	Issuer       string `yaml:"issuer"`
+	ClientID     string `yaml:"clientID"`
+	ClientSecret string `yaml:"clientSecret"`
+	//RedirectURL is registered in identity provider, its path is handled by mesher
+	RedirectURL string   `yaml:"redirectURL"`
+	Scopes      []string `yaml:"scopes"`
+	//AuthURL, TokenURL, JWKS and EndSessionURL override discovered endpoints
+	AuthURL       string `yaml:"authURL"`
+	TokenURL      string `yaml:"tokenURL"`
+	JWKS          string `yaml:"jwks"`
+	EndSessionURL string `yaml:"endSessionURL"`
+	//LogoutPath clears session, default is /oauth2/logout
+	LogoutPath string `yaml:"logoutPath"`
+	//PostLogoutRedirectURL is where user goes after logout
+	PostLogoutRedirectURL string `yaml:"postLogoutRedirectURL"`
+	//PublicPaths are regular expressions of paths which do not need login
+	PublicPaths []string `yaml:"publicPaths"`
+	//Forward maps claims of id token to headers sent to backend
+	Forward map[string]string `yaml:"forward"`
+	//ForwardAccessToken sends access token to backend in Authorization header
+	ForwardAccessToken bool `yaml:"forwardAccessToken"`
+	//Leeway is the allowed clock skew when checking id token
+	Leeway string     `yaml:"leeway"`
+	Cookie OIDCCookie `yaml:"cookie"`
+}
+
+//OIDCCookie is the session cookie, it is encrypted by secret
+type OIDCCookie struct {
+	//Name is the cookie name, default is mesher_session
+	Name   string `yaml:"name"`
+	Secret string `yaml:"secret"`
+	Domain string `yaml:"domain"`
+	//MaxAge is the longest time of a session, even if tokens are refreshed, default is 24h
+	MaxAge string `yaml:"maxAge"`
+}
@@ -0,0 +1,190 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+//Package apikey authenticates consumers by api keys at the edge,
+//the consumer of key is used in rate limit, metrics and access log of gateways
+package apikey
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	mhandler "github.com/apache/servicecomb-mesher/proxy/handler"
+	"github.com/apache/servicecomb-mesher/proxy/ingress"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/openlog"
+)
+
+//Name is the name of handler
+const Name = "apikey"
+
+//default values of config
+const (
+	DefaultQuery   = "api_key"
+	DefaultForward = "X-Consumer"
+)
+
+//errors of authentication
+var (
+	ErrMissingKey = errors.New("api key is missing")
+	ErrInvalidKey = errors.New("invalid api key")
+	ErrForbidden  = errors.New("route is not allowed for api key")
+	ErrQuota      = errors.New("quota of api key is exceeded")
+)
+
+var (
+	defaultStore *Store
+	storeErr     error
+	storeOnce    sync.Once
+)
+
+//DefaultStore returns the store of mesher config, it is shared by handler and admin api
+func DefaultStore() (*Store, error) {
+	storeOnce.Do(func() {
+		var c config.APIKey
+		if mc := config.GetConfig(); mc != nil {
+			c = mc.Mesher.APIKey
+		}
+		defaultStore, storeErr = NewStore(c.File)
+	})
+	return defaultStore, storeErr
+}
+
+//Handler rejects requests without valid key with 401, requests out of allowed routes with 403,
+//and requests beyond quota of key with 429
+type Handler struct {
+	store   *Store
+	header  string
+	query   string
+	forward string
+	limiter *ingress.RateLimiter
+}
+
+//New returns an apikey handler of config and store
+func New(c *config.APIKey, s *Store) *Handler {
+	h := &Handler{
+		store:   s,
+		header:  c.Header,
+		query:   c.Query,
+		forward: c.Forward,
+		limiter: ingress.NewRateLimiter(),
+	}
+	if h.header == "" {
+		h.header = ingress.DefaultAPIKeyHeader
+	}
+	if h.query == "" {
+		h.query = DefaultQuery
+	}
+	if h.forward == "" {
+		h.forward = DefaultForward
+	}
+	return h
+}
+
+//Handle authenticates consumer and enforces quota of key
+func (h *Handler) Handle(chain *handler.Chain, inv *invocation.Invocation, cb invocation.ResponseCallBack) {
+	req, ok := inv.Args.(*http.Request)
+	if !ok {
+		chain.Next(inv, cb)
+		return
+	}
+	protocol.DelHeader(inv, req, h.forward)
+	plain := req.Header.Get(h.header)
+	if plain == "" {
+		plain = req.URL.Query().Get(h.query)
+	}
+	if plain == "" {
+		cb(reply(http.StatusUnauthorized, nil, ErrMissingKey))
+		return
+	}
+	k, ok := h.store.lookup(plain)
+	if !ok {
+		cb(reply(http.StatusUnauthorized, nil, ErrInvalidKey))
+		return
+	}
+	if !k.allows(req) {
+		cb(reply(http.StatusForbidden, nil, ErrForbidden))
+		return
+	}
+	var header http.Header
+	if k.Quota.Limit > 0 {
+		burst := k.Quota.Burst
+		if burst == 0 {
+			burst = k.Quota.Limit
+		}
+		q := h.limiter.Take(k.ID, k.Quota.Limit, burst)
+		header = http.Header{}
+		ingress.SetQuotaHeaders(header, q)
+		if !q.Allowed {
+			metrics.RecordRateLimited(protocolOf(req), map[string]string{metrics.LHost: req.Host, metrics.LConsumer: k.Consumer})
+			cb(reply(http.StatusTooManyRequests, header, ErrQuota))
+			return
+		}
+	}
+	inv.Ctx = ingress.WithConsumer(inv.Ctx, k.Consumer)
+	protocol.SetHeader(inv, req, h.forward, k.Consumer)
+	if header == nil {
+		chain.Next(inv, cb)
+		return
+	}
+	chain.Next(inv, func(r *invocation.Response) {
+		protocol.AddResponseHeader(r, header)
+		cb(r)
+	})
+}
+
+//Name returns handler name
+func (h *Handler) Name() string {
+	return Name
+}
+
+//protocolOf tells whether request is grpc, grpc metadata are headers of request
+func protocolOf(req *http.Request) string {
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/grpc") {
+		return "grpc"
+	}
+	return "http"
+}
+
+func reply(status int, header http.Header, err error) *invocation.Response {
+	return &invocation.Response{Status: status, Err: &protocol.HTTPError{Status: status, Header: header, Message: err.Error()}}
+}
+
+func newHandler() (handler.Handler, error) {
+	var c config.APIKey
+	if mc := config.GetConfig(); mc != nil {
+		c = mc.Mesher.APIKey
+	}
+	s, err := DefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	return New(&c, s), nil
+}
+
+func init() {
+	err := mhandler.RegisterConfigured(Name, newHandler)
+	if err != nil {
+		openlog.Error("register handler error: " + err.Error())
+	}
+}
@@ -0,0 +1,123 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apikey
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/handler/handlertest"
+	"github.com/apache/servicecomb-mesher/proxy/ingress"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	chassiscommon "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/stretchr/testify/assert"
+)
+
+//memoryBackend keeps keys in memory
+type memoryBackend struct {
+	raw string
+}
+
+func (b *memoryBackend) load() (string, error) {
+	return b.raw, nil
+}
+
+func (b *memoryBackend) save(raw string) error {
+	b.raw = raw
+	return nil
+}
+
+func newTestStore(t *testing.T, raw string) *Store {
+	s := &Store{backend: &memoryBackend{raw: raw}}
+	assert.NoError(t, s.Reload())
+	return s
+}
+
+func TestHandler_Handle(t *testing.T) {
+	assert.NoError(t, metrics.Init())
+	h := New(&config.APIKey{}, newTestStore(t, `
+- id: k1
+  key: secret1
+  consumer: shop
+- id: k2
+  key: secret2
+  consumer: blog
+  routes:
+    - host: "*.blog.com"
+      methods: [GET]
+      apiPath: ^/posts
+  quota:
+    limit: 1
+    burst: 2
+`))
+	send := func(method, u, key string) (*invocation.Response, *invocation.Invocation) {
+		return handlertest.Call(h, handlertest.NewRequest(method, u, map[string]string{"X-API-Key": key}), "")
+	}
+
+	t.Run("missing key", func(t *testing.T) {
+		r, _ := send(http.MethodGet, "http://shop.com/orders", "")
+		assert.Equal(t, http.StatusUnauthorized, r.Status)
+		assert.Equal(t, ErrMissingKey.Error(), r.Err.Error())
+	})
+	t.Run("invalid key", func(t *testing.T) {
+		r, _ := send(http.MethodGet, "http://shop.com/orders", "secret3")
+		assert.Equal(t, http.StatusUnauthorized, r.Status)
+		assert.Equal(t, ErrInvalidKey.Error(), r.Err.Error())
+	})
+	t.Run("consumer is passed", func(t *testing.T) {
+		req := handlertest.NewRequest(http.MethodGet, "http://shop.com/orders", map[string]string{"X-API-Key": "secret1"})
+		req.Header.Set("X-Consumer", "admin")
+		r, inv := handlertest.Call(h, req, "")
+		assert.NoError(t, r.Err)
+		assert.Nil(t, r.Result)
+		assert.Equal(t, "shop", ingress.Consumer(inv.Ctx))
+		assert.Equal(t, "shop", req.Header.Get("X-Consumer"))
+		assert.Equal(t, "shop", chassiscommon.FromContext(inv.Ctx)["X-Consumer"])
+	})
+	t.Run("key in query", func(t *testing.T) {
+		r, inv := send(http.MethodGet, "http://shop.com/orders?api_key=secret1", "")
+		assert.NoError(t, r.Err)
+		assert.Equal(t, "shop", ingress.Consumer(inv.Ctx))
+	})
+	t.Run("route is not allowed", func(t *testing.T) {
+		r, _ := send(http.MethodPost, "http://www.blog.com/posts", "secret2")
+		assert.Equal(t, http.StatusForbidden, r.Status)
+		r, _ = send(http.MethodGet, "http://www.blog.com/users", "secret2")
+		assert.Equal(t, http.StatusForbidden, r.Status)
+		r, _ = send(http.MethodGet, "http://shop.com/posts", "secret2")
+		assert.Equal(t, http.StatusForbidden, r.Status)
+	})
+	t.Run("quota", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			r, _ := send(http.MethodGet, "http://www.blog.com/posts", "secret2")
+			assert.NoError(t, r.Err)
+			assert.Equal(t, "1", r.Result.(http.Header).Get("X-RateLimit-Limit"))
+		}
+		r, _ := send(http.MethodGet, "http://www.blog.com/posts", "secret2")
+		assert.Equal(t, http.StatusTooManyRequests, r.Status)
+		assert.Equal(t, "1", r.Err.(*protocol.HTTPError).Header.Get("Retry-After"))
+	})
+	t.Run("revoked key", func(t *testing.T) {
+		assert.NoError(t, h.store.Revoke("k1"))
+		r, _ := send(http.MethodGet, "http://shop.com/orders", "secret1")
+		assert.Equal(t, http.StatusUnauthorized, r.Status)
+	})
+}
@@ -0,0 +1,339 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/ingress"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-archaius/event"
+	"github.com/go-chassis/openlog"
+	"gopkg.in/yaml.v2"
+)
+
+//KeyAPIKeys is the config key of keys, it is used if no file is set
+const KeyAPIKeys = "mesher.apikey.keys"
+
+//errors of store
+var (
+	ErrNotFound = errors.New("api key not found")
+	ErrConflict = errors.New("api key already exists")
+)
+
+//Store holds api keys of consumers, keys are indexed by hash, so plain keys are never saved
+type Store struct {
+	backend backend
+
+	mu      sync.RWMutex
+	entries []config.APIKeyEntry
+	byHash  map[string]*key
+}
+
+//key is a compiled entry
+type key struct {
+	config.APIKeyEntry
+	routes []route
+}
+
+type route struct {
+	host    string
+	methods map[string]bool
+	path    *regexp.Regexp
+}
+
+//backend loads and saves raw keys in yaml
+type backend interface {
+	load() (string, error)
+	save(raw string) error
+}
+
+//NewStore returns a store of file, or of config if file is empty
+func NewStore(file string) (*Store, error) {
+	s := &Store{}
+	if file != "" {
+		s.backend = &fileBackend{path: file}
+	} else {
+		s.backend = archaiusBackend{}
+		if err := archaius.RegisterListener(&listener{store: s}, KeyAPIKeys); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+//Reload reads keys from backend, old keys are kept if new ones are invalid
+func (s *Store) Reload() error {
+	raw, err := s.backend.load()
+	if err != nil {
+		return err
+	}
+	var entries []config.APIKeyEntry
+	if err := yaml.Unmarshal([]byte(raw), &entries); err != nil {
+		return err
+	}
+	byHash, err := index(entries)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.entries, s.byHash = entries, byHash
+	s.mu.Unlock()
+	return nil
+}
+
+//lookup returns the key of plain key
+func (s *Store) lookup(plain string) (*key, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	k, ok := s.byHash[Hash(plain)]
+	return k, ok
+}
+
+//List returns all keys without plain keys
+func (s *Store) List() []config.APIKeyEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]config.APIKeyEntry, 0, len(s.entries))
+	for _, e := range s.entries {
+		e.Key = ""
+		result = append(result, e)
+	}
+	return result
+}
+
+//Create saves a key, id and key are generated if they are empty,
+//the returned entry holds the plain key, it can not be read again
+func (s *Store) Create(e config.APIKeyEntry) (config.APIKeyEntry, error) {
+	if e.Consumer == "" {
+		return e, errors.New("consumer is required")
+	}
+	if e.ID == "" {
+		e.ID = random(8)
+	}
+	if e.Key == "" {
+		e.Key = random(32)
+	}
+	plain := e.Key
+	e.Key, e.Hash = "", Hash(plain)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, old := range s.entries {
+		if old.ID == e.ID || entryHash(old) == e.Hash {
+			return e, ErrConflict
+		}
+	}
+	if err := s.save(append(append([]config.APIKeyEntry{}, s.entries...), e)); err != nil {
+		return e, err
+	}
+	e.Key = plain
+	return e, nil
+}
+
+//Revoke removes a key
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := make([]config.APIKeyEntry, 0, len(s.entries))
+	for _, e := range s.entries {
+		if e.ID != id {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == len(s.entries) {
+		return ErrNotFound
+	}
+	return s.save(entries)
+}
+
+//save writes entries to backend, plain keys loaded from backend are saved as hash
+func (s *Store) save(entries []config.APIKeyEntry) error {
+	for i := range entries {
+		entries[i].Hash, entries[i].Key = entryHash(entries[i]), ""
+	}
+	byHash, err := index(entries)
+	if err != nil {
+		return err
+	}
+	raw, err := yaml.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err := s.backend.save(string(raw)); err != nil {
+		return err
+	}
+	s.entries, s.byHash = entries, byHash
+	return nil
+}
+
+//index compiles entries and indexes them by hash
+func index(entries []config.APIKeyEntry) (map[string]*key, error) {
+	byHash := make(map[string]*key, len(entries))
+	ids := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		if e.ID == "" || e.Consumer == "" {
+			return nil, errors.New("id and consumer of api key are required")
+		}
+		if ids[e.ID] {
+			return nil, fmt.Errorf("duplicated api key id [%s]", e.ID)
+		}
+		ids[e.ID] = true
+		h := entryHash(e)
+		if h == "" {
+			return nil, fmt.Errorf("api key [%s] has neither key nor hash", e.ID)
+		}
+		if _, ok := byHash[h]; ok {
+			return nil, fmt.Errorf("api key [%s] is duplicated", e.ID)
+		}
+		if e.Quota.Limit < 0 || e.Quota.Burst < 0 {
+			return nil, fmt.Errorf("api key [%s] has negative quota", e.ID)
+		}
+		k := &key{APIKeyEntry: e}
+		for _, r := range e.Routes {
+			cr := route{host: r.Host}
+			if len(r.Methods) != 0 {
+				cr.methods = make(map[string]bool, len(r.Methods))
+				for _, m := range r.Methods {
+					cr.methods[strings.ToUpper(m)] = true
+				}
+			}
+			if r.APIPath != "" {
+				re, err := regexp.Compile(r.APIPath)
+				if err != nil {
+					return nil, fmt.Errorf("api key [%s] has invalid apiPath: %w", e.ID, err)
+				}
+				cr.path = re
+			}
+			k.routes = append(k.routes, cr)
+		}
+		byHash[h] = k
+	}
+	return byHash, nil
+}
+
+//allows tells whether request is in routes of key
+func (k *key) allows(req *http.Request) bool {
+	if len(k.routes) == 0 {
+		return true
+	}
+	for _, r := range k.routes {
+		if r.host != "" && !ingress.MatchHost(r.host, req.Host) {
+			continue
+		}
+		if r.methods != nil && !r.methods[req.Method] {
+			continue
+		}
+		if r.path != nil && !r.path.MatchString(req.URL.Path) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+//Hash returns hex encoded sha256 of plain key
+func Hash(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+func entryHash(e config.APIKeyEntry) string {
+	if e.Key != "" {
+		return Hash(e.Key)
+	}
+	return strings.ToLower(e.Hash)
+}
+
+func random(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+//fileBackend saves keys in a yaml file, it is replaced at once when keys change
+type fileBackend struct {
+	path string
+}
+
+func (b *fileBackend) load() (string, error) {
+	raw, err := ioutil.ReadFile(b.path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(raw), err
+}
+
+func (b *fileBackend) save(raw string) error {
+	f, err := ioutil.TempFile(filepath.Dir(b.path), filepath.Base(b.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(raw); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), b.path)
+}
+
+//archaiusBackend reads keys from config, keys changed by admin api are kept in memory source,
+//they are lost after restart unless config center is updated as well
+type archaiusBackend struct{}
+
+func (archaiusBackend) load() (string, error) {
+	return archaius.GetString(KeyAPIKeys, ""), nil
+}
+
+func (archaiusBackend) save(raw string) error {
+	return archaius.Set(KeyAPIKeys, raw)
+}
+
+//listener reloads keys when config changes
+type listener struct {
+	store *Store
+}
+
+//Event reloads keys
+func (l *listener) Event(e *event.Event) {
+	if err := l.store.Reload(); err != nil {
+		openlog.Error("invalid api keys, old keys are used: " + err.Error())
+		return
+	}
+	openlog.Info("api keys are updated", openlog.WithTags(openlog.Tags{"event": e.EventType}))
+}
@@ -0,0 +1,136 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apikey
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/go-chassis/go-archaius"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apikey")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "keys.yaml")
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`
+- id: k1
+  key: secret1
+  consumer: shop
+`), 0600))
+	s, err := NewStore(file)
+	assert.NoError(t, err)
+	k, ok := s.lookup("secret1")
+	assert.True(t, ok)
+	assert.Equal(t, "shop", k.Consumer)
+	assert.Equal(t, []config.APIKeyEntry{{ID: "k1", Consumer: "shop"}}, s.List())
+
+	created, err := s.Create(config.APIKeyEntry{Consumer: "blog", Quota: config.APIKeyQuota{Limit: 10}})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.NotEmpty(t, created.Key)
+	_, ok = s.lookup(created.Key)
+	assert.True(t, ok)
+	t.Run("plain keys are not saved", func(t *testing.T) {
+		raw, err := ioutil.ReadFile(file)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(raw), "secret1")
+		assert.NotContains(t, string(raw), created.Key)
+		assert.Contains(t, string(raw), Hash("secret1"))
+	})
+	t.Run("reload", func(t *testing.T) {
+		reloaded, err := NewStore(file)
+		assert.NoError(t, err)
+		assert.Equal(t, s.List(), reloaded.List())
+		_, ok := reloaded.lookup("secret1")
+		assert.True(t, ok)
+	})
+	t.Run("conflict", func(t *testing.T) {
+		_, err := s.Create(config.APIKeyEntry{ID: "k1", Consumer: "shop"})
+		assert.Equal(t, ErrConflict, err)
+		_, err = s.Create(config.APIKeyEntry{Key: "secret1", Consumer: "shop"})
+		assert.Equal(t, ErrConflict, err)
+		_, err = s.Create(config.APIKeyEntry{})
+		assert.Error(t, err)
+	})
+	t.Run("invalid route", func(t *testing.T) {
+		_, err := s.Create(config.APIKeyEntry{Consumer: "shop", Routes: []config.APIKeyRoute{{APIPath: "("}}})
+		assert.Error(t, err)
+		assert.Len(t, s.List(), 2)
+	})
+	t.Run("revoke", func(t *testing.T) {
+		assert.NoError(t, s.Revoke("k1"))
+		_, ok := s.lookup("secret1")
+		assert.False(t, ok)
+		assert.Equal(t, ErrNotFound, s.Revoke("k1"))
+		assert.Len(t, s.List(), 1)
+	})
+}
+
+func TestStore_Archaius(t *testing.T) {
+	assert.NoError(t, archaius.Init(archaius.WithMemorySource()))
+	assert.NoError(t, archaius.Set(KeyAPIKeys, `
+- id: k1
+  key: secret1
+  consumer: shop
+`))
+	s, err := NewStore("")
+	assert.NoError(t, err)
+	_, ok := s.lookup("secret1")
+	assert.True(t, ok)
+
+	assert.NoError(t, archaius.Set(KeyAPIKeys, `
+- id: k2
+  key: secret2
+  consumer: shop
+`))
+	assert.Eventually(t, func() bool {
+		_, ok := s.lookup("secret2")
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	//invalid keys are refused
+	assert.NoError(t, archaius.Set(KeyAPIKeys, `
+- id: k3
+  consumer: shop
+`))
+	time.Sleep(100 * time.Millisecond)
+	_, ok = s.lookup("secret2")
+	assert.True(t, ok)
+
+	created, err := s.Create(config.APIKeyEntry{Consumer: "blog"})
+	assert.NoError(t, err)
+	assert.Contains(t, archaius.GetString(KeyAPIKeys, ""), Hash(created.Key))
+}
+
+func TestIndex(t *testing.T) {
+	_, err := index([]config.APIKeyEntry{{ID: "k1", Key: "a", Consumer: "shop"}, {ID: "k1", Key: "b", Consumer: "shop"}})
+	assert.Error(t, err)
+	_, err = index([]config.APIKeyEntry{{ID: "k1", Key: "a", Consumer: "shop"}, {ID: "k2", Hash: Hash("a"), Consumer: "shop"}})
+	assert.Error(t, err)
+	_, err = index([]config.APIKeyEntry{{ID: "k1", Key: "a"}})
+	assert.Error(t, err)
+	_, err = index([]config.APIKeyEntry{{ID: "k1", Key: "a", Consumer: "shop", Quota: config.APIKeyQuota{Limit: -1}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+)
+
+//Builder builds a handler of mesher config
+type Builder func() (handler.Handler, error)
+
+var (
+	mu       sync.RWMutex
+	builders = make(map[string]Builder)
+	//pending are the handlers created by chassis in chains and not built yet
+	pending = make(map[string]bool)
+	built   = make(map[string]handler.Handler)
+)
+
+//RegisterConfigured registers a handler which needs mesher config.
+//Chassis creates handler chains before mesher config is loaded,
+//so the handler is built by BuildConfigured, and startup fails if it is in a chain and its config is invalid
+func RegisterConfigured(name string, b Builder) error {
+	mu.Lock()
+	builders[name] = b
+	mu.Unlock()
+	return handler.RegisterHandler(name, func() handler.Handler {
+		mu.Lock()
+		defer mu.Unlock()
+		pending[name] = true
+		return &configured{name: name}
+	})
+}
+
+//BuildConfigured builds handlers registered by RegisterConfigured which are created in chains since last build,
+//it is called after mesher config is loaded
+func BuildConfigured() error {
+	mu.Lock()
+	defer mu.Unlock()
+	names := make([]string, 0, len(pending))
+	for name := range pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		h, err := builders[name]()
+		if err != nil {
+			return fmt.Errorf("invalid config of handler [%s]: %w", name, err)
+		}
+		built[name] = h
+		delete(pending, name)
+	}
+	return nil
+}
+
+//configured is the handler in chains, it serves requests with the handler built of mesher config
+type configured struct {
+	name string
+}
+
+//Handle serves request with the built handler, request is rejected if it is not built yet
+func (c *configured) Handle(chain *handler.Chain, inv *invocation.Invocation, cb invocation.ResponseCallBack) {
+	mu.RLock()
+	h := built[c.name]
+	mu.RUnlock()
+	if h == nil {
+		cb(&invocation.Response{Status: http.StatusServiceUnavailable, Err: &protocol.HTTPError{
+			Status: http.StatusServiceUnavailable, Message: http.StatusText(http.StatusServiceUnavailable)}})
+		return
+	}
+	h.Handle(chain, inv, cb)
+}
+
+//Name returns handler name
+func (c *configured) Name() string {
+	return c.name
+}
@@ -0,0 +1,67 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handler_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	mhandler "github.com/apache/servicecomb-mesher/proxy/handler"
+	"github.com/apache/servicecomb-mesher/proxy/handler/handlertest"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/stretchr/testify/assert"
+)
+
+type okHandler struct{}
+
+func (okHandler) Handle(chain *handler.Chain, inv *invocation.Invocation, cb invocation.ResponseCallBack) {
+	cb(&invocation.Response{Status: http.StatusOK})
+}
+
+func (okHandler) Name() string {
+	return "ok"
+}
+
+func TestRegisterConfigured(t *testing.T) {
+	configErr := errors.New("secret is missing")
+	assert.NoError(t, mhandler.RegisterConfigured("configured-test", func() (handler.Handler, error) {
+		if configErr != nil {
+			return nil, configErr
+		}
+		return okHandler{}, nil
+	}))
+	//not in any chain, it is not built
+	assert.NoError(t, mhandler.BuildConfigured())
+
+	h, err := handler.CreateHandler("configured-test")
+	assert.NoError(t, err)
+	assert.Equal(t, "configured-test", h.Name())
+	resp, _ := handlertest.Call(h, handlertest.NewRequest(http.MethodGet, "http://127.0.0.1/", nil), "order")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Status)
+
+	err = mhandler.BuildConfigured()
+	assert.True(t, errors.Is(err, configErr))
+	assert.Contains(t, err.Error(), "configured-test")
+
+	configErr = nil
+	assert.NoError(t, mhandler.BuildConfigured())
+	resp, _ = handlertest.Call(h, handlertest.NewRequest(http.MethodGet, "http://127.0.0.1/", nil), "order")
+	assert.Equal(t, http.StatusOK, resp.Status)
+}
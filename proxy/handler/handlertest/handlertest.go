@@ -0,0 +1,57 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+//Package handlertest provides utilities to test handlers of http requests, like auth handlers
+package handlertest
+
+import (
+	"net/http"
+
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+)
+
+//NewRequest returns a request of method to url with headers, headers with empty value are not set
+func NewRequest(method, url string, header map[string]string) *http.Request {
+	req, _ := http.NewRequest(method, url, nil)
+	for k, v := range header {
+		if v != "" {
+			req.Header.Set(k, v)
+		}
+	}
+	return req
+}
+
+//Call runs handler h with request of service like gateways do, headers of request are also in invocation context.
+//It returns the response to callback, which has no error if request is passed to next handler, and the invocation
+func Call(h handler.Handler, req *http.Request, service string) (*invocation.Response, *invocation.Invocation) {
+	c := &handler.Chain{}
+	c.AddHandler(h)
+	headers := map[string]string{}
+	for k := range req.Header {
+		headers[k] = req.Header.Get(k)
+	}
+	inv := invocation.New(common.NewContext(headers))
+	inv.Args = req
+	inv.MicroServiceName = service
+	var resp *invocation.Response
+	c.Next(inv, func(r *invocation.Response) {
+		resp = r
+	})
+	return resp, inv
+}
@@ -0,0 +1,404 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+//Package jwt validates bearer tokens of requests, and forwards claims to backend
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	mhandler "github.com/apache/servicecomb-mesher/proxy/handler"
+	"github.com/apache/servicecomb-mesher/proxy/ingress"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	chassiscommon "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/openlog"
+	"github.com/golang-jwt/jwt"
+)
+
+//Name is the name of handler
+const Name = "jwt"
+
+//default values of config
+const (
+	DefaultHeader = "Authorization"
+	DefaultRealm  = "mesher"
+)
+
+//errors of token validation
+var (
+	ErrMissingToken  = errors.New("token is missing")
+	ErrUnknownKey    = errors.New("no key to verify token")
+	ErrExpired       = errors.New("token is expired")
+	ErrNotValidYet   = errors.New("token is not valid yet")
+	ErrIssuer        = errors.New("token issuer is not accepted")
+	ErrAudience      = errors.New("token audience is not accepted")
+	ErrNotConfigured = errors.New("jwt handler is not configured")
+)
+
+var validMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512", "HS256", "HS384", "HS512"}
+
+//Handler validates token in provider chain, requests with invalid token get 401,
+//and requests without required claims get 403
+type Handler struct {
+	cfg      *config.JWT
+	keys     *keySet
+	parser   *jwt.Parser
+	leeway   time.Duration
+	header   string
+	realm    string
+	policies []*policy
+	now      func() time.Time
+}
+
+type policy struct {
+	*config.JWTPolicy
+	methods map[string]bool
+	path    *regexp.Regexp
+}
+
+//New returns a jwt handler of config
+func New(c *config.JWT) (*Handler, error) {
+	h := &Handler{
+		cfg:    c,
+		parser: &jwt.Parser{ValidMethods: validMethods, SkipClaimsValidation: true},
+		header: c.Header,
+		realm:  c.Realm,
+		now:    time.Now,
+	}
+	if h.header == "" {
+		h.header = DefaultHeader
+	}
+	if h.realm == "" {
+		h.realm = DefaultRealm
+	}
+	var err error
+	if c.Leeway != "" {
+		if h.leeway, err = time.ParseDuration(c.Leeway); err != nil {
+			return nil, fmt.Errorf("invalid leeway: %w", err)
+		}
+	}
+	var cacheTime time.Duration
+	if c.JWKSCacheTime != "" {
+		if cacheTime, err = time.ParseDuration(c.JWKSCacheTime); err != nil {
+			return nil, fmt.Errorf("invalid jwksCacheTime: %w", err)
+		}
+	}
+	var static []interface{}
+	for _, path := range c.Keys {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		k, err := parsePublicKey(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key [%s]: %w", path, err)
+		}
+		static = append(static, k)
+	}
+	if c.JWKS == "" && len(static) == 0 && c.Secret == "" {
+		return nil, ErrNotConfigured
+	}
+	h.keys = newKeySet(c.JWKS, cacheTime, static, []byte(c.Secret))
+	for _, p := range c.Policies {
+		cp := &policy{JWTPolicy: p}
+		if len(p.Methods) != 0 {
+			cp.methods = make(map[string]bool, len(p.Methods))
+			for _, m := range p.Methods {
+				cp.methods[strings.ToUpper(m)] = true
+			}
+		}
+		if p.APIPath != "" {
+			if cp.path, err = regexp.Compile(p.APIPath); err != nil {
+				return nil, fmt.Errorf("invalid policy apiPath: %w", err)
+			}
+		}
+		h.policies = append(h.policies, cp)
+	}
+	return h, nil
+}
+
+//Handle validates token and forwards claims
+func (h *Handler) Handle(chain *handler.Chain, inv *invocation.Invocation, cb invocation.ResponseCallBack) {
+	req, _ := inv.Args.(*http.Request)
+	for _, name := range h.cfg.Forward {
+		protocol.DelHeader(inv, req, name)
+	}
+	p := h.policy(inv, req)
+	raw := h.token(inv, req)
+	if raw == "" {
+		if p != nil && p.Anonymous {
+			chain.Next(inv, cb)
+			return
+		}
+		cb(h.reject(http.StatusUnauthorized, "", ErrMissingToken))
+		return
+	}
+	claims, err := h.Validate(raw)
+	if err != nil {
+		openlog.Debug("invalid token: " + err.Error())
+		cb(h.reject(http.StatusUnauthorized, "invalid_token", err))
+		return
+	}
+	if p != nil {
+		if err := checkClaims(claims, p.Claims); err != nil {
+			cb(h.reject(http.StatusForbidden, "insufficient_scope", err))
+			return
+		}
+	}
+	for claim, name := range h.cfg.Forward {
+		if v, ok := ClaimHeader(claims, claim); ok {
+			protocol.SetHeader(inv, req, name, v)
+		}
+	}
+	chain.Next(inv, cb)
+}
+
+//Validate verifies signature and time, issuer and audience of token, and returns claims
+func (h *Handler) Validate(raw string) (jwt.MapClaims, error) {
+	unverified, _, err := h.parser.ParseUnverified(raw, jwt.MapClaims{})
+	if err != nil {
+		return nil, err
+	}
+	kid, _ := unverified.Header["kid"].(string)
+	keys := h.keys.candidates(kid, unverified.Method)
+	if len(keys) == 0 {
+		return nil, ErrUnknownKey
+	}
+	for _, k := range keys {
+		key := k
+		token, err := h.parser.Parse(raw, func(*jwt.Token) (interface{}, error) { return key, nil })
+		if err != nil {
+			continue
+		}
+		claims := token.Claims.(jwt.MapClaims)
+		return claims, h.checkStandardClaims(claims)
+	}
+	return nil, jwt.ErrSignatureInvalid
+}
+
+func (h *Handler) checkStandardClaims(claims jwt.MapClaims) error {
+	now := h.now()
+	if exp, ok := numericDate(claims["exp"]); ok && !now.Before(exp.Add(h.leeway)) {
+		return ErrExpired
+	}
+	if nbf, ok := numericDate(claims["nbf"]); ok && now.Add(h.leeway).Before(nbf) {
+		return ErrNotValidYet
+	}
+	if h.cfg.Issuer != "" && claims["iss"] != h.cfg.Issuer {
+		return ErrIssuer
+	}
+	if len(h.cfg.Audiences) != 0 {
+		aud := values(claims["aud"])
+		for _, a := range h.cfg.Audiences {
+			for _, v := range aud {
+				if a == v {
+					return nil
+				}
+			}
+		}
+		return ErrAudience
+	}
+	return nil
+}
+
+//policy returns the first policy matching the request
+func (h *Handler) policy(inv *invocation.Invocation, req *http.Request) *policy {
+	for _, p := range h.policies {
+		if p.Service != "" && p.Service != inv.MicroServiceName {
+			continue
+		}
+		if p.Host == "" && p.methods == nil && p.path == nil {
+			return p
+		}
+		if req == nil {
+			continue
+		}
+		if p.Host != "" && !ingress.MatchHost(p.Host, req.Host) {
+			continue
+		}
+		if p.methods != nil && !p.methods[req.Method] {
+			continue
+		}
+		if p.path != nil && !p.path.MatchString(req.URL.Path) {
+			continue
+		}
+		return p
+	}
+	return nil
+}
+
+//token returns the bearer token in header
+func (h *Handler) token(inv *invocation.Invocation, req *http.Request) string {
+	var v string
+	if req != nil {
+		v = req.Header.Get(h.header)
+	}
+	if v == "" {
+		v = chassiscommon.FromContext(inv.Ctx)[http.CanonicalHeaderKey(h.header)]
+	}
+	if len(v) > 7 && strings.EqualFold(v[:7], "Bearer ") {
+		return strings.TrimSpace(v[7:])
+	}
+	if strings.EqualFold(h.header, DefaultHeader) {
+		//other schemes like basic are not jwt
+		return ""
+	}
+	return strings.TrimSpace(v)
+}
+
+//reject returns response with WWW-Authenticate header described in RFC 6750
+func (h *Handler) reject(status int, code string, err error) *invocation.Response {
+	challenge := fmt.Sprintf(`Bearer realm="%s"`, h.realm)
+	if code != "" {
+		challenge += fmt.Sprintf(`, error="%s", error_description="%s"`, code, strings.Replace(err.Error(), `"`, "'", -1))
+	}
+	return &invocation.Response{Status: status, Err: &protocol.HTTPError{
+		Status:  status,
+		Header:  http.Header{"Www-Authenticate": []string{challenge}},
+		Message: err.Error(),
+	}}
+}
+
+//checkClaims checks required claims
+func checkClaims(claims jwt.MapClaims, required []config.ClaimMatch) error {
+	for _, r := range required {
+		v, ok := lookup(claims, r.Name)
+		if !ok {
+			return fmt.Errorf("claim [%s] is required", r.Name)
+		}
+		if len(r.Values) != 0 && !containsAny(values(v), r.Values) {
+			return fmt.Errorf("claim [%s] is not accepted", r.Name)
+		}
+	}
+	return nil
+}
+
+//lookup returns a claim, nested claim can be referred by path like realm_access.roles
+func lookup(claims map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := claims[name]; ok {
+		return v, true
+	}
+	i := strings.IndexByte(name, '.')
+	if i < 0 {
+		return nil, false
+	}
+	nested, ok := claims[name[:i]].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return lookup(nested, name[i+1:])
+}
+
+//values returns claim as strings, space separated string is split like scope
+func values(v interface{}) []string {
+	switch c := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return strings.Fields(c)
+	case []interface{}:
+		result := make([]string, 0, len(c))
+		for _, e := range c {
+			result = append(result, fmt.Sprint(e))
+		}
+		return result
+	}
+	return []string{fmt.Sprint(v)}
+}
+
+func containsAny(vs, accepted []string) bool {
+	for _, v := range vs {
+		for _, a := range accepted {
+			if v == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//ClaimHeader returns claim as header value, name can be a path of nested claim
+func ClaimHeader(claims map[string]interface{}, name string) (string, bool) {
+	v, ok := lookup(claims, name)
+	if !ok {
+		return "", false
+	}
+	return headerValue(v), true
+}
+
+//headerValue formats claim as header value, arrays are joined by comma, objects are json
+func headerValue(v interface{}) string {
+	switch c := v.(type) {
+	case string:
+		return c
+	case []interface{}:
+		return strings.Join(values(c), ",")
+	case map[string]interface{}:
+		b, err := json.Marshal(c)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+func numericDate(v interface{}) (time.Time, bool) {
+	switch n := v.(type) {
+	case float64:
+		return time.Unix(int64(n), 0), true
+	case json.Number:
+		f, err := n.Float64()
+		return time.Unix(int64(f), 0), err == nil
+	}
+	return time.Time{}, false
+}
+
+//Name returns handler name
+func (h *Handler) Name() string {
+	return Name
+}
+
+func newHandler() (handler.Handler, error) {
+	var c config.JWT
+	if mc := config.GetConfig(); mc != nil {
+		c = mc.Mesher.JWT
+	}
+	h, err := New(&c)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func init() {
+	err := mhandler.RegisterConfigured(Name, newHandler)
+	if err != nil {
+		openlog.Error("register handler error: " + err.Error())
+	}
+}
@@ -0,0 +1,196 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/handler/handlertest"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+var rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+
+func writePublicKey(t *testing.T, dir string, key interface{}) string {
+	b, err := x509.MarshalPKIXPublicKey(key)
+	assert.NoError(t, err)
+	f := filepath.Join(dir, "key.pem")
+	assert.NoError(t, ioutil.WriteFile(f, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b}), 0600))
+	return f
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	s, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	assert.NoError(t, err)
+	return s
+}
+
+//request returns a request with bearer token, it has no token if token is empty
+func request(token string) *http.Request {
+	if token == "" {
+		return handlertest.NewRequest(http.MethodGet, "http://shop.com/orders/1", nil)
+	}
+	return handlertest.NewRequest(http.MethodGet, "http://shop.com/orders/1", map[string]string{"Authorization": "Bearer " + token})
+}
+
+func TestHandler_Handle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwt")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	h, err := New(&config.JWT{
+		Issuer:    "https://auth.com",
+		Audiences: []string{"shop"},
+		Keys:      []string{writePublicKey(t, dir, &rsaKey.PublicKey)},
+		Leeway:    "30s",
+		Forward:   map[string]string{"sub": "X-User", "realm_access.roles": "X-Roles"},
+		Policies: []*config.JWTPolicy{
+			{APIPath: "^/health", Anonymous: true},
+			{Service: "order", Methods: []string{"DELETE"}, Claims: []config.ClaimMatch{{Name: "realm_access.roles", Values: []string{"admin"}}}},
+			{Service: "order", Claims: []config.ClaimMatch{{Name: "scope", Values: []string{"order"}}}},
+		},
+	})
+	assert.NoError(t, err)
+	now := time.Now()
+	claims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":          "https://auth.com",
+			"aud":          []string{"shop", "other"},
+			"sub":          "jason",
+			"exp":          now.Add(time.Minute).Unix(),
+			"scope":        "profile order",
+			"realm_access": map[string]interface{}{"roles": []string{"user", "auditor"}},
+		}
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		req := request(sign(t, jwt.SigningMethodRS256, rsaKey, claims()))
+		req.Header.Set("X-User", "admin")
+		resp, inv := handlertest.Call(h, req, "order")
+		assert.NoError(t, resp.Err)
+		assert.Equal(t, "jason", req.Header.Get("X-User"))
+		assert.Equal(t, "user,auditor", req.Header.Get("X-Roles"))
+		assert.Equal(t, "jason", inv.Headers()["X-User"])
+	})
+	t.Run("missing token", func(t *testing.T) {
+		resp, _ := handlertest.Call(h, request(""), "order")
+		assert.Equal(t, http.StatusUnauthorized, resp.Status)
+		e := resp.Err.(*protocol.HTTPError)
+		assert.Equal(t, `Bearer realm="mesher"`, e.Header.Get("WWW-Authenticate"))
+
+		req, _ := http.NewRequest(http.MethodGet, "http://shop.com/health", nil)
+		req.Header.Set("X-User", "admin")
+		resp, _ = handlertest.Call(h, req, "order")
+		assert.NoError(t, resp.Err)
+		assert.Empty(t, req.Header.Get("X-User"))
+	})
+	t.Run("invalid token", func(t *testing.T) {
+		other, _ := rsa.GenerateKey(rand.Reader, 1024)
+		expired := claims()
+		expired["exp"] = now.Add(-time.Minute).Unix()
+		inLeeway := claims()
+		inLeeway["exp"] = now.Add(-10 * time.Second).Unix()
+		notYet := claims()
+		notYet["nbf"] = now.Add(time.Minute).Unix()
+		issuer := claims()
+		issuer["iss"] = "https://evil.com"
+		audience := claims()
+		audience["aud"] = "other"
+		for name, token := range map[string]string{
+			"signature": sign(t, jwt.SigningMethodRS256, other, claims()),
+			"expired":   sign(t, jwt.SigningMethodRS256, rsaKey, expired),
+			"nbf":       sign(t, jwt.SigningMethodRS256, rsaKey, notYet),
+			"iss":       sign(t, jwt.SigningMethodRS256, rsaKey, issuer),
+			"aud":       sign(t, jwt.SigningMethodRS256, rsaKey, audience),
+			"hmac":      sign(t, jwt.SigningMethodHS256, []byte("secret"), claims()),
+			"none":      sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims()),
+			"malformed": "a.b.c",
+		} {
+			resp, _ := handlertest.Call(h, request(token), "order")
+			if assert.NotNil(t, resp, name) {
+				assert.Equal(t, http.StatusUnauthorized, resp.Status, name)
+				assert.Contains(t, resp.Err.(*protocol.HTTPError).Header.Get("WWW-Authenticate"), `error="invalid_token"`, name)
+			}
+		}
+		resp, _ := handlertest.Call(h, request(sign(t, jwt.SigningMethodRS256, rsaKey, inLeeway)), "order")
+		assert.NoError(t, resp.Err)
+	})
+	t.Run("required claims", func(t *testing.T) {
+		req := request(sign(t, jwt.SigningMethodRS256, rsaKey, claims()))
+		req.Method = http.MethodDelete
+		resp, _ := handlertest.Call(h, req, "order")
+		assert.Equal(t, http.StatusForbidden, resp.Status)
+		assert.Contains(t, resp.Err.(*protocol.HTTPError).Header.Get("WWW-Authenticate"), `error="insufficient_scope"`)
+
+		admin := claims()
+		admin["realm_access"] = map[string]interface{}{"roles": []string{"admin"}}
+		req = request(sign(t, jwt.SigningMethodRS256, rsaKey, admin))
+		req.Method = http.MethodDelete
+		resp, _ = handlertest.Call(h, req, "order")
+		assert.NoError(t, resp.Err)
+
+		noScope := claims()
+		delete(noScope, "scope")
+		resp, _ = handlertest.Call(h, request(sign(t, jwt.SigningMethodRS256, rsaKey, noScope)), "order")
+		assert.Equal(t, http.StatusForbidden, resp.Status)
+		resp, _ = handlertest.Call(h, request(sign(t, jwt.SigningMethodRS256, rsaKey, noScope)), "user")
+		assert.NoError(t, resp.Err)
+	})
+}
+
+func TestHandler_Secret(t *testing.T) {
+	h, err := New(&config.JWT{Secret: "secret", Header: "X-Token"})
+	assert.NoError(t, err)
+	req := request("")
+	req.Header.Set("X-Token", sign(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"sub": "jason"}))
+	resp, _ := handlertest.Call(h, req, "order")
+	assert.NoError(t, resp.Err)
+
+	ec, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	req.Header.Set("X-Token", sign(t, jwt.SigningMethodES256, ec, jwt.MapClaims{"sub": "jason"}))
+	resp, _ = handlertest.Call(h, req, "order")
+	assert.Equal(t, http.StatusUnauthorized, resp.Status)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&config.JWT{})
+	assert.Equal(t, ErrNotConfigured, err)
+	_, err = New(&config.JWT{Secret: "s", Leeway: "1x"})
+	assert.Error(t, err)
+	_, err = New(&config.JWT{Keys: []string{"not-exist.pem"}})
+	assert.Error(t, err)
+	_, err = New(&config.JWT{Secret: "s", Policies: []*config.JWTPolicy{{APIPath: "("}}})
+	assert.Error(t, err)
+
+	//invalid config fails startup instead of rejecting requests
+	_, err = newHandler()
+	assert.Equal(t, ErrNotConfigured, err)
+}
@@ -0,0 +1,291 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/openlog"
+	"github.com/golang-jwt/jwt"
+)
+
+//DefaultJWKSCacheTime is how long keys of JWKS are used before fetching again
+const DefaultJWKSCacheTime = 5 * time.Minute
+
+//token with unknown kid triggers fetching JWKS, but not more often than this
+const minRefreshInterval = 10 * time.Second
+
+//maxJWKSSize is the max size of JWKS response
+const maxJWKSSize = 1 << 20
+
+//jwk is a json web key, only public keys for signature are used
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+//keySet holds keys to verify tokens, keys of JWKS are cached and fetched again after cache time
+type keySet struct {
+	jwks      string
+	cacheTime time.Duration
+	client    *http.Client
+	static    []interface{}
+	secret    []byte
+
+	mu      sync.Mutex
+	keys    map[string]interface{}
+	unnamed []interface{}
+	fetched time.Time
+	now     func() time.Time
+	//refreshing is closed when the running fetch finishes
+	refreshing chan struct{}
+}
+
+func newKeySet(jwks string, cacheTime time.Duration, static []interface{}, secret []byte) *keySet {
+	if cacheTime <= 0 {
+		cacheTime = DefaultJWKSCacheTime
+	}
+	return &keySet{
+		jwks:      jwks,
+		cacheTime: cacheTime,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		static:    static,
+		secret:    secret,
+		now:       time.Now,
+	}
+}
+
+//candidates returns keys which may verify a token signed by method with kid
+func (ks *keySet) candidates(kid string, method jwt.SigningMethod) []interface{} {
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		if len(ks.secret) == 0 {
+			return nil
+		}
+		return []interface{}{ks.secret}
+	}
+	var keys []interface{}
+	if ks.jwks != "" {
+		keys = ks.jwksKeys(kid)
+	}
+	keys = append(keys, ks.static...)
+	result := keys[:0]
+	for _, k := range keys {
+		if compatible(k, method) {
+			result = append(result, k)
+		}
+	}
+	return result
+}
+
+func compatible(key interface{}, method jwt.SigningMethod) bool {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, ok := key.(*rsa.PublicKey)
+		return ok
+	case *jwt.SigningMethodECDSA:
+		_, ok := key.(*ecdsa.PublicKey)
+		return ok
+	}
+	return false
+}
+
+//jwksKeys returns the key of kid, or all keys of JWKS if kid is unknown.
+//Keys are fetched when cache expires or kid is unknown, only one fetch runs at a time,
+//and the lock is not held while fetching
+func (ks *keySet) jwksKeys(kid string) []interface{} {
+	ks.mu.Lock()
+	now := ks.now()
+	_, known := ks.keys[kid]
+	if now.Sub(ks.fetched) >= ks.cacheTime || (kid != "" && !known && now.Sub(ks.fetched) >= minRefreshInterval) {
+		done := ks.refreshing
+		if done == nil {
+			done = make(chan struct{})
+			ks.refreshing = done
+			ks.mu.Unlock()
+			keys, unnamed, err := ks.load()
+			ks.mu.Lock()
+			if err != nil {
+				openlog.Error("can not fetch jwks, old keys are used: " + err.Error())
+			} else {
+				ks.keys, ks.unnamed = keys, unnamed
+			}
+			ks.fetched = now
+			ks.refreshing = nil
+			close(done)
+		} else {
+			ks.mu.Unlock()
+			<-done
+			ks.mu.Lock()
+		}
+	}
+	defer ks.mu.Unlock()
+	var keys []interface{}
+	if k, ok := ks.keys[kid]; ok {
+		return append(keys, k)
+	}
+	for _, k := range ks.keys {
+		keys = append(keys, k)
+	}
+	return append(keys, ks.unnamed...)
+}
+
+//load reads JWKS from file or url
+func (ks *keySet) load() (map[string]interface{}, []interface{}, error) {
+	var b []byte
+	var err error
+	if strings.HasPrefix(ks.jwks, "http://") || strings.HasPrefix(ks.jwks, "https://") {
+		b, err = ks.fetch()
+	} else {
+		b, err = ioutil.ReadFile(ks.jwks)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseJWKS(b)
+}
+
+func (ks *keySet) fetch() ([]byte, error) {
+	resp, err := ks.client.Get(ks.jwks)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks response status %d", resp.StatusCode)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxJWKSSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxJWKSSize {
+		return nil, fmt.Errorf("jwks is larger than %d bytes", maxJWKSSize)
+	}
+	return b, nil
+}
+
+//parseJWKS returns keys with kid, and keys without kid, keys which are not used to sign are ignored
+func parseJWKS(b []byte) (map[string]interface{}, []interface{}, error) {
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, nil, err
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	var unnamed []interface{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			openlog.Warn(fmt.Sprintf("ignore jwk [%s]: %s", k.Kid, err))
+			continue
+		}
+		if k.Kid == "" {
+			unnamed = append(unnamed, key)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, unnamed, nil
+}
+
+func (k *jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, errors.New("invalid exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve [%s]", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type [%s]", k.Kty)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+//parsePublicKey parses a PEM encoded RSA or ECDSA public key
+func parsePublicKey(b []byte) (interface{}, error) {
+	if k, err := jwt.ParseRSAPublicKeyFromPEM(b); err == nil {
+		return k, nil
+	}
+	if k, err := jwt.ParseECPublicKeyFromPEM(b); err == nil {
+		return k, nil
+	}
+	return nil, errors.New("not a PEM encoded RSA or ECDSA public key")
+}
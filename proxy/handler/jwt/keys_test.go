@@ -0,0 +1,148 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func TestParseJWKS(t *testing.T) {
+	ec, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	b, _ := json.Marshal(map[string]interface{}{"keys": []jwk{
+		{Kid: "rsa", Kty: "RSA", Use: "sig", N: encodeInt(rsaKey.N), E: encodeInt(big.NewInt(int64(rsaKey.E)))},
+		{Kty: "EC", Crv: "P-256", X: encodeInt(ec.X), Y: encodeInt(ec.Y)},
+		{Kid: "enc", Kty: "RSA", Use: "enc", N: encodeInt(rsaKey.N), E: "AQAB"},
+		{Kid: "bad", Kty: "EC", Crv: "P-256", X: encodeInt(ec.X), Y: encodeInt(ec.X)},
+		{Kid: "oct", Kty: "oct"},
+	}})
+	keys, unnamed, err := parseJWKS(b)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.Equal(t, rsaKey.PublicKey, *keys["rsa"].(*rsa.PublicKey))
+	assert.Len(t, unnamed, 1)
+	assert.Equal(t, ec.PublicKey, *unnamed[0].(*ecdsa.PublicKey))
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	kid := "k1"
+	var fetched int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetched, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []jwk{
+			{Kid: kid, Kty: "RSA", N: encodeInt(rsaKey.N), E: encodeInt(big.NewInt(int64(rsaKey.E)))},
+		}})
+	}))
+	defer server.Close()
+
+	h, err := New(&config.JWT{JWKS: server.URL, JWKSCacheTime: "1m"})
+	assert.NoError(t, err)
+	now := time.Now()
+	h.keys.now = func() time.Time { return now }
+	token := func(kid string) string {
+		to := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "jason"})
+		to.Header["kid"] = kid
+		s, err := to.SignedString(rsaKey)
+		assert.NoError(t, err)
+		return s
+	}
+
+	_, err = h.Validate(token("k1"))
+	assert.NoError(t, err)
+	_, err = h.Validate(token("k1"))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetched))
+
+	//key is rotated, unknown kid fetches keys again, but not too often
+	kid = "k2"
+	_, err = h.Validate(token("k2"))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetched))
+	now = now.Add(minRefreshInterval)
+	_, err = h.Validate(token("k2"))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetched))
+
+	now = now.Add(time.Minute)
+	_, err = h.Validate(token("k2"))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&fetched))
+
+	//old keys are used if fetching fails
+	server.Close()
+	now = now.Add(time.Minute)
+	_, err = h.Validate(token("k2"))
+	assert.NoError(t, err)
+}
+
+func TestKeySet_Fetch(t *testing.T) {
+	var fetched int32
+	release := make(chan struct{})
+	large := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetched, 1)
+		<-release
+		if large {
+			w.Write(make([]byte, maxJWKSSize+1))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []jwk{
+			{Kid: "k1", Kty: "RSA", N: encodeInt(rsaKey.N), E: encodeInt(big.NewInt(int64(rsaKey.E)))},
+		}})
+	}))
+	defer server.Close()
+	ks := newKeySet(server.URL, time.Minute, nil, nil)
+
+	//concurrent tokens share one fetch
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Len(t, ks.candidates("k1", jwt.SigningMethodRS256), 1)
+		}()
+	}
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&fetched) == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetched))
+
+	//too large jwks is not used
+	large = true
+	_, _, err := ks.load()
+	assert.Error(t, err)
+}
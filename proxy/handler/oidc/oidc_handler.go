@@ -0,0 +1,546 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+//Package oidc logs in browser users with OpenID Connect at the edge,
+//and forwards identity of session to backend
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	mhandler "github.com/apache/servicecomb-mesher/proxy/handler"
+	"github.com/apache/servicecomb-mesher/proxy/handler/jwt"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/openlog"
+	"golang.org/x/oauth2"
+)
+
+//Name is the name of handler
+const Name = "oidc"
+
+//default values of config
+const (
+	DefaultCookieName = "mesher_session"
+	DefaultLogoutPath = "/oauth2/logout"
+	DefaultMaxAge     = 24 * time.Hour
+)
+
+const (
+	//loginTimeout is how long user can take to log in at identity provider
+	loginTimeout = 10 * time.Minute
+	//tokens are refreshed a bit before they expire
+	expirySkew = 30 * time.Second
+	//concurrent requests of a session share the result of refreshing for a while,
+	//so a rotated refresh token is not used twice
+	refreshReuse = time.Minute
+)
+
+//errors of login
+var (
+	ErrLoginRequired = errors.New("login required")
+	ErrInvalidState  = errors.New("invalid state")
+	ErrInvalidNonce  = errors.New("invalid nonce")
+	ErrNoIDToken     = errors.New("id token is missing")
+	ErrSubject       = errors.New("subject of refreshed id token is changed")
+	ErrNotConfigured = errors.New("oidc handler is not configured")
+)
+
+//Handler redirects browser users without session to identity provider,
+//other requests without session get 401
+type Handler struct {
+	cfg          *config.OIDC
+	provider     *provider
+	codec        *codec
+	cookie       http.Cookie
+	maxAge       time.Duration
+	callbackPath string
+	logoutPath   string
+	public       []*regexp.Regexp
+	now          func() time.Time
+
+	mu        sync.Mutex
+	refreshed map[string]*refreshCall
+}
+
+type refreshCall struct {
+	done chan struct{}
+	s    *session
+	err  error
+	at   time.Time
+}
+
+//New returns an oidc handler of config
+func New(c *config.OIDC) (*Handler, error) {
+	if c.Issuer == "" || c.ClientID == "" || c.RedirectURL == "" || c.Cookie.Secret == "" {
+		return nil, ErrNotConfigured
+	}
+	redirect, err := url.Parse(c.RedirectURL)
+	if err != nil || !redirect.IsAbs() {
+		return nil, fmt.Errorf("redirectURL must be an absolute url")
+	}
+	h := &Handler{
+		cfg:      c,
+		provider: &provider{cfg: c, client: &http.Client{Timeout: 10 * time.Second}},
+		cookie: http.Cookie{
+			Name:     c.Cookie.Name,
+			Domain:   c.Cookie.Domain,
+			Path:     "/",
+			Secure:   redirect.Scheme == "https",
+			HttpOnly: true,
+			//Lax sends cookies when identity provider redirects user back
+			SameSite: http.SameSiteLaxMode,
+		},
+		maxAge:       DefaultMaxAge,
+		callbackPath: redirect.Path,
+		logoutPath:   c.LogoutPath,
+		now:          time.Now,
+		refreshed:    make(map[string]*refreshCall),
+	}
+	if h.cookie.Name == "" {
+		h.cookie.Name = DefaultCookieName
+	}
+	if h.callbackPath == "" {
+		h.callbackPath = "/"
+	}
+	if h.logoutPath == "" {
+		h.logoutPath = DefaultLogoutPath
+	}
+	if c.Cookie.MaxAge != "" {
+		if h.maxAge, err = time.ParseDuration(c.Cookie.MaxAge); err != nil {
+			return nil, fmt.Errorf("invalid cookie maxAge: %w", err)
+		}
+	}
+	if h.codec, err = newCodec(c.Cookie.Secret); err != nil {
+		return nil, err
+	}
+	for _, p := range c.PublicPaths {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public path: %w", err)
+		}
+		h.public = append(h.public, re)
+	}
+	return h, nil
+}
+
+//Handle serves callback and logout, and forwards identity of session
+func (h *Handler) Handle(chain *handler.Chain, inv *invocation.Invocation, cb invocation.ResponseCallBack) {
+	req, ok := inv.Args.(*http.Request)
+	if !ok {
+		chain.Next(inv, cb)
+		return
+	}
+	for _, name := range h.cfg.Forward {
+		protocol.DelHeader(inv, req, name)
+	}
+	switch req.URL.Path {
+	case h.callbackPath:
+		cb(h.callback(req))
+		return
+	case h.logoutPath:
+		cb(h.logout(req))
+		return
+	}
+	s, header := h.session(req)
+	if s == nil {
+		if h.isPublic(req.URL.Path) {
+			next(chain, inv, cb, header)
+			return
+		}
+		cb(h.login(req, header))
+		return
+	}
+	for name, v := range s.Headers {
+		protocol.SetHeader(inv, req, name, v)
+	}
+	if h.cfg.ForwardAccessToken && s.AccessToken != "" {
+		protocol.SetHeader(inv, req, "Authorization", "Bearer "+s.AccessToken)
+	}
+	next(chain, inv, cb, header)
+}
+
+//next calls next handler, header setting cookies is sent to client with the response of backend
+func next(chain *handler.Chain, inv *invocation.Invocation, cb invocation.ResponseCallBack, header http.Header) {
+	if header == nil {
+		chain.Next(inv, cb)
+		return
+	}
+	chain.Next(inv, func(r *invocation.Response) {
+		protocol.AddResponseHeader(r, header)
+		cb(r)
+	})
+}
+
+//session returns a valid session of request, tokens are refreshed if they expire,
+//the returned header sets refreshed session or clears invalid one
+func (h *Handler) session(req *http.Request) (*session, http.Header) {
+	value, n := readChunks(req, h.cookie.Name)
+	if n == 0 {
+		return nil, nil
+	}
+	s := &session{}
+	if err := h.codec.decode(h.cookie.Name, value, s); err != nil {
+		openlog.Debug("invalid session: " + err.Error())
+		return nil, h.clearSession(n)
+	}
+	now := h.now()
+	if now.Sub(s.Created) >= h.maxAge {
+		return nil, h.clearSession(n)
+	}
+	if s.Expiry.IsZero() || now.Add(expirySkew).Before(s.Expiry) {
+		return s, nil
+	}
+	if s.RefreshToken == "" {
+		return nil, h.clearSession(n)
+	}
+	refreshed, err := h.refresh(req.Context(), s)
+	if err != nil {
+		openlog.Warn("can not refresh session: " + err.Error())
+		return nil, h.clearSession(n)
+	}
+	header, err := h.saveSession(refreshed, n)
+	if err != nil {
+		openlog.Error("can not save session: " + err.Error())
+		return nil, h.clearSession(n)
+	}
+	return refreshed, header
+}
+
+//refresh gets new tokens, concurrent requests with the same refresh token share the result
+func (h *Handler) refresh(ctx context.Context, s *session) (*session, error) {
+	h.mu.Lock()
+	now := h.now()
+	for k, c := range h.refreshed {
+		if !c.at.IsZero() && now.Sub(c.at) > refreshReuse {
+			delete(h.refreshed, k)
+		}
+	}
+	c, ok := h.refreshed[s.RefreshToken]
+	if !ok {
+		c = &refreshCall{done: make(chan struct{})}
+		h.refreshed[s.RefreshToken] = c
+	}
+	h.mu.Unlock()
+	if ok {
+		<-c.done
+		return c.s, c.err
+	}
+	c.s, c.err = h.doRefresh(ctx, s)
+	h.mu.Lock()
+	c.at = h.now()
+	h.mu.Unlock()
+	close(c.done)
+	return c.s, c.err
+}
+
+func (h *Handler) doRefresh(ctx context.Context, s *session) (*session, error) {
+	ep, err := h.provider.endpoints()
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, h.provider.client)
+	token, err := ep.oauth2.TokenSource(ctx, &oauth2.Token{RefreshToken: s.RefreshToken}).Token()
+	if err != nil {
+		return nil, err
+	}
+	refreshed := *s
+	refreshed.RefreshToken = token.RefreshToken
+	refreshed.Expiry = token.Expiry
+	if h.cfg.ForwardAccessToken {
+		refreshed.AccessToken = token.AccessToken
+	}
+	//id token is optional in refresh response
+	if raw, _ := token.Extra("id_token").(string); raw != "" {
+		claims, err := ep.verifier.Validate(raw)
+		if err != nil {
+			return nil, err
+		}
+		if sub, _ := claims["sub"].(string); sub != s.Subject {
+			return nil, ErrSubject
+		}
+		refreshed.IDToken = raw
+		refreshed.Headers = h.headers(claims)
+		if refreshed.Expiry.IsZero() {
+			refreshed.Expiry = expiry(claims)
+		}
+	}
+	return &refreshed, nil
+}
+
+//login redirects browser to identity provider, the state of login is saved in a cookie named by state,
+//so that user can log in from several tabs at the same time
+func (h *Handler) login(req *http.Request, header http.Header) *invocation.Response {
+	if !isBrowser(req) {
+		return reply(http.StatusUnauthorized, header, ErrLoginRequired.Error())
+	}
+	ep, err := h.provider.endpoints()
+	if err != nil {
+		openlog.Error("can not discover identity provider: " + err.Error())
+		return reply(http.StatusServiceUnavailable, header, "identity provider is unavailable")
+	}
+	state, nonce, verifier := random(), random(), random()
+	ls := &loginState{Nonce: nonce, Verifier: verifier, Return: req.URL.RequestURI(), Expires: h.now().Add(loginTimeout)}
+	c := h.stateCookie(state)
+	if c.Value, err = h.codec.encode(c.Name, ls); err != nil {
+		return reply(http.StatusInternalServerError, header, err.Error())
+	}
+	c.MaxAge = int(loginTimeout / time.Second)
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Add("Set-Cookie", c.String())
+	challenge := sha256.Sum256([]byte(verifier))
+	header.Set("Location", ep.oauth2.AuthCodeURL(state,
+		oauth2.SetAuthURLParam("nonce", nonce),
+		oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256")))
+	return reply(http.StatusFound, header, "")
+}
+
+//callback exchanges code for tokens, validates id token and saves session
+func (h *Handler) callback(req *http.Request) *invocation.Response {
+	q := req.URL.Query()
+	state := q.Get("state")
+	if state == "" {
+		return reply(http.StatusBadRequest, nil, ErrInvalidState.Error())
+	}
+	c := h.stateCookie(state)
+	header := http.Header{}
+	//the state cookie is used only once
+	clear := c
+	clear.MaxAge = -1
+	header.Add("Set-Cookie", clear.String())
+	sc, err := req.Cookie(c.Name)
+	if err != nil {
+		return reply(http.StatusBadRequest, header, ErrInvalidState.Error())
+	}
+	ls := &loginState{}
+	if err := h.codec.decode(c.Name, sc.Value, ls); err != nil || h.now().After(ls.Expires) {
+		return reply(http.StatusBadRequest, header, ErrInvalidState.Error())
+	}
+	if e := q.Get("error"); e != "" {
+		openlog.Warn(fmt.Sprintf("login failed: %s %s", e, q.Get("error_description")))
+		return reply(http.StatusUnauthorized, header, "login failed: "+e)
+	}
+	ep, err := h.provider.endpoints()
+	if err != nil {
+		openlog.Error("can not discover identity provider: " + err.Error())
+		return reply(http.StatusServiceUnavailable, header, "identity provider is unavailable")
+	}
+	ctx := context.WithValue(req.Context(), oauth2.HTTPClient, h.provider.client)
+	token, err := ep.oauth2.Exchange(ctx, q.Get("code"), oauth2.SetAuthURLParam("code_verifier", ls.Verifier))
+	if err != nil {
+		openlog.Warn("can not exchange code: " + err.Error())
+		return reply(http.StatusUnauthorized, header, "invalid code")
+	}
+	raw, _ := token.Extra("id_token").(string)
+	if raw == "" {
+		return reply(http.StatusUnauthorized, header, ErrNoIDToken.Error())
+	}
+	claims, err := ep.verifier.Validate(raw)
+	if err != nil {
+		openlog.Warn("invalid id token: " + err.Error())
+		return reply(http.StatusUnauthorized, header, "invalid id token")
+	}
+	if nonce, _ := claims["nonce"].(string); nonce != ls.Nonce {
+		return reply(http.StatusUnauthorized, header, ErrInvalidNonce.Error())
+	}
+	sub, _ := claims["sub"].(string)
+	s := &session{
+		Subject:      sub,
+		Headers:      h.headers(claims),
+		RefreshToken: token.RefreshToken,
+		IDToken:      raw,
+		Expiry:       token.Expiry,
+		Created:      h.now(),
+	}
+	if s.Expiry.IsZero() {
+		s.Expiry = expiry(claims)
+	}
+	if h.cfg.ForwardAccessToken {
+		s.AccessToken = token.AccessToken
+	}
+	_, old := readChunks(req, h.cookie.Name)
+	saved, err := h.saveSession(s, old)
+	if err != nil {
+		return reply(http.StatusInternalServerError, header, err.Error())
+	}
+	copyHeader(header, saved)
+	header.Set("Location", returnPath(ls.Return))
+	return reply(http.StatusFound, header, "")
+}
+
+//logout clears session, and ends session at identity provider if it supports
+func (h *Handler) logout(req *http.Request) *invocation.Response {
+	value, n := readChunks(req, h.cookie.Name)
+	header := h.clearSession(n)
+	if header == nil {
+		header = http.Header{}
+	}
+	location := h.cfg.PostLogoutRedirectURL
+	if location == "" {
+		location = "/"
+	}
+	if ep, err := h.provider.endpoints(); err == nil && ep.endSessionURL != "" {
+		q := url.Values{"client_id": {h.cfg.ClientID}}
+		if h.cfg.PostLogoutRedirectURL != "" {
+			q.Set("post_logout_redirect_uri", h.cfg.PostLogoutRedirectURL)
+		}
+		s := &session{}
+		if n != 0 && h.codec.decode(h.cookie.Name, value, s) == nil && s.IDToken != "" {
+			q.Set("id_token_hint", s.IDToken)
+		}
+		sep := "?"
+		if strings.Contains(ep.endSessionURL, "?") {
+			sep = "&"
+		}
+		location = ep.endSessionURL + sep + q.Encode()
+	}
+	header.Set("Location", location)
+	return reply(http.StatusFound, header, "")
+}
+
+func (h *Handler) saveSession(s *session, old int) (http.Header, error) {
+	c := h.cookie
+	value, err := h.codec.encode(c.Name, s)
+	if err != nil {
+		return nil, err
+	}
+	c.MaxAge = int((h.maxAge - h.now().Sub(s.Created)) / time.Second)
+	header := http.Header{}
+	writeChunks(header, c, value, old)
+	return header, nil
+}
+
+func (h *Handler) clearSession(n int) http.Header {
+	if n == 0 {
+		return nil
+	}
+	header := http.Header{}
+	writeChunks(header, h.cookie, "", n)
+	return header
+}
+
+func (h *Handler) stateCookie(state string) http.Cookie {
+	c := h.cookie
+	c.Name = h.cookie.Name + "_state_" + state
+	c.Path = h.callbackPath
+	return c
+}
+
+func (h *Handler) headers(claims map[string]interface{}) map[string]string {
+	result := make(map[string]string, len(h.cfg.Forward))
+	for claim, name := range h.cfg.Forward {
+		if v, ok := jwt.ClaimHeader(claims, claim); ok {
+			result[name] = v
+		}
+	}
+	return result
+}
+
+func (h *Handler) isPublic(path string) bool {
+	for _, re := range h.public {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+//Name returns handler name
+func (h *Handler) Name() string {
+	return Name
+}
+
+//isBrowser tells whether a request is a page navigation, api requests get 401 instead of redirection
+func isBrowser(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	return strings.Contains(req.Header.Get("Accept"), "text/html")
+}
+
+//returnPath only allows local paths, so login can not redirect user to other sites
+func returnPath(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.HasPrefix(p, "/\\") {
+		return "/"
+	}
+	return p
+}
+
+func expiry(claims map[string]interface{}) time.Time {
+	if exp, ok := claims["exp"].(float64); ok {
+		return time.Unix(int64(exp), 0)
+	}
+	return time.Time{}
+}
+
+func random() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func reply(status int, header http.Header, message string) *invocation.Response {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &invocation.Response{Status: status, Err: &protocol.HTTPError{Status: status, Header: header, Message: message}}
+}
+
+func copyHeader(dst, src http.Header) {
+	for k, vs := range src {
+		for _, v := range vs {
+			dst.Add(k, v)
+		}
+	}
+}
+
+func newHandler() (handler.Handler, error) {
+	var c config.OIDC
+	if mc := config.GetConfig(); mc != nil {
+		c = mc.Mesher.OIDC
+	}
+	h, err := New(&c)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func init() {
+	err := mhandler.RegisterConfigured(Name, newHandler)
+	if err != nil {
+		openlog.Error("register handler error: " + err.Error())
+	}
+}
@@ -0,0 +1,350 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/handler/handlertest"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+var rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+
+//login is a pending authorization of fake identity provider
+type login struct {
+	nonce     string
+	challenge string
+}
+
+//idp is a fake identity provider
+type idp struct {
+	*httptest.Server
+	mu        sync.Mutex
+	logins    map[string]login
+	refreshed int
+}
+
+func newIDP(t *testing.T) *idp {
+	p := &idp{logins: map[string]login{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+			"end_session_endpoint":   p.URL + "/logout",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kid": "k1",
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		claims := jwt.MapClaims{"iss": p.URL, "aud": "shop", "sub": "jason", "email": "jason@shop.com",
+			"exp": time.Now().Add(time.Hour).Unix()}
+		switch r.FormValue("grant_type") {
+		case "authorization_code":
+			l, ok := p.logins[r.FormValue("code")]
+			delete(p.logins, r.FormValue("code"))
+			verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+			if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != l.challenge {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"invalid_grant"}`))
+				return
+			}
+			claims["nonce"] = l.nonce
+		case "refresh_token":
+			if r.FormValue("refresh_token") != "rt" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"invalid_grant"}`))
+				return
+			}
+			p.refreshed++
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "k1"
+		idToken, err := token.SignedString(rsaKey)
+		assert.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "at",
+			"token_type":    "Bearer",
+			"expires_in":    300,
+			"refresh_token": "rt",
+			"id_token":      idToken,
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+//authorize logs in user at identity provider, and returns the callback url
+func (p *idp) authorize(t *testing.T, location string, nonce string) string {
+	u, err := url.Parse(location)
+	assert.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, p.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	if nonce == "" {
+		nonce = q.Get("nonce")
+	}
+	p.mu.Lock()
+	p.logins["code"] = login{nonce: nonce, challenge: q.Get("code_challenge")}
+	p.mu.Unlock()
+	return q.Get("redirect_uri") + "?code=code&state=" + q.Get("state")
+}
+
+func page(u string, cookies []*http.Cookie) *http.Request {
+	req := handlertest.NewRequest(http.MethodGet, u, map[string]string{"Accept": "text/html,application/xhtml+xml"})
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	return req
+}
+
+func responseHeader(r *invocation.Response) http.Header {
+	if h, ok := r.Result.(http.Header); ok {
+		return h
+	}
+	return r.Err.(*protocol.HTTPError).Header
+}
+
+func cookies(h http.Header) []*http.Cookie {
+	return (&http.Response{Header: h}).Cookies()
+}
+
+func cookie(h http.Header, name string) *http.Cookie {
+	for _, c := range cookies(h) {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func newTestHandler(t *testing.T, p *idp) *Handler {
+	h, err := New(&config.OIDC{
+		Issuer:                p.URL,
+		ClientID:              "shop",
+		ClientSecret:          "secret",
+		RedirectURL:           "https://shop.com/oauth2/callback",
+		PostLogoutRedirectURL: "https://shop.com/",
+		PublicPaths:           []string{"^/static/"},
+		Forward:               map[string]string{"sub": "X-User", "email": "X-Email"},
+		ForwardAccessToken:    true,
+		Cookie:                config.OIDCCookie{Secret: "cookie secret"},
+	})
+	assert.NoError(t, err)
+	return h
+}
+
+//logIn goes through login flow, and returns session cookies
+func logIn(t *testing.T, h *Handler, p *idp) []*http.Cookie {
+	r, _ := handlertest.Call(h, page("https://shop.com/orders?id=1", nil), "")
+	assert.Equal(t, http.StatusFound, r.Status)
+	header := responseHeader(r)
+	r, _ = handlertest.Call(h, page(p.authorize(t, header.Get("Location"), ""), cookies(header)), "")
+	assert.Equal(t, http.StatusFound, r.Status)
+	header = responseHeader(r)
+	assert.Equal(t, "/orders?id=1", header.Get("Location"))
+	var session []*http.Cookie
+	for _, c := range cookies(header) {
+		if c.MaxAge > 0 {
+			session = append(session, c)
+		}
+	}
+	return session
+}
+
+func TestHandler_Handle(t *testing.T) {
+	p := newIDP(t)
+	defer p.Close()
+	h := newTestHandler(t, p)
+
+	t.Run("api request without session", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "https://shop.com/orders", nil)
+		r, _ := handlertest.Call(h, req, "")
+		assert.Equal(t, http.StatusUnauthorized, r.Status)
+	})
+	t.Run("public path", func(t *testing.T) {
+		r, _ := handlertest.Call(h, page("https://shop.com/static/app.js", nil), "")
+		assert.NoError(t, r.Err)
+	})
+	t.Run("redirect to identity provider", func(t *testing.T) {
+		r, _ := handlertest.Call(h, page("https://shop.com/orders", nil), "")
+		assert.Equal(t, http.StatusFound, r.Status)
+		header := responseHeader(r)
+		u, err := url.Parse(header.Get("Location"))
+		assert.NoError(t, err)
+		assert.Equal(t, "shop", u.Query().Get("client_id"))
+		assert.Equal(t, "openid profile email", u.Query().Get("scope"))
+		state := cookie(header, "mesher_session_state_"+u.Query().Get("state"))
+		if assert.NotNil(t, state) {
+			assert.True(t, state.HttpOnly)
+			assert.True(t, state.Secure)
+			assert.Equal(t, "/oauth2/callback", state.Path)
+		}
+	})
+
+	session := logIn(t, h, p)
+	assert.NotEmpty(t, session)
+	t.Run("identity is forwarded", func(t *testing.T) {
+		req := page("https://shop.com/orders", session)
+		req.Header.Set("X-User", "admin")
+		r, inv := handlertest.Call(h, req, "")
+		assert.NoError(t, r.Err)
+		assert.Nil(t, r.Result)
+		assert.Equal(t, "jason", req.Header.Get("X-User"))
+		assert.Equal(t, "jason", inv.Headers()["X-User"])
+		assert.Equal(t, "jason@shop.com", inv.Headers()["X-Email"])
+		assert.Equal(t, "Bearer at", inv.Headers()["Authorization"])
+	})
+	t.Run("modified session", func(t *testing.T) {
+		c := *session[0]
+		//change the first char, so that the session can not be decrypted
+		first := "x"
+		if c.Value[0] == 'x' {
+			first = "y"
+		}
+		c.Value = first + c.Value[1:]
+		r, _ := handlertest.Call(h, page("https://shop.com/orders", []*http.Cookie{&c}), "")
+		assert.Equal(t, http.StatusFound, r.Status)
+		cleared := cookie(responseHeader(r), "mesher_session")
+		if assert.NotNil(t, cleared) {
+			assert.Equal(t, -1, cleared.MaxAge)
+		}
+	})
+	t.Run("refresh expired tokens", func(t *testing.T) {
+		h.now = func() time.Time { return time.Now().Add(10 * time.Minute) }
+		defer func() { h.now = time.Now }()
+		r, inv := handlertest.Call(h, page("https://shop.com/orders", session), "")
+		assert.NoError(t, r.Err)
+		assert.Equal(t, "jason", inv.Headers()["X-User"])
+		assert.NotNil(t, cookie(r.Result.(http.Header), "mesher_session"))
+		//requests with the same refresh token share the result
+		r, _ = handlertest.Call(h, page("https://shop.com/orders", session), "")
+		assert.NoError(t, r.Err)
+		assert.Equal(t, 1, p.refreshed)
+	})
+	t.Run("session is too old", func(t *testing.T) {
+		h.now = func() time.Time { return time.Now().Add(25 * time.Hour) }
+		defer func() { h.now = time.Now }()
+		req, _ := http.NewRequest(http.MethodGet, "https://shop.com/orders", nil)
+		req.AddCookie(session[0])
+		r, _ := handlertest.Call(h, req, "")
+		assert.Equal(t, http.StatusUnauthorized, r.Status)
+	})
+	t.Run("logout", func(t *testing.T) {
+		r, _ := handlertest.Call(h, page("https://shop.com/oauth2/logout", session), "")
+		assert.Equal(t, http.StatusFound, r.Status)
+		header := responseHeader(r)
+		u, err := url.Parse(header.Get("Location"))
+		assert.NoError(t, err)
+		assert.Equal(t, "/logout", u.Path)
+		assert.Equal(t, "https://shop.com/", u.Query().Get("post_logout_redirect_uri"))
+		assert.NotEmpty(t, u.Query().Get("id_token_hint"))
+		assert.Equal(t, -1, cookie(header, "mesher_session").MaxAge)
+	})
+}
+
+func TestHandler_Callback(t *testing.T) {
+	p := newIDP(t)
+	defer p.Close()
+	h := newTestHandler(t, p)
+	start := func() (string, http.Header) {
+		r, _ := handlertest.Call(h, page("https://shop.com/orders", nil), "")
+		header := responseHeader(r)
+		return header.Get("Location"), header
+	}
+
+	t.Run("state cookie is missing", func(t *testing.T) {
+		location, _ := start()
+		r, _ := handlertest.Call(h, page(p.authorize(t, location, ""), nil), "")
+		assert.Equal(t, http.StatusBadRequest, r.Status)
+	})
+	t.Run("state of other login", func(t *testing.T) {
+		location, _ := start()
+		_, header := start()
+		r, _ := handlertest.Call(h, page(p.authorize(t, location, ""), cookies(header)), "")
+		assert.Equal(t, http.StatusBadRequest, r.Status)
+	})
+	t.Run("invalid nonce", func(t *testing.T) {
+		location, header := start()
+		r, _ := handlertest.Call(h, page(p.authorize(t, location, "other"), cookies(header)), "")
+		assert.Equal(t, http.StatusUnauthorized, r.Status)
+		assert.Equal(t, ErrInvalidNonce.Error(), r.Err.Error())
+	})
+	t.Run("code is used twice", func(t *testing.T) {
+		location, header := start()
+		callback := p.authorize(t, location, "")
+		r, _ := handlertest.Call(h, page(callback, cookies(header)), "")
+		assert.Equal(t, http.StatusFound, r.Status)
+		r, _ = handlertest.Call(h, page(callback, cookies(header)), "")
+		assert.Equal(t, http.StatusUnauthorized, r.Status)
+	})
+	t.Run("login failed", func(t *testing.T) {
+		location, header := start()
+		u, _ := url.Parse(location)
+		r, _ := handlertest.Call(h, page("https://shop.com/oauth2/callback?error=access_denied&state="+u.Query().Get("state"), cookies(header)), "")
+		assert.Equal(t, http.StatusUnauthorized, r.Status)
+	})
+}
+
+func TestReturnPath(t *testing.T) {
+	assert.Equal(t, "/orders?id=1", returnPath("/orders?id=1"))
+	assert.Equal(t, "/", returnPath("//evil.com/"))
+	assert.Equal(t, "/", returnPath("/\\evil.com/"))
+	assert.Equal(t, "/", returnPath("https://evil.com/"))
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&config.OIDC{Issuer: "https://auth.com", ClientID: "shop", RedirectURL: "https://shop.com/callback"})
+	assert.Equal(t, ErrNotConfigured, err)
+	_, err = New(&config.OIDC{Issuer: "https://auth.com", ClientID: "shop", RedirectURL: "/callback",
+		Cookie: config.OIDCCookie{Secret: "s"}})
+	assert.Error(t, err)
+	_, err = New(&config.OIDC{Issuer: "https://auth.com", ClientID: "shop", RedirectURL: "https://shop.com/callback",
+		Cookie: config.OIDCCookie{Secret: "s", MaxAge: "a day"}})
+	assert.Error(t, err)
+	//invalid config fails startup instead of rejecting requests
+	_, err = newHandler()
+	assert.Equal(t, ErrNotConfigured, err)
+}
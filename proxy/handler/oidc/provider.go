@@ -0,0 +1,144 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/handler/jwt"
+	"golang.org/x/oauth2"
+)
+
+//failed discovery is retried, but not more often than this
+const minDiscoveryInterval = 10 * time.Second
+
+//metadata is the openid provider configuration
+type metadata struct {
+	Issuer        string `json:"issuer"`
+	AuthURL       string `json:"authorization_endpoint"`
+	TokenURL      string `json:"token_endpoint"`
+	JWKS          string `json:"jwks_uri"`
+	EndSessionURL string `json:"end_session_endpoint"`
+}
+
+//endpoints of identity provider, with a verifier of id token
+type endpoints struct {
+	oauth2        *oauth2.Config
+	verifier      *jwt.Handler
+	endSessionURL string
+}
+
+//provider discovers endpoints at the first login, so mesher starts even if identity provider is down
+type provider struct {
+	cfg    *config.OIDC
+	client *http.Client
+
+	mu        sync.Mutex
+	ep        *endpoints
+	err       error
+	attempted time.Time
+}
+
+func (p *provider) endpoints() (*endpoints, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ep != nil {
+		return p.ep, nil
+	}
+	if time.Since(p.attempted) < minDiscoveryInterval {
+		return nil, p.err
+	}
+	p.attempted = time.Now()
+	p.ep, p.err = p.discover()
+	return p.ep, p.err
+}
+
+func (p *provider) discover() (*endpoints, error) {
+	m := &metadata{Issuer: p.cfg.Issuer}
+	if p.cfg.AuthURL == "" || p.cfg.TokenURL == "" || p.cfg.JWKS == "" {
+		u := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+		resp, err := p.client.Get(u)
+		if err != nil {
+			return nil, fmt.Errorf("discovery failed: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("discovery failed: status %d", resp.StatusCode)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(m); err != nil {
+			return nil, fmt.Errorf("invalid discovery document: %w", err)
+		}
+		if m.Issuer != p.cfg.Issuer {
+			return nil, fmt.Errorf("issuer [%s] of discovery document is not [%s]", m.Issuer, p.cfg.Issuer)
+		}
+	}
+	override(&m.AuthURL, p.cfg.AuthURL)
+	override(&m.TokenURL, p.cfg.TokenURL)
+	override(&m.JWKS, p.cfg.JWKS)
+	override(&m.EndSessionURL, p.cfg.EndSessionURL)
+	if m.AuthURL == "" || m.TokenURL == "" || m.JWKS == "" {
+		return nil, fmt.Errorf("authorization, token and jwks endpoints are required")
+	}
+	verifier, err := jwt.New(&config.JWT{
+		Issuer:    m.Issuer,
+		Audiences: []string{p.cfg.ClientID},
+		JWKS:      m.JWKS,
+		Leeway:    p.cfg.Leeway,
+	})
+	if err != nil {
+		return nil, err
+	}
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	} else if !contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+	return &endpoints{
+		oauth2: &oauth2.Config{
+			ClientID:     p.cfg.ClientID,
+			ClientSecret: p.cfg.ClientSecret,
+			RedirectURL:  p.cfg.RedirectURL,
+			Scopes:       scopes,
+			Endpoint:     oauth2.Endpoint{AuthURL: m.AuthURL, TokenURL: m.TokenURL},
+		},
+		verifier:      verifier,
+		endSessionURL: m.EndSessionURL,
+	}, nil
+}
+
+func override(v *string, configured string) {
+	if configured != "" {
+		*v = configured
+	}
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
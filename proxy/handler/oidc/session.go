@@ -0,0 +1,142 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package oidc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//chunkSize keeps every cookie under the 4KB limit of browsers
+const chunkSize = 3800
+
+//ErrInvalidCookie means cookie is not encrypted by secret, or it is modified
+var ErrInvalidCookie = errors.New("invalid cookie")
+
+//session is saved in cookie after login
+type session struct {
+	Subject      string            `json:"sub"`
+	Headers      map[string]string `json:"hdr,omitempty"`
+	AccessToken  string            `json:"at,omitempty"`
+	RefreshToken string            `json:"rt,omitempty"`
+	IDToken      string            `json:"it,omitempty"`
+	//Expiry is when tokens must be refreshed
+	Expiry  time.Time `json:"exp"`
+	Created time.Time `json:"iat"`
+}
+
+//loginState is saved in cookie before redirecting user to identity provider
+type loginState struct {
+	Nonce    string    `json:"n"`
+	Verifier string    `json:"v"`
+	Return   string    `json:"r"`
+	Expires  time.Time `json:"e"`
+}
+
+//codec encrypts cookies with AES-GCM, cookie name is authenticated,
+//so a cookie can not be used under other name
+type codec struct {
+	aead cipher.AEAD
+}
+
+func newCodec(secret string) (*codec, error) {
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &codec{aead: aead}, nil
+}
+
+func (c *codec) encode(name string, v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(b)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(c.aead.Seal(nonce, nonce, b, []byte(name))), nil
+}
+
+func (c *codec) decode(name, value string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) < c.aead.NonceSize() {
+		return ErrInvalidCookie
+	}
+	n := c.aead.NonceSize()
+	plain, err := c.aead.Open(nil, b[:n], b[n:], []byte(name))
+	if err != nil {
+		return ErrInvalidCookie
+	}
+	return json.Unmarshal(plain, v)
+}
+
+//chunkName returns name of the i-th cookie of a value
+func chunkName(name string, i int) string {
+	if i == 0 {
+		return name
+	}
+	return name + "_" + strconv.Itoa(i)
+}
+
+//readChunks joins cookies of a value, and returns the number of cookies
+func readChunks(r *http.Request, name string) (string, int) {
+	var value string
+	n := 0
+	for ; ; n++ {
+		c, err := r.Cookie(chunkName(name, n))
+		if err != nil {
+			break
+		}
+		value += c.Value
+	}
+	return value, n
+}
+
+//writeChunks splits value into cookies, and removes the stale ones of an old value
+func writeChunks(h http.Header, c http.Cookie, value string, old int) {
+	name := c.Name
+	n := 0
+	for ; len(value) > 0; n++ {
+		size := chunkSize
+		if len(value) < size {
+			size = len(value)
+		}
+		c.Name, c.Value = chunkName(name, n), value[:size]
+		h.Add("Set-Cookie", c.String())
+		value = value[size:]
+	}
+	for ; n < old; n++ {
+		c.Name, c.Value, c.MaxAge = chunkName(name, n), "", -1
+		h.Add("Set-Cookie", c.String())
+	}
+}
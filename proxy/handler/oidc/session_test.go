@@ -0,0 +1,67 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package oidc
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodec(t *testing.T) {
+	c, err := newCodec("secret")
+	assert.NoError(t, err)
+	v, err := c.encode("session", &session{Subject: "jason"})
+	assert.NoError(t, err)
+
+	s := &session{}
+	assert.NoError(t, c.decode("session", v, s))
+	assert.Equal(t, "jason", s.Subject)
+	t.Run("other name", func(t *testing.T) {
+		assert.Equal(t, ErrInvalidCookie, c.decode("state", v, &session{}))
+	})
+	t.Run("modified", func(t *testing.T) {
+		assert.Equal(t, ErrInvalidCookie, c.decode("session", v[:len(v)-2]+"AA", &session{}))
+		assert.Equal(t, ErrInvalidCookie, c.decode("session", "!", &session{}))
+	})
+	t.Run("other secret", func(t *testing.T) {
+		other, err := newCodec("other")
+		assert.NoError(t, err)
+		assert.Equal(t, ErrInvalidCookie, other.decode("session", v, &session{}))
+	})
+}
+
+func TestChunks(t *testing.T) {
+	value := strings.Repeat("a", chunkSize*2+10)
+	h := http.Header{}
+	writeChunks(h, http.Cookie{Name: "s", Path: "/"}, value, 4)
+	cookies := (&http.Response{Header: h}).Cookies()
+	assert.Len(t, cookies, 4)
+	assert.Equal(t, "s_3", cookies[3].Name)
+	assert.Equal(t, -1, cookies[3].MaxAge)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://shop.com/", nil)
+	for _, c := range cookies[:3] {
+		req.AddCookie(c)
+	}
+	got, n := readChunks(req, "s")
+	assert.Equal(t, value, got)
+	assert.Equal(t, 3, n)
+}
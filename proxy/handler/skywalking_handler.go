@@ -29,11 +29,13 @@ import (
 )
 
 const (
-	HTTPPrefix = "http://"
+	HTTPPrefix  = "http://"
+	DubboPrefix = "dubbo://"
 )
 
 const (
 	HTTPClientComponentID  = 2
+	DubboComponentID       = 3
 	ServiceCombComponentID = 28
 	HTTPServerComponentID  = 49
 )
@@ -51,11 +53,7 @@ func (sp *SkyWalkingProviderHandler) Handle(chain *handler.Chain, i *invocation.
 	}
 	chain.Next(i, func(r *invocation.Response) {
 		cb(r)
-		span.Tag(go2sky.TagHTTPMethod, i.Protocol)
-		span.Tag(go2sky.TagURL, HTTPPrefix+i.MicroServiceName+i.URLPath)
-		span.Tag(go2sky.TagStatusCode, strconv.Itoa(r.Status))
-		span.SetSpanLayer(skycom.SpanLayer_Http)
-		span.SetComponent(HTTPServerComponentID)
+		tagSpan(span, i, r, HTTPServerComponentID)
 		span.End()
 	})
 }
@@ -87,17 +85,8 @@ func (sc *SkyWalkingConsumerHandler) Handle(chain *handler.Chain, i *invocation.
 	}
 	chain.Next(i, func(r *invocation.Response) {
 		cb(r)
-		span.Tag(go2sky.TagHTTPMethod, i.Protocol)
-		span.Tag(go2sky.TagURL, HTTPPrefix+i.MicroServiceName+i.URLPath)
-		span.Tag(go2sky.TagStatusCode, strconv.Itoa(r.Status))
-		span.SetSpanLayer(skycom.SpanLayer_Http)
-		span.SetComponent(HTTPServerComponentID)
-
-		spanExit.Tag(go2sky.TagHTTPMethod, i.Protocol)
-		spanExit.Tag(go2sky.TagURL, HTTPPrefix+i.MicroServiceName+i.URLPath)
-		spanExit.Tag(go2sky.TagStatusCode, strconv.Itoa(r.Status))
-		spanExit.SetSpanLayer(skycom.SpanLayer_Http)
-		spanExit.SetComponent(HTTPClientComponentID)
+		tagSpan(span, i, r, HTTPServerComponentID)
+		tagSpan(spanExit, i, r, HTTPClientComponentID)
 
 		spanExit.End()
 		span.End()
@@ -105,6 +94,23 @@ func (sc *SkyWalkingConsumerHandler) Handle(chain *handler.Chain, i *invocation.
 	})
 }
 
+//tagSpan set span layer and tags according to the protocol of invocation
+func tagSpan(span go2sky.Span, i *invocation.Invocation, r *invocation.Response, httpComponentID int32) {
+	switch i.Protocol {
+	case "dubbo":
+		span.Tag(go2sky.TagURL, DubboPrefix+skywalking.OperationName(i))
+		span.Tag(go2sky.TagStatusCode, strconv.Itoa(r.Status))
+		span.SetSpanLayer(skycom.SpanLayer_RPCFramework)
+		span.SetComponent(DubboComponentID)
+	default:
+		span.Tag(go2sky.TagHTTPMethod, i.Protocol)
+		span.Tag(go2sky.TagURL, HTTPPrefix+i.MicroServiceName+i.URLPath)
+		span.Tag(go2sky.TagStatusCode, strconv.Itoa(r.Status))
+		span.SetSpanLayer(skycom.SpanLayer_Http)
+		span.SetComponent(httpComponentID)
+	}
+}
+
 //Name return consumer name
 func (sc *SkyWalkingConsumerHandler) Name() string {
 	return skywalking.SkyWalkingConsumer
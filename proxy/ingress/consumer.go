@@ -0,0 +1,62 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingress
+
+import (
+	"context"
+
+	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+)
+
+type consumerKey struct{}
+
+//WithConsumer returns a context carrying the consumer of request, it is set by auth handlers like apikey,
+//gateways use it in rate limit, metrics and access log
+func WithConsumer(ctx context.Context, consumer string) context.Context {
+	return context.WithValue(ctx, consumerKey{}, consumer)
+}
+
+//Consumer returns the consumer of request, it is empty if request is anonymous
+func Consumer(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	c, _ := ctx.Value(consumerKey{}).(string)
+	return c
+}
+
+//AccessLogTags returns tags of access log record of an ingress request to host
+func AccessLogTags(inv *invocation.Invocation, host string) map[string]string {
+	tags := map[string]string{"host": host, "version": inv.RouteTags.Version()}
+	if consumer := Consumer(inv.Ctx); consumer != "" {
+		tags["consumer"] = consumer
+	}
+	return tags
+}
+
+//RecordConsumer counts requests of consumer with the status returned to client,
+//anonymous requests are not counted
+func RecordConsumer(protocol string, inv *invocation.Invocation, status string) {
+	consumer := Consumer(inv.Ctx)
+	if consumer == "" {
+		return
+	}
+	metrics.RecordConsumerRequest(protocol, map[string]string{metrics.LConsumer: consumer,
+		metrics.LServiceName: inv.MicroServiceName, metrics.LStatus: status})
+}
@@ -26,13 +26,14 @@ import (
 
 //error in ingress package
 var (
-	ErrNotMatch = errors.New("no matching rule")
+	ErrNotMatch    = errors.New("no matching rule")
+	ErrRateLimited = errors.New("too many requests")
 )
 var plugin = make(map[string]func() (RuleFetcher, error))
 
 //RuleFetcher query ingress rule
 type RuleFetcher interface {
-	Fetch(protocol, host, apiPath string, headers map[string][]string) (*config.IngressRule, error)
+	Fetch(protocol, method, host, apiPath string, query, headers map[string][]string) (*config.IngressRule, error)
 }
 
 //DefaultFetcher fetch config
@@ -0,0 +1,494 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+//Package kubernetes fetches ingress rules from Kubernetes Ingress and Gateway API HTTPRoute,
+//so that mesher in edge mode works as an ingress controller
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/ingress"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/openlog"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+//Name is the name of fetcher plugin
+const Name = "kubernetes"
+
+//config keys of kubernetes fetcher
+const (
+	KeyKubeConfig       = "mesher.ingress.kubernetes.kubeconfig"
+	KeyNamespace        = "mesher.ingress.kubernetes.namespace"
+	KeyIngressClass     = "mesher.ingress.kubernetes.ingressClass"
+	KeyHTTPRoute        = "mesher.ingress.kubernetes.httpRoute.enable"
+	KeyHTTPRouteVersion = "mesher.ingress.kubernetes.httpRoute.version"
+	KeyGateway          = "mesher.ingress.kubernetes.gateway"
+	KeyStatusAddress    = "mesher.ingress.kubernetes.statusAddress"
+	KeySyncTimeout      = "mesher.ingress.kubernetes.syncTimeout"
+)
+
+//default values of kubernetes fetcher
+const (
+	DefaultIngressClass     = "mesher"
+	DefaultGateway          = "mesher"
+	DefaultHTTPRouteVersion = "v1"
+	DefaultSyncTimeout      = 30 * time.Second
+)
+
+//ControllerName is written in status of HTTPRoute
+const ControllerName = "servicecomb.apache.org/mesher"
+
+//AnnotationIngressClass is the legacy annotation of ingress class
+const AnnotationIngressClass = "kubernetes.io/ingress.class"
+
+const resyncPeriod = 10 * time.Minute
+
+//ErrNotSynced means objects can not be listed from kubernetes
+var ErrNotSynced = errors.New("can not sync ingress objects from kubernetes")
+
+//ErrConflicted means rules of an object conflict with rules of an older object
+var ErrConflicted = errors.New("rules conflict with an older object")
+
+//Options is the options of fetcher
+type Options struct {
+	//Namespace to watch, empty means all namespaces
+	Namespace string
+	//IngressClass of Ingress to serve, empty means all Ingress
+	IngressClass string
+	//HTTPRoute enables watching HTTPRoute
+	HTTPRoute bool
+	//HTTPRouteVersion is the api version of gateway.networking.k8s.io
+	HTTPRouteVersion string
+	//Gateway is the name of Gateway which HTTPRoute must attach to, empty means all HTTPRoute
+	Gateway string
+	//StatusAddress is written in status of Ingress, empty means status is not written
+	StatusAddress string
+	//SyncTimeout is the max time waiting for first listing of objects
+	SyncTimeout time.Duration
+}
+
+//Fetcher watches Ingress and HTTPRoute, and translates them into ingress rules
+type Fetcher struct {
+	opts      Options
+	client    clientset.Interface
+	dynamic   dynamic.Interface
+	gvr       schema.GroupVersionResource
+	ingresses cache.SharedIndexInformer
+	routes    cache.SharedIndexInformer
+	//router holds *ingress.Router
+	router  atomic.Value
+	changed chan struct{}
+}
+
+//New returns a fetcher, call Start to watch objects
+func New(client clientset.Interface, dc dynamic.Interface, opts Options) *Fetcher {
+	if opts.HTTPRouteVersion == "" {
+		opts.HTTPRouteVersion = DefaultHTTPRouteVersion
+	}
+	if opts.SyncTimeout == 0 {
+		opts.SyncTimeout = DefaultSyncTimeout
+	}
+	f := &Fetcher{
+		opts:    opts,
+		client:  client,
+		dynamic: dc,
+		gvr:     HTTPRouteResource(opts.HTTPRouteVersion),
+		changed: make(chan struct{}, 1),
+	}
+	f.router.Store(ingress.NewRouter(nil))
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { f.notify() },
+		UpdateFunc: func(old, obj interface{}) { f.notify() },
+		DeleteFunc: func(obj interface{}) { f.notify() },
+	}
+	f.ingresses = informers.NewSharedInformerFactoryWithOptions(client, resyncPeriod,
+		informers.WithNamespace(opts.Namespace)).Networking().V1().Ingresses().Informer()
+	f.ingresses.AddEventHandler(handler)
+	if opts.HTTPRoute {
+		f.routes = dynamicinformer.NewFilteredDynamicSharedInformerFactory(dc, resyncPeriod,
+			opts.Namespace, nil).ForResource(f.gvr).Informer()
+		f.routes.AddEventHandler(handler)
+	}
+	return f
+}
+
+//HTTPRouteResource returns the resource of HTTPRoute
+func HTTPRouteResource(version string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: version, Resource: "httproutes"}
+}
+
+//Start watches objects until stop is closed, it returns after rules are loaded
+func (f *Fetcher) Start(stop <-chan struct{}) error {
+	go f.ingresses.Run(stop)
+	synced := []cache.InformerSynced{f.ingresses.HasSynced}
+	if f.routes != nil {
+		go f.routes.Run(stop)
+		synced = append(synced, f.routes.HasSynced)
+	}
+	timeout := make(chan struct{})
+	timer := time.AfterFunc(f.opts.SyncTimeout, func() { close(timeout) })
+	defer timer.Stop()
+	if !cache.WaitForCacheSync(timeout, synced...) {
+		return ErrNotSynced
+	}
+	f.sync()
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-f.changed:
+				f.sync()
+			}
+		}
+	}()
+	return nil
+}
+
+//Fetch get ingress rule
+func (f *Fetcher) Fetch(protocol, method, host, apiPath string, query, headers map[string][]string) (*config.IngressRule, error) {
+	//Ingress and HTTPRoute only hold http rules
+	if protocol != "http" {
+		return nil, ingress.ErrNotMatch
+	}
+	r := f.router.Load().(*ingress.Router).Match(method, host, apiPath, query, headers)
+	if r == nil {
+		return nil, ingress.ErrNotMatch
+	}
+	return r, nil
+}
+
+func (f *Fetcher) notify() {
+	select {
+	case f.changed <- struct{}{}:
+	default:
+	}
+}
+
+//object is an Ingress or HTTPRoute with its rules
+type object struct {
+	key     string
+	created metav1.Time
+	rules   []*rule
+	routes  []*route
+	//invalid is the reason why rules of object are not served
+	invalid error
+	ing     *networkingv1.Ingress
+	u       *unstructured.Unstructured
+	hr      *HTTPRoute
+	parents []ParentReference
+}
+
+//sync translates all objects into rules, objects with invalid rules are ignored,
+//and when rules of different objects conflict, the older object wins
+func (f *Fetcher) sync() {
+	objs := f.objects()
+	sort.SliceStable(objs, func(i, j int) bool {
+		if !objs[i].created.Equal(&objs[j].created) {
+			return objs[i].created.Before(&objs[j].created)
+		}
+		return objs[i].key < objs[j].key
+	})
+	var rules []*rule
+	var served []*route
+	owners := make(map[string]string)
+	for _, o := range objs {
+		if o.invalid != nil {
+			continue
+		}
+		if o.invalid = accept(served, owners, o); o.invalid == nil {
+			rules = append(rules, o.rules...)
+			served = append(served, o.routes...)
+			for _, r := range o.rules {
+				owners[r.id()] = o.key
+			}
+		}
+	}
+	routes, err := ingress.Compile(sortRules(rules))
+	if err != nil {
+		openlog.Error("invalid ingress rules, keep old rules: " + err.Error())
+		return
+	}
+	f.router.Store(ingress.NewRouter(routes))
+	openlog.Info("update ingress rules", openlog.WithTags(openlog.Tags{
+		"rules": len(routes),
+	}))
+	//status is written only after rules are served
+	for _, o := range objs {
+		if o.hr != nil {
+			f.updateRouteStatus(o.u, o.hr, o.parents, o.invalid)
+			continue
+		}
+		if o.invalid != nil {
+			openlog.Error("invalid ingress", openlog.WithTags(openlog.Tags{
+				"name": o.key,
+				"err":  o.invalid.Error(),
+			}))
+			continue
+		}
+		f.updateIngressStatus(o.ing)
+	}
+}
+
+//objects returns served Ingress and HTTPRoute objects
+func (f *Fetcher) objects() []*object {
+	var objs []*object
+	for _, obj := range f.ingresses.GetStore().List() {
+		ing, ok := obj.(*networkingv1.Ingress)
+		if !ok || !f.servesIngress(ing) {
+			continue
+		}
+		rs := translateIngress(ing)
+		routes, err := compile(rs)
+		objs = append(objs, &object{
+			key: ing.Namespace + "/" + ing.Name, created: ing.CreationTimestamp,
+			rules: rs, routes: routes, invalid: err, ing: ing,
+		})
+	}
+	if f.routes == nil {
+		return objs
+	}
+	for _, obj := range f.routes.GetStore().List() {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		hr := &HTTPRoute{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, hr); err != nil {
+			openlog.Error("invalid HTTPRoute: " + err.Error())
+			continue
+		}
+		parents := f.parents(hr)
+		if len(parents) == 0 {
+			continue
+		}
+		rs := translateHTTPRoute(hr)
+		routes, err := compile(rs)
+		objs = append(objs, &object{
+			key: hr.Namespace + "/" + hr.Name, created: hr.CreationTimestamp,
+			rules: rs, routes: routes, invalid: err, u: u, hr: hr, parents: parents,
+		})
+	}
+	return objs
+}
+
+//route is a rule compiled with rules of its object, so that it is checked with rules of other objects
+//without compiling all rules again
+type route struct {
+	*rule
+	compiled *ingress.Route
+}
+
+//shadows checks if r is matched before o and matches all requests of o,
+//routes are matched by priority and then in the order of rules
+func (r *route) shadows(o *route) bool {
+	first := r.Priority > o.Priority || r.Priority == o.Priority && r.before(o.rule)
+	return first && r.compiled.Covers(o.compiled)
+}
+
+//compile checks rules of an object and returns them with their compiled routes,
+//a rule with same conditions as an earlier rule of the object is not compiled
+func compile(rules []*rule) ([]*route, error) {
+	compiled, err := ingress.Compile(sortRules(append([]*rule(nil), rules...)))
+	if err != nil {
+		return nil, err
+	}
+	byRule := make(map[*config.IngressRule]*ingress.Route, len(compiled))
+	for _, c := range compiled {
+		byRule[c.Rule] = c
+	}
+	routes := make([]*route, 0, len(compiled))
+	for _, r := range rules {
+		if c, ok := byRule[r.IngressRule]; ok {
+			routes = append(routes, &route{rule: r, compiled: c})
+		}
+	}
+	return routes, nil
+}
+
+//accept checks if rules of object can be served with served rules of older objects,
+//owners maps the conditions of served rules to their objects
+func accept(served []*route, owners map[string]string, o *object) error {
+	for _, r := range o.rules {
+		if owner, ok := owners[r.id()]; ok {
+			return fmt.Errorf("%w: rule of %s has same conditions", ErrConflicted, owner)
+		}
+	}
+	for _, r := range o.routes {
+		for _, s := range served {
+			if s.shadows(r) {
+				return fmt.Errorf("%w: rule is shadowed by a rule of %s", ErrConflicted, s.key)
+			}
+			if r.shadows(s) {
+				return fmt.Errorf("%w: rule shadows a rule of %s", ErrConflicted, s.key)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *Fetcher) servesIngress(ing *networkingv1.Ingress) bool {
+	if f.opts.IngressClass == "" {
+		return true
+	}
+	if ing.Spec.IngressClassName != nil {
+		return *ing.Spec.IngressClassName == f.opts.IngressClass
+	}
+	return ing.Annotations[AnnotationIngressClass] == f.opts.IngressClass
+}
+
+//parents returns parent references of route which are served by mesher
+func (f *Fetcher) parents(hr *HTTPRoute) []ParentReference {
+	var parents []ParentReference
+	for _, p := range hr.Spec.ParentRefs {
+		if p.Kind != nil && *p.Kind != "Gateway" {
+			continue
+		}
+		if f.opts.Gateway == "" || p.Name == f.opts.Gateway {
+			parents = append(parents, p)
+		}
+	}
+	return parents
+}
+
+func (f *Fetcher) updateIngressStatus(ing *networkingv1.Ingress) {
+	if f.opts.StatusAddress == "" {
+		return
+	}
+	lb := corev1.LoadBalancerIngress{Hostname: f.opts.StatusAddress}
+	if net.ParseIP(f.opts.StatusAddress) != nil {
+		lb = corev1.LoadBalancerIngress{IP: f.opts.StatusAddress}
+	}
+	desired := []corev1.LoadBalancerIngress{lb}
+	if reflect.DeepEqual(ing.Status.LoadBalancer.Ingress, desired) {
+		return
+	}
+	ing = ing.DeepCopy()
+	ing.Status.LoadBalancer.Ingress = desired
+	_, err := f.client.NetworkingV1().Ingresses(ing.Namespace).UpdateStatus(context.Background(), ing, metav1.UpdateOptions{})
+	if err != nil {
+		openlog.Error("update ingress status failed: " + err.Error())
+	}
+}
+
+func (f *Fetcher) updateRouteStatus(u *unstructured.Unstructured, hr *HTTPRoute, parents []ParentReference, invalid error) {
+	cond := metav1.Condition{
+		Type:               "Accepted",
+		Status:             metav1.ConditionTrue,
+		Reason:             "Accepted",
+		ObservedGeneration: hr.Generation,
+	}
+	if invalid != nil {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "UnsupportedValue"
+		if errors.Is(invalid, ErrConflicted) {
+			cond.Reason = "Conflicted"
+		}
+		cond.Message = invalid.Error()
+	}
+	status := HTTPRouteStatus{}
+	for _, p := range hr.Status.Parents {
+		if p.ControllerName != ControllerName {
+			status.Parents = append(status.Parents, p)
+		}
+	}
+	for _, ref := range parents {
+		c := cond
+		c.LastTransitionTime = metav1.Now()
+		for _, old := range hr.Status.Parents {
+			if old.ControllerName == ControllerName && reflect.DeepEqual(old.ParentRef, ref) &&
+				len(old.Conditions) == 1 && sameCondition(old.Conditions[0], cond) {
+				c.LastTransitionTime = old.Conditions[0].LastTransitionTime
+			}
+		}
+		status.Parents = append(status.Parents, RouteParentStatus{
+			ParentRef: ref, ControllerName: ControllerName, Conditions: []metav1.Condition{c},
+		})
+	}
+	if reflect.DeepEqual(status, hr.Status) {
+		return
+	}
+	s, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		openlog.Error("invalid HTTPRoute status: " + err.Error())
+		return
+	}
+	u = u.DeepCopy()
+	u.Object["status"] = s
+	_, err = f.dynamic.Resource(f.gvr).Namespace(u.GetNamespace()).UpdateStatus(context.Background(), u, metav1.UpdateOptions{})
+	if err != nil {
+		openlog.Error("update HTTPRoute status failed: " + err.Error())
+	}
+}
+
+func sameCondition(a, b metav1.Condition) bool {
+	return a.Type == b.Type && a.Status == b.Status && a.Reason == b.Reason &&
+		a.Message == b.Message && a.ObservedGeneration == b.ObservedGeneration
+}
+
+func newFetcher() (ingress.RuleFetcher, error) {
+	cfg, err := clientcmd.BuildConfigFromFlags("", archaius.GetString(KeyKubeConfig, ""))
+	if err != nil {
+		return nil, err
+	}
+	client, err := clientset.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dc, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	f := New(client, dc, Options{
+		Namespace:        archaius.GetString(KeyNamespace, ""),
+		IngressClass:     archaius.GetString(KeyIngressClass, DefaultIngressClass),
+		HTTPRoute:        archaius.GetBool(KeyHTTPRoute, false),
+		HTTPRouteVersion: archaius.GetString(KeyHTTPRouteVersion, DefaultHTTPRouteVersion),
+		Gateway:          archaius.GetString(KeyGateway, DefaultGateway),
+		StatusAddress:    archaius.GetString(KeyStatusAddress, ""),
+		SyncTimeout:      config.GetDuration(KeySyncTimeout, DefaultSyncTimeout),
+	})
+	if err := f.Start(make(chan struct{})); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func init() {
+	ingress.InstallPlugin(Name, newFetcher)
+}
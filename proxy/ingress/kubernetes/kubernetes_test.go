@@ -0,0 +1,236 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kubernetes_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/ingress"
+	"github.com/apache/servicecomb-mesher/proxy/ingress/kubernetes"
+	"github.com/go-chassis/go-archaius"
+	"github.com/stretchr/testify/assert"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newIngress(name, class, path, service string) *networkingv1.Ingress {
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: &class,
+			Rules: []networkingv1.IngressRule{{
+				Host: "shop.com",
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{Path: path, Backend: networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{Name: service}}}},
+				}},
+			}},
+		},
+	}
+}
+
+func newHTTPRoute(name, gateway string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "HTTPRoute",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": name, "generation": int64(2)},
+		"spec": map[string]interface{}{
+			"parentRefs": []interface{}{map[string]interface{}{"name": gateway}},
+			"hostnames":  []interface{}{"api.com"},
+			"rules": []interface{}{map[string]interface{}{
+				"matches":     []interface{}{map[string]interface{}{"path": map[string]interface{}{"type": "PathPrefix", "value": "/" + name}}},
+				"backendRefs": []interface{}{map[string]interface{}{"name": name, "port": int64(8080)}},
+			}},
+		},
+	}}
+}
+
+func newFetcher(t *testing.T, stop chan struct{}, opts kubernetes.Options, objs ...runtime.Object) (*kubernetes.Fetcher, *fake.Clientset, *dynamicfake.FakeDynamicClient) {
+	var typed, dynamic []runtime.Object
+	for _, o := range objs {
+		if _, ok := o.(*unstructured.Unstructured); ok {
+			dynamic = append(dynamic, o)
+		} else {
+			typed = append(typed, o)
+		}
+	}
+	client := fake.NewSimpleClientset(typed...)
+	dc := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{kubernetes.HTTPRouteResource("v1"): "HTTPRouteList"}, dynamic...)
+	f := kubernetes.New(client, dc, opts)
+	assert.NoError(t, f.Start(stop))
+	return f, client, dc
+}
+
+func TestFetcher_Ingress(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	f, client, _ := newFetcher(t, stop, kubernetes.Options{IngressClass: "mesher", StatusAddress: "10.0.0.1"},
+		newIngress("order", "mesher", "/orders", "order"),
+		newIngress("other", "nginx", "/users", "user"))
+
+	r, err := f.Fetch("http", http.MethodGet, "shop.com", "/orders/1", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "order", r.Service.Name)
+	_, err = f.Fetch("http", http.MethodGet, "shop.com", "/users", nil, nil)
+	assert.Equal(t, ingress.ErrNotMatch, err)
+
+	t.Run("status is written", func(t *testing.T) {
+		assert.Eventually(t, func() bool {
+			ing, err := client.NetworkingV1().Ingresses("default").Get(context.Background(), "order", metav1.GetOptions{})
+			return err == nil && len(ing.Status.LoadBalancer.Ingress) == 1 && ing.Status.LoadBalancer.Ingress[0].IP == "10.0.0.1"
+		}, 3*time.Second, 10*time.Millisecond)
+		ing, err := client.NetworkingV1().Ingresses("default").Get(context.Background(), "other", metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Empty(t, ing.Status.LoadBalancer.Ingress)
+	})
+	t.Run("update", func(t *testing.T) {
+		_, err := client.NetworkingV1().Ingresses("default").Create(context.Background(),
+			newIngress("user", "mesher", "/users", "user"), metav1.CreateOptions{})
+		assert.NoError(t, err)
+		assert.Eventually(t, func() bool {
+			r, err := f.Fetch("http", http.MethodGet, "shop.com", "/users", nil, nil)
+			return err == nil && r.Service.Name == "user"
+		}, 3*time.Second, 10*time.Millisecond)
+		assert.NoError(t, client.NetworkingV1().Ingresses("default").Delete(context.Background(), "order", metav1.DeleteOptions{}))
+		assert.Eventually(t, func() bool {
+			_, err := f.Fetch("http", http.MethodGet, "shop.com", "/orders", nil, nil)
+			return err == ingress.ErrNotMatch
+		}, 3*time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestFetcher_HTTPRoute(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	f, _, dc := newFetcher(t, stop, kubernetes.Options{HTTPRoute: true, Gateway: "mesher"},
+		newHTTPRoute("orders", "mesher"), newHTTPRoute("users", "istio"))
+
+	r, err := f.Fetch("http", http.MethodGet, "api.com", "/orders/1", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "orders", r.Service.Name)
+	assert.Equal(t, "8080", r.Service.Port.Value)
+	_, err = f.Fetch("http", http.MethodGet, "api.com", "/users", nil, nil)
+	assert.Equal(t, ingress.ErrNotMatch, err)
+
+	routes := dc.Resource(kubernetes.HTTPRouteResource("v1")).Namespace("default")
+	assert.Eventually(t, func() bool {
+		u, err := routes.Get(context.Background(), "orders", metav1.GetOptions{})
+		if err != nil {
+			return false
+		}
+		parents, _, _ := unstructured.NestedSlice(u.Object, "status", "parents")
+		if len(parents) != 1 {
+			return false
+		}
+		p := parents[0].(map[string]interface{})
+		conditions := p["conditions"].([]interface{})
+		c := conditions[0].(map[string]interface{})
+		return p["controllerName"] == kubernetes.ControllerName && c["type"] == "Accepted" &&
+			c["status"] == "True" && c["observedGeneration"] == int64(2)
+	}, 3*time.Second, 10*time.Millisecond)
+	u, err := routes.Get(context.Background(), "users", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Nil(t, u.Object["status"])
+}
+
+//newHeaderRoute returns a HTTPRoute matching /api with header x-env: canary
+func newHeaderRoute(name string, created time.Time) *unstructured.Unstructured {
+	u := newHTTPRoute(name, "mesher")
+	u.SetCreationTimestamp(metav1.NewTime(created))
+	rules := []interface{}{map[string]interface{}{
+		"matches": []interface{}{map[string]interface{}{
+			"path":    map[string]interface{}{"type": "PathPrefix", "value": "/api"},
+			"headers": []interface{}{map[string]interface{}{"name": "x-env", "value": "canary"}},
+		}},
+		"backendRefs": []interface{}{map[string]interface{}{"name": name, "port": int64(8080)}},
+	}}
+	_ = unstructured.SetNestedSlice(u.Object, rules, "spec", "rules")
+	return u
+}
+
+func routeCondition(dc *dynamicfake.FakeDynamicClient, name string) map[string]interface{} {
+	u, err := dc.Resource(kubernetes.HTTPRouteResource("v1")).Namespace("default").Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil
+	}
+	parents, _, _ := unstructured.NestedSlice(u.Object, "status", "parents")
+	if len(parents) != 1 {
+		return nil
+	}
+	conditions := parents[0].(map[string]interface{})["conditions"].([]interface{})
+	return conditions[0].(map[string]interface{})
+}
+
+func TestFetcher_Conflict(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	now := time.Now().Truncate(time.Second)
+	f, _, dc := newFetcher(t, stop, kubernetes.Options{HTTPRoute: true, Gateway: "mesher"},
+		newHeaderRoute("canary-new", now), newHeaderRoute("canary-old", now.Add(-time.Hour)))
+	headers := map[string][]string{"X-Env": {"canary"}}
+
+	//the older route wins
+	r, err := f.Fetch("http", http.MethodGet, "api.com", "/api/1", nil, headers)
+	assert.NoError(t, err)
+	assert.Equal(t, "canary-old", r.Service.Name)
+	assert.Eventually(t, func() bool {
+		c := routeCondition(dc, "canary-old")
+		return c != nil && c["status"] == "True"
+	}, 3*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		c := routeCondition(dc, "canary-new")
+		return c != nil && c["status"] == "False" && c["reason"] == "Conflicted"
+	}, 3*time.Second, 10*time.Millisecond)
+
+	t.Run("rules are still updated", func(t *testing.T) {
+		routes := dc.Resource(kubernetes.HTTPRouteResource("v1")).Namespace("default")
+		_, err := routes.Create(context.Background(), newHTTPRoute("orders", "mesher"), metav1.CreateOptions{})
+		assert.NoError(t, err)
+		assert.Eventually(t, func() bool {
+			r, err := f.Fetch("http", http.MethodGet, "api.com", "/orders", nil, nil)
+			return err == nil && r.Service.Name == "orders"
+		}, 3*time.Second, 10*time.Millisecond)
+		assert.NoError(t, routes.Delete(context.Background(), "canary-old", metav1.DeleteOptions{}))
+		assert.Eventually(t, func() bool {
+			r, err := f.Fetch("http", http.MethodGet, "api.com", "/api/1", nil, headers)
+			return err == nil && r.Service.Name == "canary-new"
+		}, 3*time.Second, 10*time.Millisecond)
+		assert.Eventually(t, func() bool {
+			c := routeCondition(dc, "canary-new")
+			return c != nil && c["status"] == "True"
+		}, 3*time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestNewFetcher(t *testing.T) {
+	assert.NoError(t, archaius.Init(archaius.WithMemorySource()))
+	assert.NoError(t, archaius.Set(kubernetes.KeyKubeConfig, "../../../tests/util/sample_kubeconfig"))
+	assert.NoError(t, archaius.Set(kubernetes.KeySyncTimeout, "1s"))
+	assert.NoError(t, archaius.Set("mesher.ingress.type", kubernetes.Name))
+	//sample cluster is not reachable
+	assert.Error(t, ingress.Init())
+}
@@ -0,0 +1,360 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kubernetes
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//path match types of Ingress and HTTPRoute
+const (
+	PathExact             = "Exact"
+	PathPrefix            = "Prefix"
+	PathPathPrefix        = "PathPrefix"
+	PathRegularExpression = "RegularExpression"
+)
+
+//HTTPRoute is the part of gateway.networking.k8s.io HTTPRoute used by mesher
+type HTTPRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              HTTPRouteSpec   `json:"spec"`
+	Status            HTTPRouteStatus `json:"status,omitempty"`
+}
+
+//HTTPRouteSpec is the spec of HTTPRoute
+type HTTPRouteSpec struct {
+	ParentRefs []ParentReference `json:"parentRefs,omitempty"`
+	Hostnames  []string          `json:"hostnames,omitempty"`
+	Rules      []HTTPRouteRule   `json:"rules,omitempty"`
+}
+
+//ParentReference is the gateway a route attaches to
+type ParentReference struct {
+	Group       *string `json:"group,omitempty"`
+	Kind        *string `json:"kind,omitempty"`
+	Namespace   *string `json:"namespace,omitempty"`
+	Name        string  `json:"name"`
+	SectionName *string `json:"sectionName,omitempty"`
+}
+
+//HTTPRouteRule is a rule of HTTPRoute
+type HTTPRouteRule struct {
+	Matches     []HTTPRouteMatch  `json:"matches,omitempty"`
+	Filters     []HTTPRouteFilter `json:"filters,omitempty"`
+	BackendRefs []BackendRef      `json:"backendRefs,omitempty"`
+}
+
+//HTTPRouteMatch is the condition of a rule
+type HTTPRouteMatch struct {
+	Path        *ValueMatch  `json:"path,omitempty"`
+	Headers     []ValueMatch `json:"headers,omitempty"`
+	QueryParams []ValueMatch `json:"queryParams,omitempty"`
+	Method      *string      `json:"method,omitempty"`
+}
+
+//ValueMatch matches path, header or query parameter
+type ValueMatch struct {
+	Type  *string `json:"type,omitempty"`
+	Name  string  `json:"name,omitempty"`
+	Value string  `json:"value,omitempty"`
+}
+
+//HTTPRouteFilter only URLRewrite is supported
+type HTTPRouteFilter struct {
+	Type       string      `json:"type"`
+	URLRewrite *URLRewrite `json:"urlRewrite,omitempty"`
+}
+
+//URLRewrite rewrites the path
+type URLRewrite struct {
+	Path *PathModifier `json:"path,omitempty"`
+}
+
+//PathModifier is ReplaceFullPath or ReplacePrefixMatch
+type PathModifier struct {
+	Type               string  `json:"type"`
+	ReplaceFullPath    *string `json:"replaceFullPath,omitempty"`
+	ReplacePrefixMatch *string `json:"replacePrefixMatch,omitempty"`
+}
+
+//BackendRef is a weighted service
+type BackendRef struct {
+	Kind   *string `json:"kind,omitempty"`
+	Name   string  `json:"name"`
+	Port   *int32  `json:"port,omitempty"`
+	Weight *int32  `json:"weight,omitempty"`
+}
+
+//HTTPRouteStatus is the status of HTTPRoute
+type HTTPRouteStatus struct {
+	Parents []RouteParentStatus `json:"parents,omitempty"`
+}
+
+//RouteParentStatus is the status of route on a gateway
+type RouteParentStatus struct {
+	ParentRef      ParentReference    `json:"parentRef"`
+	ControllerName string             `json:"controllerName"`
+	Conditions     []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//rule is a translated rule with the information to order it
+type rule struct {
+	*config.IngressRule
+	exact   bool
+	prefix  int
+	created metav1.Time
+	key     string
+}
+
+//sortRules orders rules like Gateway API, see before
+func sortRules(rules []*rule) []*config.IngressRule {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].before(rules[j])
+	})
+	result := make([]*config.IngressRule, 0, len(rules))
+	seen := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		//same conditions in different objects, the older one wins
+		id := r.id()
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, r.IngressRule)
+	}
+	return result
+}
+
+//before orders rules like Gateway API, exact host first, then exact path, longest prefix,
+//rules with method, more headers, more query parameters, older objects, and object name
+func (r *rule) before(o *rule) bool {
+	if ha, hb := hostRank(r.Host), hostRank(o.Host); ha != hb {
+		return ha < hb
+	}
+	if r.exact != o.exact {
+		return r.exact
+	}
+	if r.prefix != o.prefix {
+		return r.prefix > o.prefix
+	}
+	if (len(r.Methods) == 0) != (len(o.Methods) == 0) {
+		return len(r.Methods) != 0
+	}
+	if len(r.Headers) != len(o.Headers) {
+		return len(r.Headers) > len(o.Headers)
+	}
+	if len(r.Query) != len(o.Query) {
+		return len(r.Query) > len(o.Query)
+	}
+	if !r.created.Equal(&o.created) {
+		return r.created.Before(&o.created)
+	}
+	return r.key < o.key
+}
+
+//id identifies the conditions of rule, rules with same id match same requests
+func (r *rule) id() string {
+	return fmt.Sprint(r.Priority, " ", r.Host, " ", strings.Join(r.Methods, ","), " ", r.APIPath, " ", r.Headers, " ", r.Query)
+}
+
+func hostRank(host string) int {
+	switch {
+	case host == "":
+		return 2
+	case strings.HasPrefix(host, "*."):
+		return 1
+	}
+	return 0
+}
+
+//pathRule returns the api path of a path match, and whether it is exact, and the length of prefix
+func pathRule(pathType, path string) (string, bool, int) {
+	switch pathType {
+	case PathExact:
+		return "^" + regexp.QuoteMeta(path) + "$", true, len(path)
+	case PathRegularExpression:
+		return "^(?:" + path + ")$", false, 0
+	}
+	p := strings.TrimSuffix(path, "/")
+	if p == "" {
+		return "^/", false, 1
+	}
+	return "^" + regexp.QuoteMeta(p) + "(/|$)", false, len(p)
+}
+
+//translateIngress translates an Ingress to rules
+func translateIngress(ing *networkingv1.Ingress) []*rule {
+	var rules []*rule
+	key := ing.Namespace + "/" + ing.Name
+	for _, ir := range ing.Spec.Rules {
+		if ir.HTTP == nil {
+			continue
+		}
+		for _, p := range ir.HTTP.Paths {
+			s, ok := ingressService(p.Backend)
+			if !ok {
+				continue
+			}
+			pathType := PathPrefix
+			if p.PathType != nil && *p.PathType == networkingv1.PathTypeExact {
+				pathType = PathExact
+			}
+			apiPath, exact, prefix := pathRule(pathType, p.Path)
+			rules = append(rules, &rule{
+				IngressRule: &config.IngressRule{Host: ir.Host, APIPath: apiPath, Service: s},
+				exact:       exact, prefix: prefix, created: ing.CreationTimestamp, key: key,
+			})
+		}
+	}
+	if ing.Spec.DefaultBackend != nil {
+		if s, ok := ingressService(*ing.Spec.DefaultBackend); ok {
+			//default backend is the last choice
+			rules = append(rules, &rule{
+				IngressRule: &config.IngressRule{Service: s, Priority: -1},
+				created:     ing.CreationTimestamp, key: key,
+			})
+		}
+	}
+	return rules
+}
+
+func ingressService(b networkingv1.IngressBackend) (config.Service, bool) {
+	if b.Service == nil {
+		return config.Service{}, false
+	}
+	s := config.Service{Name: b.Service.Name}
+	s.Port.Name = b.Service.Port.Name
+	if b.Service.Port.Number != 0 {
+		s.Port.Value = strconv.Itoa(int(b.Service.Port.Number))
+	}
+	return s, true
+}
+
+//translateHTTPRoute translates a HTTPRoute to rules
+func translateHTTPRoute(hr *HTTPRoute) []*rule {
+	var rules []*rule
+	key := hr.Namespace + "/" + hr.Name
+	hosts := hr.Spec.Hostnames
+	if len(hosts) == 0 {
+		hosts = []string{""}
+	}
+	for _, r := range hr.Spec.Rules {
+		services := routeServices(r.BackendRefs)
+		if len(services) == 0 {
+			continue
+		}
+		matches := r.Matches
+		if len(matches) == 0 {
+			matches = []HTTPRouteMatch{{}}
+		}
+		for _, m := range matches {
+			pathType, path := PathPathPrefix, "/"
+			if m.Path != nil {
+				path = m.Path.Value
+				if m.Path.Type != nil {
+					pathType = *m.Path.Type
+				}
+			}
+			apiPath, exact, prefix := pathRule(pathType, path)
+			for _, host := range hosts {
+				ir := &config.IngressRule{Host: host, APIPath: apiPath}
+				if m.Method != nil {
+					ir.Methods = []string{*m.Method}
+				}
+				ir.Headers = valueMatches(m.Headers)
+				ir.Query = valueMatches(m.QueryParams)
+				if len(services) == 1 {
+					ir.Service = services[0]
+				} else {
+					ir.Services = services
+				}
+				applyFilters(ir, r.Filters, pathType, path)
+				rules = append(rules, &rule{
+					IngressRule: ir, exact: exact, prefix: prefix, created: hr.CreationTimestamp, key: key,
+				})
+			}
+		}
+	}
+	return rules
+}
+
+func routeServices(refs []BackendRef) []config.Service {
+	services := make([]config.Service, 0, len(refs))
+	for _, b := range refs {
+		if b.Kind != nil && *b.Kind != "Service" {
+			continue
+		}
+		s := config.Service{Name: b.Name, Weight: 1}
+		if b.Weight != nil {
+			s.Weight = int(*b.Weight)
+		}
+		if b.Port != nil {
+			s.Port.Value = strconv.Itoa(int(*b.Port))
+		}
+		services = append(services, s)
+	}
+	return services
+}
+
+func valueMatches(ms []ValueMatch) []config.ValueMatch {
+	var result []config.ValueMatch
+	for _, m := range ms {
+		v := config.ValueMatch{Name: m.Name}
+		if m.Type != nil && *m.Type == PathRegularExpression {
+			v.Regex = "^(?:" + m.Value + ")$"
+		} else {
+			v.Exact = m.Value
+		}
+		result = append(result, v)
+	}
+	return result
+}
+
+//applyFilters sets path rewriting of URLRewrite filter to all services
+func applyFilters(ir *config.IngressRule, filters []HTTPRouteFilter, pathType, path string) {
+	for _, f := range filters {
+		if f.Type != "URLRewrite" || f.URLRewrite == nil || f.URLRewrite.Path == nil {
+			continue
+		}
+		set := func(s *config.Service) {
+			p := f.URLRewrite.Path
+			switch {
+			case p.ReplaceFullPath != nil:
+				s.RedirectPath = *p.ReplaceFullPath
+			case p.ReplacePrefixMatch != nil && pathType == PathPathPrefix:
+				s.StripPrefix = path
+				if *p.ReplacePrefixMatch != "/" {
+					s.AddPrefix = *p.ReplacePrefixMatch
+				}
+			}
+		}
+		set(&ir.Service)
+		for i := range ir.Services {
+			set(&ir.Services[i])
+		}
+	}
+}
@@ -0,0 +1,175 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kubernetes
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/ingress"
+	"github.com/stretchr/testify/assert"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func strPtr(s string) *string { return &s }
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func TestPathRule(t *testing.T) {
+	p, exact, prefix := pathRule(PathExact, "/a.b")
+	assert.Equal(t, `^/a\.b$`, p)
+	assert.True(t, exact)
+	assert.Equal(t, 4, prefix)
+	p, _, prefix = pathRule(PathPrefix, "/foo/")
+	assert.Equal(t, `^/foo(/|$)`, p)
+	assert.Equal(t, 4, prefix)
+	p, _, _ = pathRule(PathPathPrefix, "/")
+	assert.Equal(t, `^/`, p)
+	p, exact, _ = pathRule(PathRegularExpression, "/v[0-9]+/.*")
+	assert.Equal(t, `^(?:/v[0-9]+/.*)$`, p)
+	assert.False(t, exact)
+}
+
+func TestTranslateIngress(t *testing.T) {
+	exact := networkingv1.PathTypeExact
+	ing := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "shop"},
+		Spec: networkingv1.IngressSpec{
+			DefaultBackend: &networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: "web"}},
+			Rules: []networkingv1.IngressRule{{
+				Host: "shop.com",
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{
+						{Path: "/orders", Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+							Name: "order", Port: networkingv1.ServiceBackendPort{Number: 8080}}}},
+						{Path: "/orders/list", PathType: &exact, Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+							Name: "list", Port: networkingv1.ServiceBackendPort{Name: "http"}}}},
+					},
+				}},
+			}},
+		},
+	}
+	routes, err := ingress.Compile(sortRules(translateIngress(ing)))
+	assert.NoError(t, err)
+	r := ingress.NewRouter(routes)
+	assert.Equal(t, "list", r.Match("GET", "shop.com", "/orders/list", nil, nil).Service.Name)
+	assert.Equal(t, "http", r.Match("GET", "shop.com", "/orders/list", nil, nil).Service.Port.Name)
+	matched := r.Match("GET", "shop.com", "/orders/1", nil, nil)
+	assert.Equal(t, "order", matched.Service.Name)
+	assert.Equal(t, "8080", matched.Service.Port.Value)
+	assert.Equal(t, "web", r.Match("GET", "shop.com", "/ordersx", nil, nil).Service.Name)
+	assert.Equal(t, "web", r.Match("GET", "other.com", "/", nil, nil).Service.Name)
+}
+
+func TestTranslateHTTPRoute(t *testing.T) {
+	hr := &HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "shop"},
+		Spec: HTTPRouteSpec{
+			Hostnames: []string{"shop.com", "*.shop.com"},
+			Rules: []HTTPRouteRule{
+				{
+					Matches: []HTTPRouteMatch{{
+						Path:    &ValueMatch{Type: strPtr(PathPathPrefix), Value: "/api"},
+						Method:  strPtr("GET"),
+						Headers: []ValueMatch{{Name: "X-Version", Value: "v2"}},
+					}},
+					Filters: []HTTPRouteFilter{{Type: "URLRewrite", URLRewrite: &URLRewrite{
+						Path: &PathModifier{Type: "ReplacePrefixMatch", ReplacePrefixMatch: strPtr("/v2")}}}},
+					BackendRefs: []BackendRef{{Name: "api-v2", Port: int32Ptr(8080)}},
+				},
+				{
+					Matches: []HTTPRouteMatch{{Path: &ValueMatch{Type: strPtr(PathPathPrefix), Value: "/api"}}},
+					BackendRefs: []BackendRef{
+						{Name: "api-v1", Weight: int32Ptr(90)},
+						{Name: "api-canary", Weight: int32Ptr(10)},
+					},
+				},
+			},
+		},
+	}
+	rules := translateHTTPRoute(hr)
+	assert.Len(t, rules, 4)
+	routes, err := ingress.Compile(sortRules(rules))
+	assert.NoError(t, err)
+	r := ingress.NewRouter(routes)
+
+	matched := r.Match("GET", "shop.com", "/api/users", nil, map[string][]string{"X-Version": {"v2"}})
+	assert.Equal(t, "api-v2", matched.Service.Name)
+	assert.Equal(t, "/api", matched.Service.StripPrefix)
+	assert.Equal(t, "/v2", matched.Service.AddPrefix)
+	matched = r.Match("GET", "www.shop.com", "/api/users", nil, nil)
+	assert.Len(t, matched.Services, 2)
+	assert.Equal(t, 90, matched.Services[0].Weight)
+	assert.Nil(t, r.Match("GET", "other.com", "/api", nil, nil))
+}
+
+func TestSortRules(t *testing.T) {
+	older := metav1.NewTime(time.Now().Add(-time.Hour))
+	newer := metav1.Now()
+	prefix := func(name, path string, created metav1.Time) *networkingv1.Ingress {
+		return &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, CreationTimestamp: created},
+			Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{Path: path, Backend: networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{Name: name}}}},
+				}},
+			}}},
+		}
+	}
+	var rules []*rule
+	rules = append(rules, translateIngress(prefix("root", "/", older))...)
+	rules = append(rules, translateIngress(prefix("new", "/foo", newer))...)
+	rules = append(rules, translateIngress(prefix("old", "/foo", older))...)
+	rules = append(rules, translateIngress(prefix("bar", "/foo/bar", newer))...)
+	sorted := sortRules(rules)
+	assert.Len(t, sorted, 3)
+	assert.Equal(t, "bar", sorted[0].Service.Name)
+	//the older object wins a conflict
+	assert.Equal(t, "old", sorted[1].Service.Name)
+	assert.Equal(t, "root", sorted[2].Service.Name)
+	_, err := ingress.Compile(sorted)
+	assert.NoError(t, err)
+}
+
+func TestAccept(t *testing.T) {
+	now := metav1.Now()
+	newObject := func(key string, created metav1.Time, rules ...*config.IngressRule) *object {
+		o := &object{key: key, created: created}
+		for _, r := range rules {
+			o.rules = append(o.rules, &rule{IngressRule: r, created: created, key: key})
+		}
+		routes, err := compile(o.rules)
+		assert.NoError(t, err)
+		o.routes = routes
+		return o
+	}
+	all := newObject("default/all", metav1.NewTime(now.Add(-time.Hour)), &config.IngressRule{Host: "shop.com"})
+	owners := map[string]string{all.rules[0].id(): all.key}
+
+	err := accept(all.routes, owners, newObject("default/same", now, &config.IngressRule{Host: "shop.com"}))
+	assert.True(t, errors.Is(err, ErrConflicted))
+	//the older rule matches all requests of shop.com
+	err = accept(all.routes, owners, newObject("default/orders", now, &config.IngressRule{Host: "shop.com", APIPath: "^/orders"}))
+	assert.True(t, errors.Is(err, ErrConflicted))
+	assert.Contains(t, err.Error(), "shadowed by a rule of default/all")
+	assert.NoError(t, accept(all.routes, owners, newObject("default/other", now, &config.IngressRule{Host: "other.com"})))
+}
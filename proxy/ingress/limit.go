@@ -0,0 +1,204 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingress
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+)
+
+//rate limit keys of ingress rule
+const (
+	LimitKeyGlobal   = "global"
+	LimitKeyIP       = "ip"
+	LimitKeyHeader   = "header"
+	LimitKeyAPIKey   = "apiKey"
+	LimitKeyConsumer = "consumer"
+)
+
+//DefaultAPIKeyHeader is the default header of api key
+const DefaultAPIKeyHeader = "X-API-Key"
+
+//idle buckets are removed in sweep, a bucket is full again after being idle for burst/limit seconds
+const sweepInterval = time.Minute
+
+//Quota is the result of a rate limit check
+type Quota struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	//Reset is the time until the bucket is full
+	Reset time.Duration
+	//RetryAfter is the time until next request is allowed, it is 0 if request is allowed
+	RetryAfter time.Duration
+}
+
+//RateLimiter enforces the Limit of ingress rules with token buckets,
+//buckets are identified by the matching conditions of rule and the limit key,
+//so they survive rule reloading, and a changed Limit takes effect at once
+type RateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+//NewRateLimiter returns a rate limiter
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{buckets: make(map[string]*bucket), lastSweep: time.Now(), now: time.Now}
+}
+
+//Allow takes a token from the bucket of rule and key, it returns nil if rule has no limit
+func (l *RateLimiter) Allow(rule *config.IngressRule, key string) *Quota {
+	if rule.Limit <= 0 {
+		return nil
+	}
+	return l.Take(ruleID(rule)+"|"+key, rule.Limit, Burst(rule))
+}
+
+//Take takes a token from the bucket of id, limit is the tokens added per second,
+//and burst is the bucket size
+func (l *RateLimiter) Take(id string, limit, burst int) *Quota {
+	rate := float64(limit)
+	size := float64(burst)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.lastSweep) > sweepInterval {
+		l.sweep(now)
+	}
+	b, ok := l.buckets[id]
+	if !ok {
+		b = &bucket{tokens: size, last: now}
+		l.buckets[id] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > size {
+		b.tokens = size
+	}
+	b.last = now
+	q := &Quota{Limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		q.Allowed = true
+	} else {
+		q.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	q.Remaining = int(b.tokens)
+	q.Reset = seconds((size - b.tokens) / rate)
+	return q
+}
+
+//sweep removes buckets which are full again, rate of a bucket is unknown here,
+//so the idle time is compared with a safe interval instead
+func (l *RateLimiter) sweep(now time.Time) {
+	for id, b := range l.buckets {
+		if now.Sub(b.last) > sweepInterval {
+			delete(l.buckets, id)
+		}
+	}
+	l.lastSweep = now
+}
+
+//Burst returns the bucket size of rule
+func Burst(rule *config.IngressRule) int {
+	if rule.RateLimit.Burst > 0 {
+		return rule.RateLimit.Burst
+	}
+	return rule.Limit
+}
+
+//SetQuotaHeaders tells client the quota, durations are rounded up to seconds
+func SetQuotaHeaders(h http.Header, q *Quota) {
+	h.Set("X-RateLimit-Limit", strconv.Itoa(q.Limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(q.Remaining))
+	h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(q.Reset)))
+	if !q.Allowed {
+		h.Set("Retry-After", strconv.Itoa(ceilSeconds(q.RetryAfter)))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+//ruleID identifies a rule by its matching conditions, rules with same conditions are shadowed,
+//so it is unique in a rule list
+func ruleID(rule *config.IngressRule) string {
+	return fmt.Sprintf("%s|%v|%s|%v|%v|%d", rule.Host, rule.Methods, APIPath(rule),
+		rule.Headers, rule.Query, rule.Priority)
+}
+
+func checkRateLimit(rule *config.IngressRule) error {
+	if rule.Limit < 0 || rule.RateLimit.Burst < 0 {
+		return errors.New("negative limit")
+	}
+	switch rule.RateLimit.Key {
+	case "", LimitKeyGlobal, LimitKeyIP, LimitKeyAPIKey, LimitKeyConsumer:
+	case LimitKeyHeader:
+		if rule.RateLimit.Header == "" {
+			return errors.New("header of rate limit is empty")
+		}
+	default:
+		return fmt.Errorf("unknown rate limit key [%s]", rule.RateLimit.Key)
+	}
+	return nil
+}
+
+//LimitKey returns the key of rate limit bucket, requests without the key share one bucket.
+//gRPC metadata are headers of request, so it works for both http and grpc
+func LimitKey(rule *config.IngressRule, r *http.Request, consumer string) string {
+	switch rule.RateLimit.Key {
+	case LimitKeyConsumer:
+		return consumer
+	case LimitKeyIP:
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			return host
+		}
+		return r.RemoteAddr
+	case LimitKeyHeader:
+		return r.Header.Get(rule.RateLimit.Header)
+	case LimitKeyAPIKey:
+		name := rule.RateLimit.Header
+		if name == "" {
+			name = DefaultAPIKeyHeader
+		}
+		if k := r.Header.Get(name); k != "" {
+			return k
+		}
+		return r.URL.Query().Get("api_key")
+	}
+	return ""
+}
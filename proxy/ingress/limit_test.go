@@ -0,0 +1,126 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingress
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	utiltags "github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Now()
+	l := NewRateLimiter()
+	l.now = func() time.Time { return now }
+	rule := &config.IngressRule{APIPath: "/api", Limit: 2, RateLimit: config.RateLimit{Burst: 3}}
+
+	for i := 2; i >= 0; i-- {
+		q := l.Allow(rule, "")
+		assert.True(t, q.Allowed)
+		assert.Equal(t, i, q.Remaining)
+	}
+	q := l.Allow(rule, "")
+	assert.False(t, q.Allowed)
+	assert.Equal(t, 2, q.Limit)
+	assert.Equal(t, 500*time.Millisecond, q.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, q.Reset)
+
+	t.Run("keys have own buckets", func(t *testing.T) {
+		assert.True(t, l.Allow(rule, "10.0.0.1").Allowed)
+	})
+	t.Run("refill", func(t *testing.T) {
+		now = now.Add(500 * time.Millisecond)
+		assert.True(t, l.Allow(rule, "").Allowed)
+		assert.False(t, l.Allow(rule, "").Allowed)
+	})
+	t.Run("reloaded rule keeps bucket and takes new limit", func(t *testing.T) {
+		reloaded := &config.IngressRule{APIPath: "/api", Limit: 10}
+		assert.False(t, l.Allow(reloaded, "").Allowed)
+		now = now.Add(100 * time.Millisecond)
+		q := l.Allow(reloaded, "")
+		assert.True(t, q.Allowed)
+		assert.Equal(t, 10, q.Limit)
+	})
+	t.Run("no limit", func(t *testing.T) {
+		assert.Nil(t, l.Allow(&config.IngressRule{APIPath: "/api"}, ""))
+	})
+	t.Run("sweep", func(t *testing.T) {
+		now = now.Add(2 * sweepInterval)
+		l.Allow(rule, "")
+		assert.Equal(t, 1, len(l.buckets))
+	})
+}
+
+func TestRateLimiter_Take(t *testing.T) {
+	now := time.Now()
+	l := NewRateLimiter()
+	l.now = func() time.Time { return now }
+	assert.True(t, l.Take("k1", 1, 1).Allowed)
+	assert.False(t, l.Take("k1", 1, 1).Allowed)
+	assert.True(t, l.Take("k2", 1, 1).Allowed)
+}
+
+func TestLimitKey(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "http://shop.com/api?api_key=k1", nil)
+	req.RemoteAddr = "10.0.0.1:3000"
+	req.Header.Set("X-User", "jason")
+	consumer := Consumer(WithConsumer(context.Background(), "shop"))
+	assert.Equal(t, "shop", consumer)
+	assert.Equal(t, "", Consumer(context.Background()))
+
+	key := func(rl config.RateLimit) string {
+		return LimitKey(&config.IngressRule{RateLimit: rl}, req, consumer)
+	}
+	assert.Equal(t, "", key(config.RateLimit{}))
+	assert.Equal(t, "10.0.0.1", key(config.RateLimit{Key: LimitKeyIP}))
+	assert.Equal(t, "jason", key(config.RateLimit{Key: LimitKeyHeader, Header: "X-User"}))
+	assert.Equal(t, "k1", key(config.RateLimit{Key: LimitKeyAPIKey}))
+	assert.Equal(t, "shop", key(config.RateLimit{Key: LimitKeyConsumer}))
+}
+
+func TestAccessLogTags(t *testing.T) {
+	inv := &invocation.Invocation{Ctx: context.Background(),
+		RouteTags: utiltags.NewDefaultTag("1.0", "default")}
+	assert.Equal(t, map[string]string{"host": "shop.com", "version": "1.0"}, AccessLogTags(inv, "shop.com"))
+	inv.Ctx = WithConsumer(inv.Ctx, "shop")
+	assert.Equal(t, map[string]string{"host": "shop.com", "version": "1.0", "consumer": "shop"},
+		AccessLogTags(inv, "shop.com"))
+}
+
+func TestSetQuotaHeaders(t *testing.T) {
+	h := http.Header{}
+	SetQuotaHeaders(h, &Quota{Limit: 10, Remaining: 0, Reset: 1100 * time.Millisecond, RetryAfter: 100 * time.Millisecond})
+	assert.Equal(t, "10", h.Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", h.Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "2", h.Get("X-RateLimit-Reset"))
+	assert.Equal(t, "1", h.Get("Retry-After"))
+}
+
+func TestCheckRateLimit(t *testing.T) {
+	assert.NoError(t, checkRateLimit(&config.IngressRule{Limit: 1, RateLimit: config.RateLimit{Key: LimitKeyIP}}))
+	assert.NoError(t, checkRateLimit(&config.IngressRule{Limit: 1, RateLimit: config.RateLimit{Key: LimitKeyConsumer}}))
+	assert.Error(t, checkRateLimit(&config.IngressRule{Limit: -1}))
+	assert.Error(t, checkRateLimit(&config.IngressRule{Limit: 1, RateLimit: config.RateLimit{Key: LimitKeyHeader}}))
+	assert.Error(t, checkRateLimit(&config.IngressRule{Limit: 1, RateLimit: config.RateLimit{Key: "user"}}))
+}
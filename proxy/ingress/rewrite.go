@@ -0,0 +1,75 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingress
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+)
+
+//Rewrite returns the path and raw query sent to the service of a compiled rule.
+//the path is replaced by redirect path first, capture groups of api path can be used in it,
+//like apiPath /v1/users/(\d+) and redirectPath /users/$1, use ${1} if it is followed by
+//letters or digits. then prefix is stripped and added. query string of request is kept unless dropQuery is set,
+//query string in redirect path is added to it
+func Rewrite(rule *config.IngressRule, s *config.Service, path, rawQuery string) (string, string) {
+	if s.DropQuery {
+		rawQuery = ""
+	}
+	if s.RedirectPath != "" {
+		path = expand(rule.PathRegexp, s.RedirectPath, path)
+		if i := strings.IndexByte(path, '?'); i >= 0 {
+			rawQuery = joinQuery(path[i+1:], rawQuery)
+			path = path[:i]
+		}
+	}
+	if p := strings.TrimSuffix(s.StripPrefix, "/"); p != "" {
+		if path == p {
+			path = "/"
+		} else if strings.HasPrefix(path, p+"/") {
+			path = path[len(p):]
+		}
+	}
+	if s.AddPrefix != "" {
+		path = strings.TrimSuffix(s.AddPrefix, "/") + path
+	}
+	return path, rawQuery
+}
+
+func expand(re *regexp.Regexp, template, path string) string {
+	if re == nil || !strings.Contains(template, "$") {
+		return template
+	}
+	m := re.FindStringSubmatchIndex(path)
+	if m == nil {
+		return template
+	}
+	return string(re.ExpandString(nil, template, path, m))
+}
+
+func joinQuery(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return a + "&" + b
+}
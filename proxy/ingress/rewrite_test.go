@@ -0,0 +1,64 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingress_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/ingress"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewrite(t *testing.T) {
+	cases := []struct {
+		name  string
+		rule  config.IngressRule
+		path  string
+		query string
+		want  string
+		wantQ string
+	}{
+		{"keep path and query", config.IngressRule{APIPath: "/api"}, "/api/a", "x=1", "/api/a", "x=1"},
+		{"fixed redirect path", config.IngressRule{APIPath: "/api",
+			Service: config.Service{RedirectPath: "/another/api"}}, "/api/a", "x=1", "/another/api", "x=1"},
+		{"capture groups", config.IngressRule{APIPath: `^/v1/users/(\d+)/(\w+)$`,
+			Service: config.Service{RedirectPath: "/users/$1/${2}s"}}, "/v1/users/12/order", "", "/users/12/orders", ""},
+		{"named group", config.IngressRule{APIPath: `^/v1/(?P<rest>.*)`,
+			Service: config.Service{RedirectPath: "/v2/$rest?from=v1"}}, "/v1/a/b", "x=1", "/v2/a/b", "from=v1&x=1"},
+		{"drop query", config.IngressRule{APIPath: "/api",
+			Service: config.Service{DropQuery: true}}, "/api/a", "x=1", "/api/a", ""},
+		{"strip prefix", config.IngressRule{APIPath: "^/orders",
+			Service: config.Service{StripPrefix: "/orders/"}}, "/orders/1", "", "/1", ""},
+		{"strip whole path", config.IngressRule{APIPath: "^/orders",
+			Service: config.Service{StripPrefix: "/orders"}}, "/orders", "", "/", ""},
+		{"strip prefix at segment", config.IngressRule{APIPath: "^/orders",
+			Service: config.Service{StripPrefix: "/orders"}}, "/ordersx/1", "", "/ordersx/1", ""},
+		{"strip and add prefix", config.IngressRule{APIPath: "^/orders",
+			Service: config.Service{StripPrefix: "/orders", AddPrefix: "/api/v2/"}}, "/orders/1", "", "/api/v2/1", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.rule.PathRegexp = regexp.MustCompile(c.rule.APIPath)
+			p, q := ingress.Rewrite(&c.rule, &c.rule.Service, c.path, c.query)
+			assert.Equal(t, c.want, p)
+			assert.Equal(t, c.wantQ, q)
+		})
+	}
+}
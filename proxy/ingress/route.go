@@ -0,0 +1,296 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingress
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+)
+
+//ErrShadowed means a rule can never be matched because an earlier rule matches all its requests
+var ErrShadowed = errors.New("rule is shadowed")
+
+//Route is a compiled ingress rule
+type Route struct {
+	Rule    *config.IngressRule
+	index   int
+	order   int
+	apiPath string
+	methods map[string]bool
+	path    *regexp.Regexp
+	headers []*matcher
+	query   []*matcher
+}
+
+//Routes is compiled ingress rules in match order
+type Routes []*Route
+
+//Compile sorts rules by priority, rules with same priority keep their order,
+//it returns error if any rule is invalid or shadowed by an earlier rule
+func Compile(rules []*config.IngressRule) (Routes, error) {
+	routes := make(Routes, 0, len(rules))
+	for i, r := range rules {
+		route, err := compileRule(i, r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ingress rule %d: %s", i, err)
+		}
+		routes = append(routes, route)
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].Rule.Priority > routes[j].Rule.Priority
+	})
+	for i, r := range routes {
+		r.order = i
+		for _, earlier := range routes[:i] {
+			if earlier.Covers(r) {
+				return nil, fmt.Errorf("%w: ingress rule %d is shadowed by rule %d", ErrShadowed, r.index, earlier.index)
+			}
+		}
+	}
+	return routes, nil
+}
+
+//Match returns the first rule matching the request
+func (rs Routes) Match(method, host, apiPath string, query, headers map[string][]string) *config.IngressRule {
+	for _, r := range rs {
+		if r.Match(method, host, apiPath, query, headers) {
+			return r.Rule
+		}
+	}
+	return nil
+}
+
+//Match checks if request matches the rule
+func (r *Route) Match(method, host, apiPath string, query, headers map[string][]string) bool {
+	if !MatchHost(r.Rule.Host, host) {
+		return false
+	}
+	if len(r.methods) != 0 && !r.methods[strings.ToUpper(method)] {
+		return false
+	}
+	if r.path != nil && !r.path.MatchString(apiPath) {
+		return false
+	}
+	for _, m := range r.headers {
+		if !m.match(http.Header(headers)[http.CanonicalHeaderKey(m.Name)]) {
+			return false
+		}
+	}
+	for _, m := range r.query {
+		if !m.match(url.Values(query)[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+//MatchHost checks if request host matches rule host, rule host can be empty, exact host or
+//wildcard host like *.example.com which matches exactly one label, port of request host
+//is ignored unless rule host has a port
+func MatchHost(ruleHost, host string) bool {
+	if ruleHost == "" {
+		return true
+	}
+	if !strings.Contains(ruleHost, ":") {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+	}
+	if strings.HasPrefix(ruleHost, "*.") {
+		i := strings.IndexByte(host, '.')
+		return i > 0 && strings.EqualFold(host[i:], ruleHost[1:])
+	}
+	return strings.EqualFold(ruleHost, host)
+}
+
+//Covers returns true if every request matching o also matches r
+func (r *Route) Covers(o *Route) bool {
+	return coversHost(r.Rule.Host, o.Rule.Host) &&
+		coversMethods(r.methods, o.methods) &&
+		(coversPath(r.apiPath, o.apiPath) || coversGRPC(r.Rule.GRPC, o.Rule.GRPC)) &&
+		coversMatchers(r.headers, o.headers) &&
+		coversMatchers(r.query, o.query)
+}
+
+func coversHost(h, o string) bool {
+	if h == "" || strings.EqualFold(h, o) {
+		return true
+	}
+	return o != "" && !strings.HasPrefix(o, "*.") && MatchHost(h, o)
+}
+
+func coversMethods(m, o map[string]bool) bool {
+	if len(m) == 0 {
+		return true
+	}
+	if len(o) == 0 {
+		return false
+	}
+	for k := range o {
+		if !m[k] {
+			return false
+		}
+	}
+	return true
+}
+
+//coversPath handles the common cases: empty path, same path, and a plain literal path
+//which is contained by the literal prefix of the other one
+func coversPath(p, o string) bool {
+	if p == "" || p == ".*" || p == o {
+		return true
+	}
+	if prefix, complete := regexp.MustCompile(p).LiteralPrefix(); !complete || prefix != p {
+		return false
+	}
+	prefix, _ := regexp.MustCompile(strings.TrimPrefix(o, "^")).LiteralPrefix()
+	return strings.Contains(prefix, p)
+}
+
+func coversGRPC(m, o config.GRPCMatch) bool {
+	return m.Service != "" && m.Service == o.Service && (m.Method == "" || m.Method == o.Method)
+}
+
+func coversMatchers(m, o []*matcher) bool {
+	for _, a := range m {
+		found := false
+		for _, b := range o {
+			if strings.EqualFold(a.Name, b.Name) && a.ValueMatch == b.ValueMatch {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func compileRule(i int, rule *config.IngressRule) (*Route, error) {
+	r := &Route{Rule: rule, index: i}
+	if strings.Contains(strings.TrimPrefix(rule.Host, "*."), "*") {
+		return nil, fmt.Errorf("invalid host [%s]", rule.Host)
+	}
+	if len(rule.Methods) != 0 {
+		r.methods = make(map[string]bool, len(rule.Methods))
+		for _, m := range rule.Methods {
+			r.methods[strings.ToUpper(m)] = true
+		}
+	}
+	if rule.GRPC != (config.GRPCMatch{}) {
+		if rule.APIPath != "" {
+			return nil, errors.New("apiPath and grpc can not be both set")
+		}
+		if rule.GRPC.Service == "" || strings.Contains(rule.GRPC.Service+rule.GRPC.Method, "/") {
+			return nil, fmt.Errorf("invalid grpc service [%s] or method [%s]", rule.GRPC.Service, rule.GRPC.Method)
+		}
+	}
+	r.apiPath = APIPath(rule)
+	var err error
+	if r.apiPath != "" {
+		if r.path, err = regexp.Compile(r.apiPath); err != nil {
+			return nil, err
+		}
+	}
+	rule.PathRegexp = r.path
+	if r.headers, err = compileMatchers(rule.Headers); err != nil {
+		return nil, err
+	}
+	if r.query, err = compileMatchers(rule.Query); err != nil {
+		return nil, err
+	}
+	if err = checkServices(rule); err != nil {
+		return nil, err
+	}
+	if err = checkRateLimit(rule); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+//APIPath returns the api path of rule, for a grpc rule it is built from service and method,
+//the method is a capture group named method, so that redirect path can refer it as $method
+func APIPath(rule *config.IngressRule) string {
+	if rule.GRPC.Service == "" {
+		return rule.APIPath
+	}
+	method := "[^/]+"
+	if rule.GRPC.Method != "" {
+		method = regexp.QuoteMeta(rule.GRPC.Method)
+	}
+	return "^/" + regexp.QuoteMeta(rule.GRPC.Service) + "/(?P<method>" + method + ")$"
+}
+
+type matcher struct {
+	config.ValueMatch
+	regex *regexp.Regexp
+}
+
+func compileMatchers(ms []config.ValueMatch) ([]*matcher, error) {
+	result := make([]*matcher, 0, len(ms))
+	for _, m := range ms {
+		if m.Name == "" {
+			return nil, errors.New("match name is empty")
+		}
+		n := 0
+		for _, set := range []bool{m.Exact != "", m.Prefix != "", m.Regex != "", m.Present, m.Absent} {
+			if set {
+				n++
+			}
+		}
+		if n > 1 {
+			return nil, fmt.Errorf("more than one condition for [%s]", m.Name)
+		}
+		c := &matcher{ValueMatch: m}
+		if m.Regex != "" {
+			var err error
+			if c.regex, err = regexp.Compile(m.Regex); err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+func (m *matcher) match(values []string) bool {
+	if m.Absent {
+		return len(values) == 0
+	}
+	if len(values) == 0 {
+		return false
+	}
+	switch {
+	case m.Exact != "":
+		return values[0] == m.Exact
+	case m.Prefix != "":
+		return strings.HasPrefix(values[0], m.Prefix)
+	case m.regex != nil:
+		return m.regex.MatchString(values[0])
+	}
+	return true
+}
@@ -0,0 +1,218 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingress_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/ingress"
+	"github.com/stretchr/testify/assert"
+)
+
+func compile(t *testing.T, raw string) ingress.Routes {
+	rules, err := config.NewRules(raw)
+	assert.NoError(t, err)
+	routes, err := ingress.Compile(rules.Value())
+	assert.NoError(t, err)
+	return routes
+}
+
+func TestRoutes_Match(t *testing.T) {
+	routes := compile(t, `
+- host: "*.example.com"
+  apiPath: ^/api
+  methods: [get, HEAD]
+  headers:
+    - name: x-canary
+      exact: "true"
+    - name: X-Debug
+      absent: true
+  query:
+    - name: version
+      prefix: v2
+  service:
+    name: canary
+- host: "*.example.com"
+  apiPath: ^/api
+  headers:
+    - name: Authorization
+      present: true
+    - name: User-Agent
+      regex: Mobile
+  service:
+    name: mobile
+- host: "*.example.com"
+  apiPath: ^/api
+  service:
+    name: api
+- apiPath: /admin
+  priority: 10
+  service:
+    name: admin
+`)
+	cases := []struct {
+		name    string
+		method  string
+		host    string
+		path    string
+		query   map[string][]string
+		headers http.Header
+		service string
+	}{
+		{"priority", http.MethodGet, "a.example.com", "/api/admin", nil, nil, "admin"},
+		{"all conditions", http.MethodGet, "a.example.com:8080", "/api/v1", map[string][]string{"version": {"v2.1"}},
+			http.Header{"X-Canary": {"true"}}, "canary"},
+		{"method", http.MethodPost, "a.example.com", "/api/v1", map[string][]string{"version": {"v2.1"}},
+			http.Header{"X-Canary": {"true"}}, "api"},
+		{"absent", http.MethodGet, "a.example.com", "/api/v1", map[string][]string{"version": {"v2.1"}},
+			http.Header{"X-Canary": {"true"}, "X-Debug": {"1"}}, "api"},
+		{"query prefix", http.MethodGet, "a.example.com", "/api/v1", map[string][]string{"version": {"v1"}},
+			http.Header{"X-Canary": {"true"}}, "api"},
+		{"present and regex", http.MethodGet, "b.example.com", "/api/v1", nil,
+			http.Header{"Authorization": {"token"}, "User-Agent": {"Mobile Safari"}}, "mobile"},
+		{"regex", http.MethodGet, "b.example.com", "/api/v1", nil,
+			http.Header{"Authorization": {"token"}, "User-Agent": {"curl"}}, "api"},
+		{"wildcard matches one label", http.MethodGet, "a.b.example.com", "/api/v1", nil, nil, ""},
+		{"wildcard does not match apex", http.MethodGet, "example.com", "/api/v1", nil, nil, ""},
+	}
+	router := ingress.NewRouter(routes)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := routes.Match(c.method, c.host, c.path, c.query, c.headers)
+			assert.Equal(t, r, router.Match(c.method, c.host, c.path, c.query, c.headers))
+			if c.service == "" {
+				assert.Nil(t, r)
+				return
+			}
+			assert.NotNil(t, r)
+			assert.Equal(t, c.service, r.Service.Name)
+		})
+	}
+}
+
+func TestMatchHost(t *testing.T) {
+	assert.True(t, ingress.MatchHost("", "foo.com"))
+	assert.True(t, ingress.MatchHost("Foo.com", "foo.com:80"))
+	assert.False(t, ingress.MatchHost("foo.com:8080", "foo.com:80"))
+	assert.True(t, ingress.MatchHost("*.foo.com", "a.foo.com"))
+	assert.False(t, ingress.MatchHost("*.foo.com", ".foo.com"))
+}
+
+func TestCompile(t *testing.T) {
+	t.Run("shadowed", func(t *testing.T) {
+		for _, raw := range []string{`
+- apiPath: /api
+  service: {name: a}
+- apiPath: /api/v1
+  service: {name: b}
+`, `
+- host: "*.foo.com"
+  service: {name: a}
+- host: a.foo.com
+  methods: [GET]
+  apiPath: ^/api
+  headers:
+    - name: x-user
+      exact: jason
+  service: {name: b}
+`, `
+- methods: [GET, POST]
+  headers:
+    - name: x-user
+      present: true
+  service: {name: a}
+- methods: [GET]
+  priority: -1
+  headers:
+    - name: x-user
+      present: true
+    - name: x-age
+      exact: "18"
+  service: {name: b}
+`, `
+- grpc: {service: helloworld.Greeter}
+  service: {name: a}
+- grpc: {service: helloworld.Greeter, method: SayHello}
+  service: {name: b}
+`} {
+			rules, err := config.NewRules(raw)
+			assert.NoError(t, err)
+			_, err = ingress.Compile(rules.Value())
+			assert.True(t, errors.Is(err, ingress.ErrShadowed), raw)
+		}
+	})
+	t.Run("reachable", func(t *testing.T) {
+		routes := compile(t, `
+- apiPath: /api
+  methods: [GET]
+  service: {name: a}
+- apiPath: /api
+  headers:
+    - name: x-user
+      exact: jason
+  service: {name: b}
+- apiPath: ^/api$
+  service: {name: c}
+- apiPath: /api/v1
+  priority: 1
+  service: {name: d}
+`)
+		assert.Equal(t, 4, len(routes))
+		assert.Equal(t, "d", routes[0].Rule.Service.Name)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		for _, raw := range []string{
+			"- apiPath: \"(\"",
+			"- host: a.*.com",
+			"- headers: [{name: a, exact: b, absent: true}]",
+			"- query: [{regex: a}]",
+			"- {apiPath: /api, grpc: {service: a.B}}",
+			"- grpc: {method: SayHello}",
+			"- grpc: {service: a/B}",
+		} {
+			rules, err := config.NewRules(raw)
+			assert.NoError(t, err)
+			_, err = ingress.Compile(rules.Value())
+			assert.Error(t, err, raw)
+		}
+	})
+}
+
+func TestAPIPath(t *testing.T) {
+	routes := compile(t, `
+- grpc: {service: helloworld.Greeter, method: SayHello}
+  service: {name: hello}
+- grpc: {service: helloworld.Greeter}
+  service: {name: greeter}
+`)
+	r := ingress.NewRouter(routes)
+	assert.Equal(t, "hello", r.Match(http.MethodPost, "", "/helloworld.Greeter/SayHello", nil, nil).Service.Name)
+	assert.Equal(t, "greeter", r.Match(http.MethodPost, "", "/helloworld.Greeter/SayBye", nil, nil).Service.Name)
+	assert.Nil(t, r.Match(http.MethodPost, "", "/helloworldxGreeter/SayBye", nil, nil))
+	assert.Nil(t, r.Match(http.MethodPost, "", "/helloworld.Greeter/SayHello/x", nil, nil))
+
+	rule := &config.IngressRule{GRPC: config.GRPCMatch{Service: "helloworld.Greeter"},
+		Service: config.Service{Name: "greeter", RedirectPath: "/helloworld.v2.Greeter/$method"}}
+	_, err := ingress.Compile([]*config.IngressRule{rule})
+	assert.NoError(t, err)
+	path, _ := ingress.Rewrite(rule, &rule.Service, "/helloworld.Greeter/SayHello", "")
+	assert.Equal(t, "/helloworld.v2.Greeter/SayHello", path)
+}
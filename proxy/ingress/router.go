@@ -0,0 +1,187 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingress
+
+import (
+	"net"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+)
+
+//Router finds the rule of a request with an index of hosts and path prefixes,
+//routes are indexed by host first, then by the literal prefix of api path in a radix tree,
+//only api path anchored by ^ has a prefix, like ^/orders/, others are checked for every request of the host.
+//it is read only after built, so it can be shared by goroutines
+type Router struct {
+	exact    map[string]*node
+	wildcard map[string]*node
+	any      *node
+}
+
+//NewRouter builds a router of compiled routes, routes keep their order
+func NewRouter(routes Routes) *Router {
+	rt := &Router{exact: make(map[string]*node), wildcard: make(map[string]*node), any: &node{}}
+	for _, r := range routes {
+		var tree *node
+		host := strings.ToLower(r.Rule.Host)
+		switch {
+		case host == "":
+			tree = rt.any
+		case strings.HasPrefix(host, "*."):
+			tree = rt.wildcard[host[1:]]
+			if tree == nil {
+				tree = &node{}
+				rt.wildcard[host[1:]] = tree
+			}
+		default:
+			tree = rt.exact[host]
+			if tree == nil {
+				tree = &node{}
+				rt.exact[host] = tree
+			}
+		}
+		tree.insert(pathPrefix(r.apiPath), r)
+	}
+	return rt
+}
+
+//Match returns the first rule matching the request
+func (rt *Router) Match(method, host, apiPath string, query, headers map[string][]string) *config.IngressRule {
+	host = strings.ToLower(host)
+	hosts := []string{host}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hosts = append(hosts, h)
+	}
+	candidates := make([][]*Route, 0, 8)
+	collect := func(routes []*Route) {
+		candidates = append(candidates, routes)
+	}
+	for _, h := range hosts {
+		if tree := rt.exact[h]; tree != nil {
+			tree.walk(apiPath, collect)
+		}
+		if i := strings.IndexByte(h, '.'); i > 0 {
+			if tree := rt.wildcard[h[i:]]; tree != nil {
+				tree.walk(apiPath, collect)
+			}
+		}
+	}
+	rt.any.walk(apiPath, collect)
+	//merge candidate lists by order, each list is sorted
+	for {
+		best := -1
+		for i, c := range candidates {
+			if len(c) != 0 && (best == -1 || c[0].order < candidates[best][0].order) {
+				best = i
+			}
+		}
+		if best == -1 {
+			return nil
+		}
+		r := candidates[best][0]
+		candidates[best] = candidates[best][1:]
+		if r.Match(method, host, apiPath, query, headers) {
+			return r.Rule
+		}
+	}
+}
+
+//pathPrefix returns the literal prefix of a path pattern anchored at the beginning of text,
+//it returns empty string if pattern is not anchored
+func pathPrefix(pattern string) string {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil || re.Op != syntax.OpConcat || len(re.Sub) < 2 || re.Sub[0].Op != syntax.OpBeginText {
+		return ""
+	}
+	var b strings.Builder
+	for _, sub := range re.Sub[1:] {
+		if sub.Op != syntax.OpLiteral || sub.Flags&syntax.FoldCase != 0 {
+			break
+		}
+		b.WriteString(string(sub.Rune))
+	}
+	return b.String()
+}
+
+//node is a node of radix tree, children have different first bytes
+type node struct {
+	prefix   string
+	children []*node
+	routes   []*Route
+}
+
+//insert adds a route with the key, routes must be inserted by order
+func (n *node) insert(key string, r *Route) {
+	for {
+		if key == "" {
+			n.routes = append(n.routes, r)
+			return
+		}
+		var child *node
+		for _, c := range n.children {
+			if c.prefix[0] == key[0] {
+				child = c
+				break
+			}
+		}
+		if child == nil {
+			n.children = append(n.children, &node{prefix: key, routes: []*Route{r}})
+			return
+		}
+		l := commonPrefix(key, child.prefix)
+		if l < len(child.prefix) {
+			//split the child
+			split := &node{prefix: child.prefix[l:], children: child.children, routes: child.routes}
+			child.prefix = child.prefix[:l]
+			child.children = []*node{split}
+			child.routes = nil
+		}
+		n = child
+		key = key[l:]
+	}
+}
+
+//walk calls fn with routes of every node whose key is a prefix of path
+func (n *node) walk(path string, fn func([]*Route)) {
+	for n != nil {
+		if len(n.routes) != 0 {
+			fn(n.routes)
+		}
+		var next *node
+		for _, c := range n.children {
+			if strings.HasPrefix(path, c.prefix) {
+				next = c
+				break
+			}
+		}
+		if next != nil {
+			path = path[len(next.prefix):]
+		}
+		n = next
+	}
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
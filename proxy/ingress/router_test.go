@@ -0,0 +1,126 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingress_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/ingress"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_Match(t *testing.T) {
+	routes := compile(t, `
+- host: "*.shop.com:8080"
+  apiPath: ^/orders
+  service: {name: wildcard-port}
+- apiPath: ^/orders/v2
+  methods: [POST]
+  service: {name: orders-v2-post}
+- host: shop.com
+  apiPath: ^/orders/v2
+  service: {name: shop-orders-v2}
+- apiPath: ^/orders/
+  service: {name: orders}
+- apiPath: ^/order
+  service: {name: order}
+- apiPath: (?i)^/USERS
+  service: {name: users}
+- apiPath: /catalog
+  service: {name: catalog}
+- host: shop.com:8080
+  service: {name: shop-port}
+- host: "*.SHOP.com"
+  service: {name: shop-wildcard}
+`)
+	router := ingress.NewRouter(routes)
+	cases := []struct {
+		method, host, path, service string
+	}{
+		{http.MethodPost, "shop.com", "/orders/v2/1", "orders-v2-post"},
+		{http.MethodGet, "Shop.com", "/orders/v2/1", "shop-orders-v2"},
+		{http.MethodGet, "shop.com:80", "/orders/v2/1", "shop-orders-v2"},
+		{http.MethodGet, "foo.com", "/orders/v2/1", "orders"},
+		{http.MethodGet, "foo.com", "/orders/1", "orders"},
+		{http.MethodGet, "foo.com", "/orders", "order"},
+		{http.MethodGet, "a.shop.com:8080", "/orders", "wildcard-port"},
+		{http.MethodGet, "a.shop.com", "/orders", "order"},
+		{http.MethodGet, "foo.com", "/users/1", "users"},
+		{http.MethodGet, "foo.com", "/api/catalog/1", "catalog"},
+		{http.MethodGet, "shop.com:8080", "/", "shop-port"},
+		{http.MethodGet, "a.shop.com", "/", "shop-wildcard"},
+		{http.MethodGet, "shop.com", "/", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.method+" "+c.host+c.path, func(t *testing.T) {
+			r := router.Match(c.method, c.host, c.path, nil, nil)
+			assert.Equal(t, routes.Match(c.method, c.host, c.path, nil, nil), r)
+			if c.service == "" {
+				assert.Nil(t, r)
+				return
+			}
+			assert.NotNil(t, r)
+			assert.Equal(t, c.service, r.Service.Name)
+		})
+	}
+	assert.Nil(t, ingress.NewRouter(nil).Match(http.MethodGet, "foo.com", "/", nil, nil))
+}
+
+//manyRoutes returns n rules like ^/api/service{i}/v{j}/, each with a host
+func manyRoutes(b *testing.B, n int) ingress.Routes {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "- host: host%d.com\n  apiPath: ^/api/service%d/v%d/\n  service: {name: s%d}\n", i%10, i, i%3, i)
+	}
+	rules, err := config.NewRules(sb.String())
+	if err != nil {
+		b.Fatal(err)
+	}
+	routes, err := ingress.Compile(rules.Value())
+	if err != nil {
+		b.Fatal(err)
+	}
+	return routes
+}
+
+func BenchmarkRouter_Match(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		routes := manyRoutes(b, n)
+		router := ingress.NewRouter(routes)
+		path := fmt.Sprintf("/api/service%d/v%d/items/1", n-1, (n-1)%3)
+		host := fmt.Sprintf("host%d.com", (n-1)%10)
+		b.Run(fmt.Sprintf("router-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if router.Match(http.MethodGet, host, path, nil, nil) == nil {
+					b.Fatal("not match")
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("linear-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if routes.Match(http.MethodGet, host, path, nil, nil) == nil {
+					b.Fatal("not match")
+				}
+			}
+		})
+	}
+}
@@ -18,59 +18,66 @@
 package servicecomb
 
 import (
+	"fmt"
 	"github.com/apache/servicecomb-mesher/proxy/config"
 	"github.com/apache/servicecomb-mesher/proxy/ingress"
 	"github.com/go-chassis/go-archaius"
-	"github.com/patrickmn/go-cache"
-	"regexp"
-	"time"
+	"sync/atomic"
 )
 
 const (
-	cacheTTL       = 30
-	ingressRuleKey = "mesher.ingress.rule.http"
+	ingressRuleKeyPrefix = "mesher.ingress.rule."
 )
 
-var rulesData []*config.IngressRule
+//routers holds *ingress.Router of each protocol, it is replaced when rules change
+var routers = map[string]*atomic.Value{
+	"http": {},
+	"grpc": {},
+}
 
 //IngressRuleFetcher query ingress rule
 type IngressRuleFetcher struct {
-	cache *cache.Cache
 }
 
 //Fetch get ingress rule
-func (f *IngressRuleFetcher) Fetch(protocol, host, apiPath string, headers map[string][]string) (*config.IngressRule, error) {
-	for _, r := range rulesData {
-		if r.Host != "" && host != r.Host {
-			//do not match host,then ignore path
-			continue
-		}
-		match, err := regexp.MatchString(r.APIPath, apiPath)
-		if err != nil {
-			return nil, err
-		}
-		if match {
-			return r, nil
-		}
+func (f *IngressRuleFetcher) Fetch(protocol, method, host, apiPath string, query, headers map[string][]string) (*config.IngressRule, error) {
+	router, ok := routers[protocol]
+	if !ok {
+		return nil, ingress.ErrNotMatch
+	}
+	r := router.Load().(*ingress.Router).Match(method, host, apiPath, query, headers)
+	if r == nil {
+		return nil, ingress.ErrNotMatch
 	}
-	return nil, ingress.ErrNotMatch
+	return r, nil
 }
 
-func newFetcher() (ingress.RuleFetcher, error) {
-	raw := archaius.GetString(ingressRuleKey, "")
+//compile builds router of raw rules, rules are compiled only once here
+func compile(raw string) (*ingress.Router, error) {
 	rules, err := config.NewRules(raw)
 	if err != nil {
 		return nil, err
 	}
-
-	err = archaius.RegisterListener(&ingressRuleEventListener{}, ingressRuleKey)
+	routes, err := ingress.Compile(rules.Value())
 	if err != nil {
 		return nil, err
 	}
-	rulesData = rules.Value()
-	return &IngressRuleFetcher{
-		cache: cache.New(cacheTTL*time.Second, 0),
-	}, nil
+	return ingress.NewRouter(routes), nil
+}
+
+func newFetcher() (ingress.RuleFetcher, error) {
+	for protocol, router := range routers {
+		key := ingressRuleKeyPrefix + protocol
+		r, err := compile(archaius.GetString(key, ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		if err = archaius.RegisterListener(&ingressRuleEventListener{}, key); err != nil {
+			return nil, err
+		}
+		router.Store(r)
+	}
+	return &IngressRuleFetcher{}, nil
 }
 
 func init() {
@@ -0,0 +1,113 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package servicecomb_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/ingress"
+	_ "github.com/apache/servicecomb-mesher/proxy/ingress/servicecomb"
+	"github.com/go-chassis/go-archaius"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngressRuleFetcher_Fetch(t *testing.T) {
+	assert.NoError(t, archaius.Init(archaius.WithMemorySource()))
+	assert.NoError(t, archaius.Set("mesher.ingress.rule.http", `
+- host: foo.com
+  methods: [GET]
+  apiPath: /some/api
+  service:
+    name: foo
+`))
+	assert.NoError(t, ingress.Init())
+
+	r, err := ingress.DefaultFetcher.Fetch("http", http.MethodGet, "foo.com", "/some/api", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", r.Service.Name)
+	_, err = ingress.DefaultFetcher.Fetch("http", http.MethodPost, "foo.com", "/some/api", nil, nil)
+	assert.Equal(t, ingress.ErrNotMatch, err)
+
+	t.Run("shadowed rule is refused", func(t *testing.T) {
+		assert.NoError(t, archaius.Set("mesher.ingress.rule.http", `
+- apiPath: /some
+  service:
+    name: all
+- apiPath: /some/api
+  service:
+    name: bar
+`))
+		time.Sleep(100 * time.Millisecond)
+		r, err := ingress.DefaultFetcher.Fetch("http", http.MethodGet, "foo.com", "/some/api", nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "foo", r.Service.Name)
+	})
+	t.Run("update", func(t *testing.T) {
+		assert.NoError(t, archaius.Set("mesher.ingress.rule.http", `
+- apiPath: /some/api
+  service:
+    name: bar
+`))
+		assert.Eventually(t, func() bool {
+			r, err := ingress.DefaultFetcher.Fetch("http", http.MethodPost, "bar.com", "/some/api", nil, nil)
+			return err == nil && r.Service.Name == "bar"
+		}, 3*time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestIngressRuleFetcher_FetchGRPC(t *testing.T) {
+	assert.NoError(t, archaius.Init(archaius.WithMemorySource()))
+	assert.NoError(t, archaius.Set("mesher.ingress.rule.http", `
+- apiPath: /helloworld.Greeter/SayHello
+  service:
+    name: rest
+`))
+	assert.NoError(t, archaius.Set("mesher.ingress.rule.grpc", `
+- grpc:
+    service: helloworld.Greeter
+    method: SayHello
+  service:
+    name: greeter
+`))
+	assert.NoError(t, ingress.Init())
+
+	r, err := ingress.DefaultFetcher.Fetch("grpc", http.MethodPost, "foo.com", "/helloworld.Greeter/SayHello", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "greeter", r.Service.Name)
+	r, err = ingress.DefaultFetcher.Fetch("http", http.MethodPost, "foo.com", "/helloworld.Greeter/SayHello", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "rest", r.Service.Name)
+	_, err = ingress.DefaultFetcher.Fetch("dubbo", http.MethodPost, "foo.com", "/helloworld.Greeter/SayHello", nil, nil)
+	assert.Equal(t, ingress.ErrNotMatch, err)
+
+	assert.NoError(t, archaius.Set("mesher.ingress.rule.grpc", `
+- grpc:
+    service: helloworld.Greeter
+  service:
+    name: greeter-v2
+`))
+	assert.Eventually(t, func() bool {
+		r, err := ingress.DefaultFetcher.Fetch("grpc", http.MethodPost, "foo.com", "/helloworld.Greeter/SayBye", nil, nil)
+		return err == nil && r.Service.Name == "greeter-v2"
+	}, 3*time.Second, 10*time.Millisecond)
+	r, err = ingress.DefaultFetcher.Fetch("http", http.MethodPost, "foo.com", "/helloworld.Greeter/SayHello", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "rest", r.Service.Name)
+}
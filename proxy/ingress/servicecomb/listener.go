@@ -18,10 +18,13 @@
 package servicecomb
 
 import (
-	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/ingress"
+	"github.com/go-chassis/go-archaius"
 	"github.com/go-chassis/go-archaius/event"
 	"github.com/go-chassis/go-chassis/v2/core/common"
 	"github.com/go-chassis/openlog"
+	"strings"
+	"sync/atomic"
 )
 
 type ingressRuleEventListener struct{}
@@ -37,19 +40,20 @@ func (r *ingressRuleEventListener) Event(e *event.Event) {
 		"event": e.EventType,
 		"rule":  e.Value,
 	}))
-	raw, ok := e.Value.(string)
+	//events are dispatched asynchronously and may arrive out of order,
+	//so the latest value is read instead of the value in event
+	router, ok := routers[strings.TrimPrefix(e.Key, ingressRuleKeyPrefix)]
 	if !ok {
-		openlog.Error("invalid ingress rule", openlog.WithTags(openlog.Tags{
-			"value": raw,
-		}))
+		return
 	}
+	raw := archaius.GetString(e.Key, "")
 	switch e.EventType {
 	case common.Update:
-		saveRules(raw)
+		saveRules(router, raw)
 	case common.Create:
-		saveRules(raw)
+		saveRules(router, raw)
 	case common.Delete:
-		rulesData = nil
+		router.Store(ingress.NewRouter(nil))
 		openlog.Info("ingress rule is removed", openlog.WithTags(
 			openlog.Tags{
 				"key": e.Key,
@@ -58,14 +62,16 @@ func (r *ingressRuleEventListener) Event(e *event.Event) {
 
 }
 
-func saveRules(raw string) {
-	rules, err := config.NewRules(raw)
+func saveRules(router *atomic.Value, raw string) {
+	r, err := compile(raw)
 	if err != nil {
 		openlog.Error("invalid ingress rule", openlog.WithTags(openlog.Tags{
 			"value": raw,
+			"err":   err.Error(),
 		}))
+		return
 	}
-	rulesData = rules.Value()
+	router.Store(r)
 	openlog.Info("update ingress rule", openlog.WithTags(openlog.Tags{
 		"value": raw,
 	}))
@@ -0,0 +1,81 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingress
+
+import (
+	"errors"
+	"hash/fnv"
+	"math/rand"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+)
+
+//SelectService selects the service of a rule, if rule has several services, it selects one by weight,
+//requests with same non-empty key always select the same service, key can also be a service name
+func SelectService(rule *config.IngressRule, key string) *config.Service {
+	if len(rule.Services) == 0 {
+		return &rule.Service
+	}
+	total := 0
+	for i := range rule.Services {
+		total += rule.Services[i].Weight
+	}
+	if total <= 0 {
+		return &rule.Services[0]
+	}
+	var n int
+	if key != "" {
+		for i := range rule.Services {
+			if rule.Services[i].Name == key && rule.Services[i].Weight > 0 {
+				return &rule.Services[i]
+			}
+		}
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		n = int(h.Sum32() % uint32(total))
+	} else {
+		n = rand.Intn(total)
+	}
+	for i := range rule.Services {
+		n -= rule.Services[i].Weight
+		if n < 0 {
+			return &rule.Services[i]
+		}
+	}
+	return &rule.Services[len(rule.Services)-1]
+}
+
+func checkServices(rule *config.IngressRule) error {
+	if len(rule.Services) == 0 {
+		return nil
+	}
+	total := 0
+	for _, s := range rule.Services {
+		if s.Name == "" {
+			return errors.New("service name is empty")
+		}
+		if s.Weight < 0 {
+			return errors.New("weight of service " + s.Name + " is negative")
+		}
+		total += s.Weight
+	}
+	if total == 0 {
+		return errors.New("total weight of services is 0")
+	}
+	return nil
+}
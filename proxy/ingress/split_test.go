@@ -0,0 +1,88 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingress_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/ingress"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectService(t *testing.T) {
+	rule := &config.IngressRule{
+		Service: config.Service{Name: "ignored"},
+		Services: []config.Service{
+			{Name: "legacy-orders", Weight: 80},
+			{Name: "orders-v2", Weight: 20},
+			{Name: "disabled", Weight: 0},
+		},
+	}
+	t.Run("weight", func(t *testing.T) {
+		count := map[string]int{}
+		for i := 0; i < 10000; i++ {
+			count[ingress.SelectService(rule, "").Name]++
+		}
+		assert.InDelta(t, 8000, count["legacy-orders"], 500)
+		assert.InDelta(t, 2000, count["orders-v2"], 500)
+		assert.Equal(t, 0, count["disabled"])
+	})
+	t.Run("sticky", func(t *testing.T) {
+		count := map[string]int{}
+		for i := 0; i < 1000; i++ {
+			key := "user" + strconv.Itoa(i)
+			s := ingress.SelectService(rule, key)
+			assert.Equal(t, s, ingress.SelectService(rule, key))
+			count[s.Name]++
+		}
+		assert.InDelta(t, 800, count["legacy-orders"], 100)
+		assert.Equal(t, "orders-v2", ingress.SelectService(rule, "orders-v2").Name)
+		assert.NotEqual(t, "disabled", ingress.SelectService(rule, "disabled").Name)
+	})
+	t.Run("single service", func(t *testing.T) {
+		assert.Equal(t, "foo", ingress.SelectService(&config.IngressRule{Service: config.Service{Name: "foo"}}, "").Name)
+	})
+}
+
+func TestCompile_Services(t *testing.T) {
+	for _, raw := range []string{
+		"- services: [{name: a, weight: -1}, {name: b, weight: 2}]",
+		"- services: [{name: a}, {name: b}]",
+		"- services: [{weight: 1}]",
+	} {
+		rules, err := config.NewRules(raw)
+		assert.NoError(t, err)
+		_, err = ingress.Compile(rules.Value())
+		assert.Error(t, err, raw)
+	}
+	routes := compile(t, `
+- apiPath: /orders
+  services:
+    - name: legacy-orders
+      weight: 90
+    - name: orders-v2
+      weight: 10
+      redirectPath: /v2/orders
+  sticky:
+    cookie: orders-backend
+`)
+	assert.Equal(t, 2, len(routes[0].Rule.Services))
+	assert.Equal(t, "orders-backend", routes[0].Rule.Sticky.Cookie)
+}
@@ -0,0 +1,76 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+//Package accesslog writes one line for each request proxied by mesher,
+//protocols fill a record and call Log after the request finished
+package accesslog
+
+import (
+	"time"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/openlog"
+)
+
+//KeyEnable is the config key to enable access log
+const KeyEnable = "mesher.accessLog.enable"
+
+var enabled bool
+
+//Record is one line of access log
+type Record struct {
+	Protocol  string
+	Source    string
+	Service   string
+	Operation string
+	Status    string
+	Latency   time.Duration
+	//Tags holds protocol specific fields
+	Tags map[string]string
+}
+
+//Init reads access log config
+func Init() {
+	enabled = archaius.GetBool(KeyEnable, false)
+	openlog.Info("access log", openlog.WithTags(openlog.Tags{
+		"enable": enabled,
+	}))
+}
+
+//Enabled returns whether access log is enabled
+func Enabled() bool {
+	return enabled
+}
+
+//Log writes the record if access log is enabled
+func Log(r *Record) {
+	if !enabled {
+		return
+	}
+	tags := openlog.Tags{
+		"protocol":  r.Protocol,
+		"source":    r.Source,
+		"service":   r.Service,
+		"operation": r.Operation,
+		"status":    r.Status,
+		"latency":   r.Latency.String(),
+	}
+	for k, v := range r.Tags {
+		tags[k] = v
+	}
+	openlog.Info("access", openlog.WithTags(tags))
+}
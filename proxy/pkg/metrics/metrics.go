@@ -42,9 +42,53 @@ const (
 	LStartTime             = "start_time_seconds"
 )
 
+//Constants with labels for rpc protocols like dubbo
+const (
+	LInterface = "interface"
+	LMethod    = "method"
+	LStatus    = "status"
+	LListener  = "listener"
+	//LActiveConnections is a gauge name of opened connections of a listener
+	LActiveConnections = "active_connections"
+	//LQueueDepth is a gauge name of messages waiting to be sent of a listener
+	LQueueDepth = "queue_depth"
+	//LPendingRequests is a gauge name of requests in processing of a listener
+	LPendingRequests = "pending_requests"
+)
+
+//Constants with names of connection metrics of l4 protocols like tcp
+const (
+	LTotalConnections     = "connections_total"
+	LConnectionFailures   = "connection_failures_total"
+	LReceivedBytes        = "received_bytes_total"
+	LSentBytes            = "sent_bytes_total"
+	LConnectionDurSeconds = "connection_duration_seconds"
+)
+
+//Constants with names and labels of ingress metrics
+const (
+	LRateLimited = "ratelimited_total"
+	LHost        = "host"
+	LAPIPath     = "api_path"
+	//LConsumer is the consumer of api key or other credentials
+	LConsumer = "consumer"
+	//LConsumerRequests is a counter name of requests of a consumer
+	LConsumerRequests = "consumer_requests_total"
+)
+
 var (
 	//LabelNames is a fixed list with service name, appID, version
 	LabelNames = []string{LServiceName, LApp, LVersion}
+	//RPCLabelNames is a fixed list of rpc protocol latency labels
+	RPCLabelNames = []string{LServiceName, LApp, LVersion, LInterface, LMethod}
+	//RPCStatusLabelNames is a fixed list of rpc protocol status labels
+	RPCStatusLabelNames = []string{LServiceName, LApp, LVersion, LInterface, LMethod, LStatus}
+	//ConnLabelNames is a fixed list of connection labels
+	ConnLabelNames = []string{LServiceName, LListener}
+	//RouteLabelNames is a fixed list of ingress rule labels
+	RouteLabelNames = []string{LHost, LAPIPath, LConsumer}
+	//ConsumerLabelNames is a fixed list of consumer request labels
+	ConsumerLabelNames = []string{LConsumer, LServiceName, LStatus}
 )
 
 //Options define recorder options
@@ -64,6 +108,46 @@ func RecordLatency(labelValues map[string]string, latency float64) {
 	defaultRecorder.RecordLatency(labelValues, latency)
 }
 
+//RecordRPCStatus record an operation status of a rpc protocol,
+//metrics name is prefixed with protocol name, like dubbo_requests_total
+func RecordRPCStatus(protocol string, labelValues map[string]string, failed bool) {
+	defaultRecorder.RecordRPCStatus(protocol, labelValues, failed)
+}
+
+//RecordRPCLatency record an operation latency of a rpc protocol
+func RecordRPCLatency(protocol string, labelValues map[string]string, latency float64) {
+	defaultRecorder.RecordRPCLatency(protocol, labelValues, latency)
+}
+
+//RecordListenerGauge record a gauge of a protocol listener, like active connections,
+//metrics name is prefixed with protocol name, like dubbo_active_connections
+func RecordListenerGauge(protocol, name, listener string, val float64) {
+	defaultRecorder.RecordListenerGauge(protocol, name, listener, val)
+}
+
+//RecordConnection record a closed connection of a l4 protocol with bytes received from and sent to downstream,
+//metrics name is prefixed with protocol name, like tcp_received_bytes_total
+func RecordConnection(protocol string, labelValues map[string]string, received, sent int64, duration float64) {
+	defaultRecorder.RecordConnection(protocol, labelValues, received, sent, duration)
+}
+
+//RecordConnectionFailure record a connection of a l4 protocol which can not reach upstream
+func RecordConnectionFailure(protocol string, labelValues map[string]string) {
+	defaultRecorder.RecordConnectionFailure(protocol, labelValues)
+}
+
+//RecordRateLimited record a request rejected by rate limit of an ingress rule,
+//metrics name is prefixed with protocol name, like http_ratelimited_total
+func RecordRateLimited(protocol string, labelValues map[string]string) {
+	defaultRecorder.RecordRateLimited(protocol, labelValues)
+}
+
+//RecordConsumerRequest record a request of a consumer at the edge,
+//metrics name is prefixed with protocol name, like http_consumer_requests_total
+func RecordConsumerRequest(protocol string, labelValues map[string]string) {
+	defaultRecorder.RecordConsumerRequest(protocol, labelValues)
+}
+
 //RecordStartTime record mesher start time
 func RecordStartTime(labelValues map[string]string, start time.Time) {
 	defaultRecorder.RecordStartTime(labelValues, start)
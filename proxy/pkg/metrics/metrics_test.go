@@ -72,3 +72,52 @@ func TestRecordStatus(t *testing.T) {
 	}
 	assert.Equal(1000, int(c))
 }
+
+func TestRecordRPCStatus(t *testing.T) {
+	lvs := map[string]string{
+		metrics.LServiceName: "service",
+		metrics.LInterface:   "com.foo.Hello",
+		metrics.LMethod:      "sayHello",
+		metrics.LStatus:      "20",
+	}
+	metrics.RecordRPCStatus("dubbo", lvs, false)
+	lvs[metrics.LStatus] = "80"
+	metrics.RecordRPCStatus("dubbo", lvs, true)
+	metrics.RecordRPCLatency("dubbo", lvs, 2)
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	assert.NoError(t, err)
+	counts := map[string]float64{}
+	for _, metricFamily := range metricFamilies {
+		for _, m := range metricFamily.Metric {
+			if m.Counter != nil {
+				counts[metricFamily.GetName()] += m.Counter.GetValue()
+			}
+		}
+	}
+	assert.Equal(t, float64(2), counts["dubbo_"+metrics.LTotalRequest])
+	assert.Equal(t, float64(1), counts["dubbo_"+metrics.LTotalFailures])
+	assert.Equal(t, float64(1), counts["dubbo_"+metrics.LTotalSuccess])
+}
+
+func TestRecordConnection(t *testing.T) {
+	lvs := map[string]string{
+		metrics.LServiceName: "mysql",
+		metrics.LListener:    "127.0.0.1:3306",
+	}
+	metrics.RecordConnection("tcp", lvs, 10, 200, 1.5)
+	metrics.RecordConnectionFailure("tcp", lvs)
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	assert.NoError(t, err)
+	counts := map[string]float64{}
+	for _, metricFamily := range metricFamilies {
+		for _, m := range metricFamily.Metric {
+			if m.Counter != nil {
+				counts[metricFamily.GetName()] += m.Counter.GetValue()
+			}
+		}
+	}
+	assert.Equal(t, float64(2), counts["tcp_"+metrics.LTotalConnections])
+	assert.Equal(t, float64(1), counts["tcp_"+metrics.LConnectionFailures])
+	assert.Equal(t, float64(10), counts["tcp_"+metrics.LReceivedBytes])
+	assert.Equal(t, float64(200), counts["tcp_"+metrics.LSentBytes])
+}
@@ -56,6 +56,65 @@ func (e *PromRecorder) RecordLatency(LabelValues map[string]string, latency floa
 
 }
 
+//RecordRPCStatus record rpc operation status, the status code itself is kept as a label
+func (e *PromRecorder) RecordRPCStatus(protocol string, LabelValues map[string]string, failed bool) {
+	labels := pickLabels(RPCStatusLabelNames, LabelValues)
+	if failed {
+		DefaultPrometheusExporter.Count(protocol+"_"+LTotalFailures, RPCStatusLabelNames, labels)
+	} else {
+		DefaultPrometheusExporter.Count(protocol+"_"+LTotalSuccess, RPCStatusLabelNames, labels)
+	}
+	DefaultPrometheusExporter.Count(protocol+"_"+LTotalRequest, RPCStatusLabelNames, labels)
+}
+
+//RecordRPCLatency record rpc operation latency
+func (e *PromRecorder) RecordRPCLatency(protocol string, LabelValues map[string]string, latency float64) {
+	DefaultPrometheusExporter.Summary(protocol+"_"+LRequestLatencySeconds, latency, RPCLabelNames,
+		pickLabels(RPCLabelNames, LabelValues))
+}
+
+//RecordListenerGauge record a gauge of a protocol listener
+func (e *PromRecorder) RecordListenerGauge(protocol, name, listener string, val float64) {
+	DefaultPrometheusExporter.Gauge(protocol+"_"+name, val, []string{LListener},
+		map[string]string{LListener: listener})
+}
+
+//RecordConnection record bytes and duration of a connection
+func (e *PromRecorder) RecordConnection(protocol string, LabelValues map[string]string, received, sent int64,
+	duration float64) {
+	labels := pickLabels(ConnLabelNames, LabelValues)
+	DefaultPrometheusExporter.Count(protocol+"_"+LTotalConnections, ConnLabelNames, labels)
+	DefaultPrometheusExporter.Add(protocol+"_"+LReceivedBytes, float64(received), ConnLabelNames, labels)
+	DefaultPrometheusExporter.Add(protocol+"_"+LSentBytes, float64(sent), ConnLabelNames, labels)
+	DefaultPrometheusExporter.Summary(protocol+"_"+LConnectionDurSeconds, duration, ConnLabelNames, labels)
+}
+
+//RecordConnectionFailure record a failed connection
+func (e *PromRecorder) RecordConnectionFailure(protocol string, LabelValues map[string]string) {
+	labels := pickLabels(ConnLabelNames, LabelValues)
+	DefaultPrometheusExporter.Count(protocol+"_"+LTotalConnections, ConnLabelNames, labels)
+	DefaultPrometheusExporter.Count(protocol+"_"+LConnectionFailures, ConnLabelNames, labels)
+}
+
+//RecordRateLimited record a rejected request of an ingress rule
+func (e *PromRecorder) RecordRateLimited(protocol string, LabelValues map[string]string) {
+	DefaultPrometheusExporter.Count(protocol+"_"+LRateLimited, RouteLabelNames, pickLabels(RouteLabelNames, LabelValues))
+}
+
+//RecordConsumerRequest record a request of a consumer
+func (e *PromRecorder) RecordConsumerRequest(protocol string, LabelValues map[string]string) {
+	DefaultPrometheusExporter.Count(protocol+"_"+LConsumerRequests, ConsumerLabelNames, pickLabels(ConsumerLabelNames, LabelValues))
+}
+
+//pickLabels returns label values of given names, missing label is set to empty
+func pickLabels(names []string, LabelValues map[string]string) map[string]string {
+	labels := make(map[string]string, len(names))
+	for _, n := range names {
+		labels[n] = LabelValues[n]
+	}
+	return labels
+}
+
 //RecordStartTime save start time
 func (e *PromRecorder) RecordStartTime(LabelValues map[string]string, start time.Time) {
 	DefaultPrometheusExporter.Gauge(LStartTime, float64(start.Unix()), e.LabelNames, LabelValues)
@@ -68,6 +68,11 @@ func GetPrometheusExporter() *PrometheusExporter {
 
 //Count function returns count
 func (s *PrometheusExporter) Count(name string, labelNames []string, labels prometheus.Labels) {
+	s.Add(name, 1, labelNames, labels)
+}
+
+//Add function adds val to a counter
+func (s *PrometheusExporter) Add(name string, val float64, labelNames []string, labels prometheus.Labels) {
 	s.countersMutex.RLock()
 	cv, ok := s.counters[name]
 	s.countersMutex.RUnlock()
@@ -81,7 +86,7 @@ func (s *PrometheusExporter) Count(name string, labelNames []string, labels prom
 		s.counters[name] = cv
 		defer s.countersMutex.Unlock()
 	}
-	cv.With(labels).Add(1)
+	cv.With(labels).Add(val)
 
 }
 
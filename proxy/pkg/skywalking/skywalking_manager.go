@@ -39,16 +39,25 @@ const (
 var r go2sky.Reporter
 var tracer *go2sky.Tracer
 
+//OperationName returns span operation name of an invocation,
+//rpc protocols like dubbo have no url path, so schema and operation id are used
+func OperationName(i *invocation.Invocation) string {
+	if i.URLPath == "" && i.OperationID != "" {
+		return i.MicroServiceName + "/" + i.SchemaID + "." + i.OperationID
+	}
+	return i.MicroServiceName + i.URLPath
+}
+
 //CreateEntrySpan use tracer to create and start an entry span for incoming request
 func CreateEntrySpan(i *invocation.Invocation) (go2sky.Span, context.Context, error) {
-	return tracer.CreateEntrySpan(i.Ctx, i.MicroServiceName+i.URLPath, func() (string, error) {
+	return tracer.CreateEntrySpan(i.Ctx, OperationName(i), func() (string, error) {
 		return i.Headers()[CrossProcessProtocolV2], nil
 	})
 }
 
 //CreateExitSpan use tracer to create and start an exit span for client
 func CreateExitSpan(ctx context.Context, i *invocation.Invocation) (go2sky.Span, error) {
-	return tracer.CreateExitSpan(ctx, i.MicroServiceName+i.URLPath, i.Endpoint+i.URLPath, func(header string) error {
+	return tracer.CreateExitSpan(ctx, OperationName(i), i.Endpoint+i.URLPath, func(header string) error {
 		i.SetHeader(CrossProcessProtocolV2, header)
 		return nil
 	})
@@ -0,0 +1,264 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sniff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+//ErrNeedMore means bytes peeked are not enough to match a protocol
+var ErrNeedMore = errors.New("need more bytes")
+
+//Matcher returns the protocol of a connection from its first bytes, it returns empty string if not matched,
+//and ErrNeedMore if it can not decide with bytes peeked so far
+type Matcher func(b []byte) (string, error)
+
+//Protocol names matched by built in matchers
+const (
+	ProtocolHTTP  = "http"
+	ProtocolGRPC  = "grpc"
+	ProtocolDubbo = "dubbo"
+	//ProtocolHTTP2 is matched by http2 preface or h2 in ALPN, connections of it go to the http2 based protocol
+	//listening on the address by ListenHTTP2, like grpc or triple
+	ProtocolHTTP2 = "h2"
+)
+
+//HTTP2Preface is the client connection preface of http2
+const HTTP2Preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+var httpMethods = []string{"GET ", "POST ", "PUT ", "DELETE ", "HEAD ", "OPTIONS ", "PATCH ", "TRACE ", "CONNECT "}
+
+type namedMatcher struct {
+	name string
+	m    Matcher
+}
+
+//matchers are tried in install order
+var matchers []namedMatcher
+
+//InstallMatcher installs a matcher, a matcher with same name is replaced
+func InstallMatcher(name string, m Matcher) {
+	for i := range matchers {
+		if matchers[i].name == name {
+			matchers[i].m = m
+			return
+		}
+	}
+	matchers = append(matchers, namedMatcher{name: name, m: m})
+}
+
+func init() {
+	InstallMatcher(ProtocolHTTP2, MatchHTTP2)
+	InstallMatcher(ProtocolDubbo, MatchDubbo)
+	InstallMatcher("tls", MatchTLS)
+	InstallMatcher(ProtocolHTTP, MatchHTTP)
+}
+
+//match tries all matchers, it returns ErrNeedMore if no matcher matches and any of them needs more bytes
+func match(b []byte) (string, error) {
+	var err error
+	for _, nm := range matchers {
+		p, e := nm.m(b)
+		if e == ErrNeedMore {
+			err = e
+			continue
+		}
+		if p != "" {
+			return p, nil
+		}
+	}
+	return "", err
+}
+
+//hasPrefix checks that b starts with prefix, it returns ErrNeedMore if b is a part of prefix
+func hasPrefix(b []byte, prefix string) (bool, error) {
+	if len(b) < len(prefix) {
+		if bytes.Equal(b, []byte(prefix[:len(b)])) {
+			return false, ErrNeedMore
+		}
+		return false, nil
+	}
+	return bytes.HasPrefix(b, []byte(prefix)), nil
+}
+
+//MatchHTTP2 matches http2 connection preface
+func MatchHTTP2(b []byte) (string, error) {
+	ok, err := hasPrefix(b, HTTP2Preface)
+	if ok {
+		return ProtocolHTTP2, nil
+	}
+	return "", err
+}
+
+//MatchDubbo matches the magic of dubbo header
+func MatchDubbo(b []byte) (string, error) {
+	ok, err := hasPrefix(b, "\xda\xbb")
+	if ok {
+		return ProtocolDubbo, nil
+	}
+	return "", err
+}
+
+//MatchHTTP matches method of http1 request line
+func MatchHTTP(b []byte) (string, error) {
+	var err error
+	for _, m := range httpMethods {
+		ok, e := hasPrefix(b, m)
+		if ok {
+			return ProtocolHTTP, nil
+		}
+		if e != nil {
+			err = e
+		}
+	}
+	return "", err
+}
+
+//MatchTLS matches TLS ClientHello, the protocol is decided by ALPN of it,
+//h2 goes to the http2 based protocol, others go to the protocol set by mesher.sniff.tlsProtocol
+func MatchTLS(b []byte) (string, error) {
+	//record type handshake, version 3.x
+	ok, err := hasPrefix(b, "\x16\x03")
+	if !ok {
+		return "", err
+	}
+	if len(b) < 5 {
+		return "", ErrNeedMore
+	}
+	n := int(binary.BigEndian.Uint16(b[3:5]))
+	if len(b) < 5+n {
+		if 5+n > MaxPeekSize {
+			return tlsProtocol(nil), nil
+		}
+		return "", ErrNeedMore
+	}
+	return tlsProtocol(parseALPN(b[5 : 5+n])), nil
+}
+
+func tlsProtocol(alpn []string) string {
+	for _, p := range alpn {
+		if p == "h2" {
+			return ProtocolHTTP2
+		}
+	}
+	return defaultTLSProtocol()
+}
+
+//parseALPN returns protocols of ALPN extension in a ClientHello handshake message,
+//it returns nil if message is not a ClientHello or it has no ALPN
+func parseALPN(b []byte) []string {
+	r := reader(b)
+	//handshake type ClientHello and length
+	if t, ok := r.u8(); !ok || t != 1 {
+		return nil
+	}
+	if _, ok := r.next(3); !ok {
+		return nil
+	}
+	//version and random
+	if _, ok := r.next(2 + 32); !ok {
+		return nil
+	}
+	//session ID, cipher suites and compression methods
+	if _, ok := r.vector(1); !ok {
+		return nil
+	}
+	if _, ok := r.vector(2); !ok {
+		return nil
+	}
+	if _, ok := r.vector(1); !ok {
+		return nil
+	}
+	ext, ok := r.vector(2)
+	if !ok {
+		return nil
+	}
+	for len(ext) > 0 {
+		typ, ok := ext.u16()
+		if !ok {
+			return nil
+		}
+		data, ok := ext.vector(2)
+		if !ok {
+			return nil
+		}
+		//application_layer_protocol_negotiation
+		if typ != 16 {
+			continue
+		}
+		list, ok := data.vector(2)
+		if !ok {
+			return nil
+		}
+		var protos []string
+		for len(list) > 0 {
+			p, ok := list.vector(1)
+			if !ok {
+				return nil
+			}
+			protos = append(protos, string(p))
+		}
+		return protos
+	}
+	return nil
+}
+
+//reader reads TLS handshake fields
+type reader []byte
+
+func (r *reader) next(n int) (reader, bool) {
+	if n > len(*r) {
+		return nil, false
+	}
+	v := (*r)[:n]
+	*r = (*r)[n:]
+	return v, true
+}
+
+func (r *reader) u8() (int, bool) {
+	v, ok := r.next(1)
+	if !ok {
+		return 0, false
+	}
+	return int(v[0]), true
+}
+
+func (r *reader) u16() (int, bool) {
+	v, ok := r.next(2)
+	if !ok {
+		return 0, false
+	}
+	return int(binary.BigEndian.Uint16(v)), true
+}
+
+//vector reads a field prefixed with its length in size bytes
+func (r *reader) vector(size int) (reader, bool) {
+	var n int
+	var ok bool
+	switch size {
+	case 1:
+		n, ok = r.u8()
+	default:
+		n, ok = r.u16()
+	}
+	if !ok {
+		return nil, false
+	}
+	return r.next(n)
+}
@@ -0,0 +1,302 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+//Package sniff lets protocol servers share one listen address. When sniffing is enabled,
+//servers listening on the same address share one listener, the first bytes of every connection
+//are peeked to decide which protocol it is, then the connection is accepted by the server of that protocol
+package sniff
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/pkg/ports"
+	"github.com/go-chassis/go-archaius"
+	chassisCommon "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/openlog"
+)
+
+//Config keys of sniffing
+const (
+	KeyEnabled = "mesher.sniff.enabled"
+	//KeyTimeout is the max time to wait for the first bytes of a connection
+	KeyTimeout = "mesher.sniff.timeout"
+	//KeyDefaultProtocol is the protocol of connections not matched by any matcher
+	KeyDefaultProtocol = "mesher.sniff.defaultProtocol"
+	//KeyTLSProtocol is the protocol of TLS connections without h2 in ALPN
+	KeyTLSProtocol = "mesher.sniff.tlsProtocol"
+)
+
+//DefaultTimeout is the default time to wait for the first bytes
+const DefaultTimeout = 5 * time.Second
+
+//MaxPeekSize is the max bytes peeked, it holds a TLS record
+const MaxPeekSize = 5 + 16384
+
+//ErrListenerClosed is returned by Accept of a closed listener
+var ErrListenerClosed = errors.New("sniff listener closed")
+
+var (
+	mu      sync.Mutex
+	sharers = make(map[string]*sharedListener)
+)
+
+//Enabled returns whether sniffing is enabled
+func Enabled() bool {
+	return archaius.GetBool(KeyEnabled, false)
+}
+
+func defaultTLSProtocol() string {
+	return archaius.GetString(KeyTLSProtocol, ProtocolHTTP)
+}
+
+func timeout() time.Duration {
+	s := archaius.GetString(KeyTimeout, "")
+	if s == "" {
+		return DefaultTimeout
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		openlog.Warn(fmt.Sprintf("invalid %s %s, use default %s", KeyTimeout, s, DefaultTimeout))
+		return DefaultTimeout
+	}
+	return d
+}
+
+//Listen listens on addr for protocol. If sniffing is disabled, it is the same as net.Listen,
+//otherwise protocols listening on the same address share one listener,
+//and the fixed port of protocol becomes the port of addr, so that port selector handler targets it
+func Listen(network, addr, protocol string) (net.Listener, error) {
+	return listen(network, addr, protocol, false)
+}
+
+//ListenHTTP2 is Listen of a protocol on top of http2, which accepts connections matched as ProtocolHTTP2.
+//Http2 based protocols like grpc and triple can not be told apart by first bytes,
+//so only one of them can listen on an address
+func ListenHTTP2(network, addr, protocol string) (net.Listener, error) {
+	return listen(network, addr, protocol, true)
+}
+
+func listen(network, addr, protocol string, h2 bool) (net.Listener, error) {
+	if !Enabled() {
+		return net.Listen(network, addr)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	s, ok := sharers[addr]
+	if !ok {
+		ln, err := net.Listen(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		s = &sharedListener{ln: ln, addr: addr, subs: make(map[string]*listener)}
+		sharers[addr] = s
+		go s.acceptLoop()
+		openlog.Info("sniff protocols on " + addr)
+	}
+	l, err := s.add(protocol, h2)
+	if err != nil {
+		return nil, err
+	}
+	if _, port, err := net.SplitHostPort(addr); err == nil {
+		p := protocol
+		if p == ProtocolHTTP {
+			p = chassisCommon.ProtocolRest
+		}
+		ports.SetFixedPort(p, port)
+	}
+	return l, nil
+}
+
+//sharedListener accepts connections for listeners of protocols
+type sharedListener struct {
+	ln   net.Listener
+	addr string
+	mu   sync.Mutex
+	subs map[string]*listener
+	//h2 is the protocol accepting http2 connections
+	h2 string
+}
+
+func (s *sharedListener) add(protocol string, h2 bool) (*listener, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[protocol]; ok {
+		return nil, fmt.Errorf("protocol %s already listens on %s", protocol, s.addr)
+	}
+	if h2 {
+		if s.h2 != "" {
+			return nil, fmt.Errorf("protocol %s and %s are both http2 based, they can not share %s", s.h2, protocol, s.addr)
+		}
+		s.h2 = protocol
+	}
+	l := &listener{
+		shared:   s,
+		protocol: protocol,
+		conns:    make(chan net.Conn),
+		done:     make(chan struct{}),
+	}
+	s.subs[protocol] = l
+	return l, nil
+}
+
+//remove removes listener of protocol, the shared listener is closed when no one listens
+func (s *sharedListener) remove(protocol string) {
+	mu.Lock()
+	defer mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subs, protocol)
+	if s.h2 == protocol {
+		s.h2 = ""
+	}
+	if len(s.subs) == 0 {
+		s.ln.Close()
+		delete(sharers, s.addr)
+	}
+}
+
+//get returns listener of protocol, http2 connections go to the http2 based protocol
+func (s *sharedListener) get(protocol string) *listener {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if protocol == ProtocolHTTP2 {
+		protocol = s.h2
+	}
+	return s.subs[protocol]
+}
+
+func (s *sharedListener) acceptLoop() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			s.mu.Lock()
+			for _, l := range s.subs {
+				l.closeWith(err)
+			}
+			s.mu.Unlock()
+			return
+		}
+		go s.dispatch(conn)
+	}
+}
+
+//dispatch peeks first bytes of conn and hands it to the listener of its protocol
+func (s *sharedListener) dispatch(conn net.Conn) {
+	pc, protocol, err := Peek(conn, timeout())
+	if err != nil {
+		openlog.Debug(fmt.Sprintf("sniff protocol of %s failed: %s", conn.RemoteAddr(), err))
+		conn.Close()
+		return
+	}
+	l := s.get(protocol)
+	if l == nil {
+		openlog.Warn(fmt.Sprintf("no %s server on %s, close connection from %s",
+			protocol, s.addr, conn.RemoteAddr()))
+		conn.Close()
+		return
+	}
+	select {
+	case l.conns <- pc:
+	case <-l.done:
+		conn.Close()
+	}
+}
+
+//Peek reads the first bytes of conn until a matcher matches, the returned connection reads from the first byte.
+//Connections not matched go to mesher.sniff.defaultProtocol
+func Peek(conn net.Conn, d time.Duration) (net.Conn, string, error) {
+	r := bufio.NewReaderSize(conn, MaxPeekSize)
+	conn.SetReadDeadline(time.Now().Add(d))
+	defer conn.SetReadDeadline(time.Time{})
+	n := 1
+	for {
+		b, err := r.Peek(n)
+		if err != nil {
+			return nil, "", err
+		}
+		//bytes already buffered are matched together
+		b, _ = r.Peek(r.Buffered())
+		protocol, err := match(b)
+		if err == nil {
+			if protocol == "" {
+				protocol = archaius.GetString(KeyDefaultProtocol, ProtocolHTTP)
+			}
+			return &peekedConn{Conn: conn, r: r}, protocol, nil
+		}
+		if len(b) >= MaxPeekSize {
+			return nil, "", ErrNeedMore
+		}
+		n = len(b) + 1
+	}
+}
+
+//peekedConn reads bytes peeked before reading the connection
+type peekedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *peekedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+//listener is the listener of a protocol on a shared listener
+type listener struct {
+	shared   *sharedListener
+	protocol string
+	conns    chan net.Conn
+	once     sync.Once
+	done     chan struct{}
+	err      error
+}
+
+//Accept waits for the next connection of the protocol
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done:
+		return nil, l.err
+	}
+}
+
+//Close stops accepting connections of the protocol
+func (l *listener) Close() error {
+	l.closeWith(ErrListenerClosed)
+	l.shared.remove(l.protocol)
+	return nil
+}
+
+func (l *listener) closeWith(err error) {
+	l.once.Do(func() {
+		l.err = err
+		close(l.done)
+	})
+}
+
+//Addr returns address of the shared listener
+func (l *listener) Addr() net.Addr {
+	return l.shared.ln.Addr()
+}
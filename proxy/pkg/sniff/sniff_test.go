@@ -0,0 +1,174 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sniff
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/pkg/ports"
+	"github.com/go-chassis/go-archaius"
+	chassisCommon "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/stretchr/testify/assert"
+)
+
+//clientHello returns the first bytes sent by a TLS client with ALPN protos
+func clientHello(t *testing.T, protos ...string) []byte {
+	c, s := net.Pipe()
+	defer s.Close()
+	go func() {
+		tls.Client(c, &tls.Config{InsecureSkipVerify: true, NextProtos: protos}).Handshake()
+		c.Close()
+	}()
+	b := make([]byte, MaxPeekSize)
+	n, err := io.ReadAtLeast(s, b, 5)
+	assert.NoError(t, err)
+	return b[:n]
+}
+
+func TestMatch(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	cases := []struct {
+		b        string
+		protocol string
+		err      error
+	}{
+		{HTTP2Preface + "\x00\x00", ProtocolHTTP2, nil},
+		{"PRI * HTTP", "", ErrNeedMore},
+		{"\xda\xbb\xc2\x00", ProtocolDubbo, nil},
+		{"\xda", "", ErrNeedMore},
+		{"GET / HTTP/1.1\r\n", ProtocolHTTP, nil},
+		{"POST /a HTTP/1.1\r\n", ProtocolHTTP, nil},
+		{"PU", "", ErrNeedMore},
+		{"\x16\x03\x01", "", ErrNeedMore},
+		{"*1\r\n$4\r\nPING\r\n", "", nil},
+	}
+	for _, c := range cases {
+		p, err := match([]byte(c.b))
+		assert.Equal(t, c.protocol, p, c.b)
+		assert.Equal(t, c.err, err, c.b)
+	}
+
+	p, err := match(clientHello(t, "h2", "http/1.1"))
+	assert.NoError(t, err)
+	assert.Equal(t, ProtocolHTTP2, p)
+	p, err = match(clientHello(t, "http/1.1"))
+	assert.NoError(t, err)
+	assert.Equal(t, ProtocolHTTP, p)
+	archaius.Set(KeyTLSProtocol, ProtocolDubbo)
+	defer archaius.Delete(KeyTLSProtocol)
+	p, err = match(clientHello(t))
+	assert.NoError(t, err)
+	assert.Equal(t, ProtocolDubbo, p)
+	h := clientHello(t, "h2")
+	_, err = match(h[:len(h)-1])
+	assert.Equal(t, ErrNeedMore, err)
+}
+
+func TestListen(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	//disabled
+	ln, err := Listen("tcp", "127.0.0.1:0", ProtocolHTTP)
+	assert.NoError(t, err)
+	_, ok := ln.(*net.TCPListener)
+	assert.True(t, ok)
+	ln.Close()
+
+	archaius.Set(KeyEnabled, true)
+	archaius.Set(KeyTimeout, "200ms")
+	defer archaius.Delete(KeyEnabled)
+	defer archaius.Delete(KeyTimeout)
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := free.Addr().String()
+	free.Close()
+	h, err := Listen("tcp", addr, ProtocolHTTP)
+	assert.NoError(t, err)
+	g, err := ListenHTTP2("tcp", addr, ProtocolGRPC)
+	assert.NoError(t, err)
+	_, err = Listen("tcp", addr, ProtocolGRPC)
+	assert.Error(t, err)
+	//grpc and triple can not be told apart
+	_, err = ListenHTTP2("tcp", addr, "triple")
+	assert.Error(t, err)
+	assert.Equal(t, h.Addr(), g.Addr())
+	_, port, _ := net.SplitHostPort(addr)
+	assert.Equal(t, port, ports.GetFixedPort(chassisCommon.ProtocolRest))
+	assert.Equal(t, port, ports.GetFixedPort(ProtocolGRPC))
+	target := addr
+
+	send := func(data string) net.Conn {
+		c, err := net.Dial("tcp", target)
+		assert.NoError(t, err)
+		_, err = c.Write([]byte(data))
+		assert.NoError(t, err)
+		return c
+	}
+	expect := func(l net.Listener, data string) {
+		c, err := l.Accept()
+		assert.NoError(t, err)
+		defer c.Close()
+		b := make([]byte, len(data))
+		_, err = io.ReadFull(c, b)
+		assert.NoError(t, err)
+		assert.Equal(t, data, string(b))
+	}
+	c := send("GET / HTTP/1.1\r\n\r\n")
+	defer c.Close()
+	expect(h, "GET / HTTP/1.1\r\n\r\n")
+	c = send(HTTP2Preface)
+	defer c.Close()
+	expect(g, HTTP2Preface)
+	//not matched, default protocol
+	c = send("hello")
+	defer c.Close()
+	expect(h, "hello")
+
+	//no dubbo server, connection is closed
+	c = send("\xda\xbb")
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = c.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+	//nothing sent in time
+	c, err = net.Dial("tcp", target)
+	assert.NoError(t, err)
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = c.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+
+	//http2 connections go to the http2 based protocol listening
+	assert.NoError(t, g.Close())
+	_, err = g.Accept()
+	assert.Equal(t, ErrListenerClosed, err)
+	tri, err := ListenHTTP2("tcp", addr, "triple")
+	assert.NoError(t, err)
+	c = send(HTTP2Preface)
+	defer c.Close()
+	expect(tri, HTTP2Preface)
+
+	//shared listener is closed after all listeners are closed
+	assert.NoError(t, tri.Close())
+	c = send("GET / HTTP/1.1\r\n\r\n")
+	defer c.Close()
+	expect(h, "GET / HTTP/1.1\r\n\r\n")
+	assert.NoError(t, h.Close())
+	_, err = net.Dial("tcp", target)
+	assert.Error(t, err)
+}
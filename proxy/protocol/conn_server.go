@@ -0,0 +1,136 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package protocol
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
+	"github.com/go-chassis/go-chassis/v2/core/server"
+	"github.com/go-chassis/openlog"
+)
+
+//ConnServer listens on addresses and tracks opened connections for servers of connection based protocols,
+//like tcp, redis, thrift and highway, so that all listeners and connections are closed when server stops.
+//It is embedded in protocol servers, Protocol must be set
+type ConnServer struct {
+	Protocol  string
+	mu        sync.Mutex
+	stopped   bool
+	listeners []net.Listener
+	conns     map[net.Conn]struct{}
+	//active connections of every listener, which is the value of active connections gauge
+	active map[string]int
+}
+
+//Listen listens on addr, TLS is used if t is not nil, serve is called in a new goroutine for every connection
+//with the listen address, which is the listener label of metrics
+func (s *ConnServer) Listen(addr string, t *tls.Config, serve func(conn net.Conn, listener string)) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if t != nil {
+		ln = tls.NewListener(ln, t)
+	}
+	s.mu.Lock()
+	s.listeners = append(s.listeners, ln)
+	s.mu.Unlock()
+	openlog.Info(s.Protocol + " proxy listen on " + addr)
+	go s.acceptLoop(ln, addr, serve)
+	return nil
+}
+
+func (s *ConnServer) acceptLoop(ln net.Listener, addr string, serve func(conn net.Conn, listener string)) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.isStopped() {
+				return
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			server.ErrRuntime <- err
+			return
+		}
+		if !s.track(conn, addr) {
+			conn.Close()
+			return
+		}
+		go func() {
+			defer s.untrack(conn, addr)
+			serve(conn, addr)
+		}()
+	}
+}
+
+func (s *ConnServer) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+//track remembers an opened connection so that it is closed when server stops
+func (s *ConnServer) track(conn net.Conn, listener string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]struct{})
+		s.active = make(map[string]int)
+	}
+	s.conns[conn] = struct{}{}
+	s.active[listener]++
+	metrics.RecordListenerGauge(s.Protocol, metrics.LActiveConnections, listener, float64(s.active[listener]))
+	return true
+}
+
+func (s *ConnServer) untrack(conn net.Conn, listener string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+	s.active[listener]--
+	metrics.RecordListenerGauge(s.Protocol, metrics.LActiveConnections, listener, float64(s.active[listener]))
+}
+
+//Stop closes all listeners and connections
+func (s *ConnServer) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	for _, ln := range s.listeners {
+		ln.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	openlog.Info(s.Protocol + " proxy stopped")
+	return nil
+}
+
+//String returns the protocol name
+func (s *ConnServer) String() string {
+	return s.Protocol
+}
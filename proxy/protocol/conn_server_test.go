@@ -0,0 +1,60 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package protocol
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnServer(t *testing.T) {
+	s := &ConnServer{Protocol: "test"}
+	assert.Equal(t, "test", s.String())
+	served := make(chan string, 1)
+	assert.NoError(t, s.Listen("127.0.0.1:0", nil, func(conn net.Conn, listener string) {
+		served <- listener
+		conn.Read(make([]byte, 1))
+	}))
+	addr := s.listeners[0].Addr().String()
+	conn, err := net.Dial("tcp", addr)
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "127.0.0.1:0", <-served)
+
+	assert.NoError(t, s.Stop())
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err, "connection must be closed when server stops")
+	_, err = net.Dial("tcp", addr)
+	assert.Error(t, err, "listener must be closed when server stops")
+}
+
+func TestConnServer_ActiveConnections(t *testing.T) {
+	s := &ConnServer{Protocol: "test"}
+	a, b := &net.TCPConn{}, &net.TCPConn{}
+	assert.True(t, s.track(a, "127.0.0.1:1"))
+	assert.True(t, s.track(b, "127.0.0.1:2"))
+	assert.Equal(t, 1, s.active["127.0.0.1:1"], "connections of other listeners must not be counted")
+	s.untrack(b, "127.0.0.1:2")
+	assert.Equal(t, 1, s.active["127.0.0.1:1"])
+	assert.Equal(t, 0, s.active["127.0.0.1:2"])
+}
@@ -26,6 +26,22 @@ import (
 	"sync"
 )
 
+//MaxPayload limits body length of a response frame, connection receiving a larger frame is closed
+var MaxPayload = dubbo.DefaultMaxPayload
+
+var (
+	pool     *util.WorkerPool
+	poolOnce sync.Once
+)
+
+//processPool returns the worker pool shared by all client connections to process responses
+func processPool() *util.WorkerPool {
+	poolOnce.Do(func() {
+		pool = util.NewWorkerPool(util.DefaultWorkers, util.DefaultQueueSize)
+	})
+	return pool
+}
+
 //SndTask is a struct
 type SndTask struct{}
 
@@ -115,52 +131,27 @@ func (this *DubboClientConnection) Close() {
 
 //MsgRecvLoop is a method which receives message
 func (this *DubboClientConnection) MsgRecvLoop() {
-	//通知处理应答消息
+	//header buffer is reused by every frame, decoded response does not refer to it
+	header := make([]byte, dubbo.HeaderLength)
 	for {
-		//先处理消息头
-		buf := make([]byte, dubbo.HeaderLength)
-		size, err := this.conn.Read(buf)
+		body, err := dubbo.ReadFrame(this.conn, header, MaxPayload)
 		if err != nil {
-			if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
-				openlog.Error("client Recv head time err:" + err.Error())
-				//time.Sleep(time.Second * 3)
-				continue
-			}
-			openlog.Error("client Recv head err:" + err.Error())
+			//通知关闭连接
+			openlog.Error("client Recv err:" + err.Error())
 			break
 		}
-
-		if size < dubbo.HeaderLength {
-			continue
-		}
 		rsp := new(dubbo.DubboRsp)
 		bodyLen := 0
-		ret := this.codec.DecodeDubboRsqHead(rsp, buf, &bodyLen)
+		ret := this.codec.DecodeDubboRsqHead(rsp, header, &bodyLen)
 		if ret != dubbo.Success {
 			openlog.Info("Recv DecodeDubboRsqHead failed")
 			continue
 		}
-		body := make([]byte, bodyLen)
-		count := 0
-		for {
-			redBuff := body[count:]
-			size, err = this.conn.Read(redBuff)
-			if err != nil {
-				if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
-					continue
-				}
-				//通知关闭连接
-				openlog.Error("Recv client body err:" + err.Error())
-				goto exitloop
-			}
-			count += size
-			if count == bodyLen {
-				break
-			}
+		if !processPool().Submit(ProcessTask{this, rsp, body}, nil) {
+			//workers are busy, slow down reading instead of dropping the response
+			this.ProcessBody(rsp, body)
 		}
-		this.routineMgr.Spawn(ProcessTask{this, rsp, body}, nil, fmt.Sprintf("Client ProcessTask-%d", rsp.GetID()))
 	}
-exitloop:
 	this.Close()
 }
 
@@ -194,10 +185,10 @@ func (this *DubboClientConnection) MsgSndLoop() {
 			openlog.Error("MsgSndLoop Dequeue:" + err.Error())
 			break
 		}
-		var buffer util.WriteBuffer
-		buffer.Init(0)
-		this.codec.EncodeDubboReq(msg.(*dubbo.Request), &buffer)
+		buffer := util.AcquireWriteBuffer()
+		this.codec.EncodeDubboReq(msg.(*dubbo.Request), buffer)
 		_, err = this.conn.Write(buffer.GetValidData())
+		util.ReleaseWriteBuffer(buffer)
 		if err != nil {
 			openlog.Error("Send exception:" + err.Error())
 			break
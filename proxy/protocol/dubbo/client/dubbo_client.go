@@ -57,6 +57,14 @@ type RespondResult struct {
 	Wait *chan int
 }
 
+//notify wakes up the request waiting for result, it never blocks, the request may be timed out
+func (r *RespondResult) notify() {
+	select {
+	case *r.Wait <- 1:
+	default:
+	}
+}
+
 //ClientMgr is a struct which has attributes for managing client
 type ClientMgr struct {
 	mapMutex sync.Mutex
@@ -176,7 +184,7 @@ func (this *DubboClient) close() {
 	this.closed = true
 	this.mapMutex.Lock()
 	for _, v := range this.msgWaitRspMap {
-		*v.Wait <- 1
+		v.notify()
 	}
 	this.msgWaitRspMap = make(map[int64]*RespondResult) //清空map
 	this.mapMutex.Unlock()
@@ -216,7 +224,8 @@ func (this *DubboClient) Send(dubboReq *dubbo.Request) (*dubbo.DubboRsp, error)
 		}
 	}
 	this.mapMutex.Unlock()
-	wait := make(chan int)
+	//buffered, so that response or close does not block when request is timed out
+	wait := make(chan int, 1)
 	result := &RespondResult{nil, &wait}
 	msgID := dubboReq.GetMsgID()
 	this.AddWaitMsg(msgID, result)
@@ -255,7 +264,7 @@ func (this *DubboClient) RspCallBack(rsp *dubbo.DubboRsp) {
 	if _, ok := this.msgWaitRspMap[msgID]; ok {
 		result = this.msgWaitRspMap[msgID]
 		result.Rsp = rsp
-		*result.Wait <- 1
+		result.notify()
 	}
 }
 
@@ -41,6 +41,7 @@ const (
 	PathKey            string = "path"
 	InterfaceKey       string = "interface"
 	VersionKey         string = "version"
+	GroupKey           string = "group"
 	CommaSeparator     string = ","
 	FileSeparator      string = "/"
 	SemicolonSeparator string = ";"
@@ -222,6 +223,25 @@ func (p *DubboCodec) DecodeDubboRspBody(buffer *util.ReadBuffer, rsp *DubboRsp)
 	return 0
 }
 
+//EncodeHeartbeatReq is a method which encodes a two way heartbeat request with null data
+func (p *DubboCodec) EncodeHeartbeatReq(id int64, buffer *util.WriteBuffer) int {
+	header := make([]byte, HeaderLength)
+	util.Short2bytes(Magic, header, 0)
+	header[2] = FlagRequest | FlagTwoWay | FlagEvent | p.GetContentTypeID()
+	util.Long2bytes(id, header, 4)
+	if buffer.WriteIndex(HeaderLength) != nil {
+		return -1
+	}
+	buffer.WriteObject(nil)
+
+	len := buffer.WrittenBytes() - HeaderLength
+	util.Int2bytes(len, header, 12)
+	buffer.WriteIndex(0)
+	buffer.WriteBytes(header)
+	buffer.WriteIndex(HeaderLength + len)
+	return 0
+}
+
 //EncodeDubboReq is a method which encodes dubbo request
 func (p *DubboCodec) EncodeDubboReq(req *Request, buffer *util.WriteBuffer) int {
 	// set Magic number.
@@ -353,6 +373,17 @@ func (p *DubboCodec) DecodeDubboReqBody(req *Request, bodyBuf *util.ReadBuffer)
 		req.SetMethodName(bodyBuf.ReadString())
 
 		obj = bodyBuf.GetBuf()
+		if args, attachments, offset, ok := p.decodeReqArgsAndAttachments(bodyBuf); ok {
+			for k, v := range attachments {
+				req.SetAttachment(k, v)
+			}
+			req.SetArguments(args)
+			if IsGenericMethod(req.GetMethodName()) {
+				req.SetGenericCall(NewGenericCall(args))
+			}
+			//attachments are encoded again behind the arguments, so that changes on them are sent out
+			obj = bodyBuf.GetBuf()[:offset]
+		}
 		req.SetBroken(false)
 		req.SetData(obj)
 	}
@@ -360,6 +391,53 @@ func (p *DubboCodec) DecodeDubboReqBody(req *Request, bodyBuf *util.ReadBuffer)
 	return 0
 }
 
+//decodeReqArgsAndAttachments reads the arguments of request body and the attachments behind them,
+//it returns the offset where the attachments begin
+func (p *DubboCodec) decodeReqArgsAndAttachments(bodyBuf *util.ReadBuffer) ([]util.Argument, map[string]string, int, bool) {
+	start := bodyBuf.ReadIndex()
+	d := util.NewObjectDecoder(bodyBuf.GetBuf()[start:])
+	obj, err := d.ReadObject()
+	if err != nil {
+		return nil, nil, 0, false
+	}
+	var args []util.Argument
+	if typeDesc, ok := obj.(string); ok && typeDesc != "" {
+		args = util.TypeDesToArgsObjArry(typeDesc)
+		for i := range args {
+			v, err := d.ReadObject()
+			if err != nil {
+				return nil, nil, 0, false
+			}
+			args[i].SetValue(v)
+		}
+	}
+	offset := start + d.Offset()
+	obj, err = d.ReadObject()
+	if err != nil {
+		return nil, nil, 0, false
+	}
+	attachments := make(map[string]string)
+	switch m := obj.(type) {
+	case map[string]interface{}:
+		for k, v := range m {
+			if s, ok := v.(string); ok {
+				attachments[k] = s
+			}
+		}
+	case map[interface{}]interface{}:
+		for k, v := range m {
+			ks, ok1 := k.(string)
+			vs, ok2 := v.(string)
+			if ok1 && ok2 {
+				attachments[ks] = vs
+			}
+		}
+	default:
+		return nil, nil, 0, false
+	}
+	return args, attachments, offset, true
+}
+
 //DecodeDubboReqHead is a method which decodes dubbo request header
 func (p *DubboCodec) DecodeDubboReqHead(req *Request, header []byte, bodyLen *int) int {
 	if len(header) < HeaderLength {
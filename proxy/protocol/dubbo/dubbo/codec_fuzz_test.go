@@ -0,0 +1,83 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/utils"
+)
+
+//FuzzDubboCodec feeds malformed or truncated frames to codec, decoding must neither panic nor allocate by wire length
+func FuzzDubboCodec(f *testing.F) {
+	var body util.WriteBuffer
+	body.Init(0)
+	body.WriteObject("2.0.2")
+	body.WriteObject("com.foo.Hello")
+	body.WriteObject("1.0.0")
+	body.WriteObject("sayHello")
+	body.WriteObject("Ljava/lang/String;I")
+	body.WriteObject("world")
+	body.WriteObject(int32(1))
+	body.WriteObject(map[string]string{"group": "g1"})
+	req := &Request{}
+	req.SetTwoWay(true)
+	req.SetData(body.GetValidData())
+	var wbf util.WriteBuffer
+	wbf.Init(0)
+	(&DubboCodec{}).EncodeDubboReq(req, &wbf)
+	frame := append([]byte{}, wbf.GetValidData()...)
+
+	var hb util.WriteBuffer
+	hb.Init(0)
+	(&DubboCodec{}).EncodeHeartbeatReq(1, &hb)
+
+	f.Add(frame)
+	f.Add(frame[:len(frame)/2])
+	f.Add(frame[:HeaderLength])
+	f.Add(hb.GetValidData())
+	f.Add([]byte{MagicHigh, MagicLow, 0xc2, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0x7f, 0xff, 0xff, 0xff})
+	f.Add([]byte{MagicHigh, MagicLow, 0x02, Ok, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 2, 0x91, 0x4e})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		codec := &DubboCodec{}
+		header := make([]byte, HeaderLength)
+		frameBody, err := ReadFrame(bytes.NewReader(data), header, 1024)
+		if err != nil {
+			return
+		}
+		if len(frameBody) > 1024 {
+			t.Fatalf("body of %d bytes exceeds the limit", len(frameBody))
+		}
+		bodyLen := 0
+		req := &Request{}
+		if codec.DecodeDubboReqHead(req, header, &bodyLen) == Success {
+			var rbf util.ReadBuffer
+			rbf.SetBuffer(frameBody)
+			codec.DecodeDubboReqBody(req, &rbf)
+		}
+		rsp := &DubboRsp{}
+		rsp.Init()
+		if codec.DecodeDubboRsqHead(rsp, header, &bodyLen) == Success {
+			var rbf util.ReadBuffer
+			rbf.SetBuffer(frameBody)
+			codec.DecodeDubboRspBody(&rbf, rsp)
+		}
+	})
+}
@@ -18,6 +18,7 @@
 package dubbo
 
 import (
+	"io"
 	"testing"
 
 	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/utils"
@@ -42,6 +43,10 @@ func TestDubboCodec_DecodeDubboReqBody(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, c)
 	assert.Equal(t, hessian.BC_NULL, c[0])
+	//buffer is drained by Read
+	_, err = rbf.Read(c)
+	assert.Equal(t, io.EOF, err)
+	rbf.SetBuffer([]byte{hessian.BC_NULL})
 	obj, err := rbf.ReadObject()
 	assert.Nil(t, err)
 	assert.Nil(t, obj)
@@ -291,3 +296,72 @@ func TestDubboCodec_DecodeDubboReqBody(t *testing.T) {
 
 	GCurMSGID = 0
 }
+
+func TestDubboCodec_DecodeDubboReqBodyAttachments(t *testing.T) {
+	d := &DubboCodec{}
+	var body util.WriteBuffer
+	body.Init(0)
+	body.WriteObject("2.0.2")
+	body.WriteObject("com.foo.Hello")
+	body.WriteObject("1.0.0")
+	body.WriteObject("sayHello")
+	body.WriteObject("Ljava/lang/String;I")
+	body.WriteObject("world")
+	body.WriteObject(int32(1))
+	argsLen := body.WrittenBytes()
+	body.WriteObject(map[string]string{"group": "g1", "timeout": "3000"})
+
+	req := &Request{}
+	rbf := &util.ReadBuffer{}
+	rbf.SetBuffer(body.GetValidData())
+	d.DecodeDubboReqBody(req, rbf)
+	assert.Equal(t, "sayHello", req.GetMethodName())
+	assert.Equal(t, "com.foo.Hello", req.GetAttachment(PathKey, ""))
+	assert.Equal(t, "g1", req.GetAttachment("group", ""))
+	assert.Equal(t, argsLen, len(req.GetData().([]byte)))
+
+	t.Run("changed attachments are encoded once", func(t *testing.T) {
+		req.SetAttachment("sw6", "trace")
+		var wbf util.WriteBuffer
+		wbf.Init(0)
+		d.EncodeDubboReq(req, &wbf)
+		encoded := wbf.GetValidData()
+
+		req2 := &Request{}
+		bodyLen := 0
+		assert.Equal(t, Success, d.DecodeDubboReqHead(req2, encoded[:HeaderLength], &bodyLen))
+		assert.Equal(t, len(encoded)-HeaderLength, bodyLen)
+		rbf.SetBuffer(encoded[HeaderLength:])
+		d.DecodeDubboReqBody(req2, rbf)
+		assert.Equal(t, "trace", req2.GetAttachment("sw6", ""))
+		assert.Equal(t, "g1", req2.GetAttachment("group", ""))
+		assert.Equal(t, argsLen, len(req2.GetData().([]byte)))
+	})
+
+	t.Run("malformed arguments keep raw body", func(t *testing.T) {
+		req := &Request{}
+		raw := append(body.GetValidData()[:argsLen-2:argsLen-2], 0x4f, 0x91)
+		rbf.SetBuffer(raw)
+		d.DecodeDubboReqBody(req, rbf)
+		assert.False(t, req.IsBroken())
+		assert.Equal(t, "", req.GetAttachment("group", ""))
+		assert.Equal(t, len(raw), len(req.GetData().([]byte)))
+	})
+}
+
+func TestDubboCodec_EncodeHeartbeatReq(t *testing.T) {
+	d := &DubboCodec{}
+	var wbf util.WriteBuffer
+	wbf.Init(0)
+	assert.Equal(t, 0, d.EncodeHeartbeatReq(7, &wbf))
+	data := wbf.GetValidData()
+
+	req := &Request{}
+	bodyLen := 0
+	assert.Equal(t, Success, d.DecodeDubboReqHead(req, data[:HeaderLength], &bodyLen))
+	assert.Equal(t, int64(7), req.GetMsgID())
+	assert.True(t, req.IsHeartbeat())
+	assert.True(t, req.IsTwoWay())
+	assert.Equal(t, len(data)-HeaderLength, bodyLen)
+	assert.Equal(t, []byte{hessian.BC_NULL}, data[HeaderLength:])
+}
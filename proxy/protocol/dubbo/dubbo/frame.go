@@ -0,0 +1,54 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/utils"
+)
+
+//DefaultMaxPayload is the default limit of frame body length, same as dubbo
+const DefaultMaxPayload = 8 * 1024 * 1024
+
+//ReadFrame reads a whole frame into header and returns the body,
+//a frame with invalid magic or a body longer than maxPayload breaks the stream, so it returns error.
+//maxPayload less than 1 means no limit.
+//The body is not pooled like write buffers, because decoded requests and responses refer to it,
+//arguments and values are forwarded as they are after the frame is decoded, until the call ends
+func ReadFrame(r io.Reader, header []byte, maxPayload int) ([]byte, error) {
+	if len(header) < HeaderLength {
+		return nil, &util.BaseError{ErrMsg: "header buffer is too small"}
+	}
+	if _, err := io.ReadFull(r, header[:HeaderLength]); err != nil {
+		return nil, err
+	}
+	if header[0] != MagicHigh || header[1] != MagicLow {
+		return nil, &util.BaseError{ErrMsg: "invalid magic of frame"}
+	}
+	bodyLen := int(util.Bytes2int(header, 12))
+	if bodyLen < 0 || (maxPayload > 0 && bodyLen > maxPayload) {
+		return nil, &util.BaseError{ErrMsg: fmt.Sprintf("payload length %d exceeds the limit %d", bodyLen, maxPayload)}
+	}
+	body := make([]byte, bodyLen)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
@@ -0,0 +1,68 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func heartbeatFrame(t *testing.T) []byte {
+	var wbf util.WriteBuffer
+	wbf.Init(0)
+	assert.Equal(t, 0, (&DubboCodec{}).EncodeHeartbeatReq(1, &wbf))
+	return wbf.GetValidData()
+}
+
+func TestReadFrame(t *testing.T) {
+	frame := heartbeatFrame(t)
+	header := make([]byte, HeaderLength)
+
+	t.Run("frame arriving byte by byte is read fully", func(t *testing.T) {
+		body, err := ReadFrame(iotest.OneByteReader(bytes.NewReader(frame)), header, DefaultMaxPayload)
+		assert.NoError(t, err)
+		assert.Equal(t, frame[:HeaderLength], header)
+		assert.Equal(t, frame[HeaderLength:], body)
+	})
+	t.Run("truncated frame", func(t *testing.T) {
+		_, err := ReadFrame(bytes.NewReader(frame[:len(frame)-1]), header, DefaultMaxPayload)
+		assert.Equal(t, io.EOF, err)
+		_, err = ReadFrame(bytes.NewReader(frame[:HeaderLength-1]), header, DefaultMaxPayload)
+		assert.Equal(t, io.ErrUnexpectedEOF, err)
+	})
+	t.Run("oversized frame is rejected before allocation", func(t *testing.T) {
+		big := append([]byte{}, frame[:HeaderLength]...)
+		util.Int2bytes(1<<30, big, 12)
+		_, err := ReadFrame(bytes.NewReader(big), header, DefaultMaxPayload)
+		assert.Error(t, err)
+		util.Int2bytes(-1, big, 12)
+		_, err = ReadFrame(bytes.NewReader(big), header, 0)
+		assert.Error(t, err)
+	})
+	t.Run("invalid magic", func(t *testing.T) {
+		bad := append([]byte{}, frame...)
+		bad[0] = 0
+		_, err := ReadFrame(bytes.NewReader(bad), header, DefaultMaxPayload)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,76 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"reflect"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/utils"
+)
+
+//Method names of GenericService, arguments of them are (method, parameterTypes, args)
+const (
+	GenericInvoke      = "$invoke"
+	GenericInvokeAsync = "$invokeAsync"
+)
+
+//IsGenericMethod checks whether a method is a generic invocation
+func IsGenericMethod(name string) bool {
+	return name == GenericInvoke || name == GenericInvokeAsync
+}
+
+//GenericCall is the real call wrapped in a generic invocation, only the method is kept for routing,
+//parameter types and arguments are not, because a generic invocation is sent to provider as it is
+type GenericCall struct {
+	Method string
+}
+
+//NewGenericCall unwraps the real call from arguments of a generic invocation,
+//it returns nil if arguments are not in the form of (String, String[], Object[])
+func NewGenericCall(args []util.Argument) *GenericCall {
+	if len(args) != 3 {
+		return nil
+	}
+	method, ok := args[0].GetValue().(string)
+	if !ok || method == "" {
+		return nil
+	}
+	//parameter types are java class names, like "java.lang.String"
+	for _, t := range toSlice(args[1].GetValue()) {
+		if _, ok := t.(string); !ok {
+			return nil
+		}
+	}
+	return &GenericCall{Method: method}
+}
+
+//toSlice converts a decoded java array or list to slice
+func toSlice(v interface{}) []interface{} {
+	if s, ok := v.([]interface{}); ok {
+		return s
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil
+	}
+	s := make([]interface{}, rv.Len())
+	for i := range s {
+		s[i] = rv.Index(i).Interface()
+	}
+	return s
+}
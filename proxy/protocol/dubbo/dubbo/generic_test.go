@@ -0,0 +1,65 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubbo
+
+import (
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDubboCodec_DecodeGenericCall(t *testing.T) {
+	d := &DubboCodec{}
+	var body util.WriteBuffer
+	body.Init(0)
+	body.WriteObject("2.0.2")
+	body.WriteObject("com.foo.Hello")
+	body.WriteObject("1.0.0")
+	body.WriteObject(GenericInvoke)
+	body.WriteObject("Ljava/lang/String;[Ljava/lang/String;[Ljava/lang/Object;")
+	body.WriteObject("sayHello")
+	body.WriteObject([]string{"java.lang.String", "int"})
+	body.WriteObject([]interface{}{"world", int32(1)})
+	argsLen := body.WrittenBytes()
+	body.WriteObject(map[string]string{"generic": "true"})
+
+	req := &Request{}
+	rbf := &util.ReadBuffer{}
+	rbf.SetBuffer(body.GetValidData())
+	d.DecodeDubboReqBody(req, rbf)
+	assert.Equal(t, GenericInvoke, req.GetMethodName())
+	assert.Equal(t, "true", req.GetAttachment("generic", ""))
+	//generic invocation is sent as it is
+	assert.Equal(t, argsLen, len(req.GetData().([]byte)))
+
+	g := req.GetGenericCall()
+	if assert.NotNil(t, g) {
+		assert.Equal(t, "sayHello", g.Method)
+	}
+}
+
+func TestNewGenericCall(t *testing.T) {
+	assert.Nil(t, NewGenericCall(nil))
+	assert.Nil(t, NewGenericCall([]util.Argument{{Value: 1}, {}, {}}))
+	g := NewGenericCall([]util.Argument{{Value: "ping"}, {}, {}})
+	assert.Equal(t, "ping", g.Method)
+	assert.Nil(t, NewGenericCall([]util.Argument{{Value: "ping"}, {Value: []interface{}{1}}, {}}))
+	assert.True(t, IsGenericMethod(GenericInvokeAsync))
+	assert.False(t, IsGenericMethod("invoke"))
+}
@@ -43,6 +43,7 @@ type Request struct {
 	twoWay   bool
 	isBroken bool
 	data     interface{}
+	generic  *GenericCall
 }
 
 //NewDubboRequest is a function which creates new dubbo request
@@ -72,6 +73,16 @@ func (p *Request) SetBroken(broken bool) {
 
 }
 
+//GetGenericCall returns the real call of a generic invocation, it is nil for a normal call
+func (p *Request) GetGenericCall() *GenericCall {
+	return p.generic
+}
+
+//SetGenericCall sets the real call of a generic invocation
+func (p *Request) SetGenericCall(g *GenericCall) {
+	p.generic = g
+}
+
 //SetEvent sets event to be true
 func (p *Request) SetEvent(event string) {
 	p.event = true
@@ -167,7 +178,7 @@ func (p *DubboRPCInvocation) SetAttachment(key string, value string) {
 		p.attachments = make(map[string]string)
 	}
 	if value == "" { //is empty, remove the key
-		delete(p.attachments, value)
+		delete(p.attachments, key)
 	} else {
 		p.attachments[key] = value
 	}
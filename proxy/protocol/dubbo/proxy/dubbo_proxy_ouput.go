@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"github.com/apache/servicecomb-mesher/proxy/cmd"
 	mesherCommon "github.com/apache/servicecomb-mesher/proxy/common"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/accesslog"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
 	mesherRuntime "github.com/apache/servicecomb-mesher/proxy/pkg/runtime"
 	"github.com/apache/servicecomb-mesher/proxy/protocol"
 	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/client"
@@ -35,13 +37,13 @@ import (
 	"github.com/go-chassis/go-chassis/v2/core/invocation"
 	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
 	"github.com/go-chassis/go-chassis/v2/pkg/runtime"
-	"github.com/go-chassis/go-chassis/v2/pkg/util/tags"
 	"github.com/go-chassis/go-chassis/v2/third_party/forked/afex/hystrix-go/hystrix"
 	"github.com/go-chassis/openlog"
+	"strconv"
+	"time"
 )
 
 var dr = resolver.GetDestinationResolver("http")
-var sr = resolver.GetSourceResolver()
 
 const (
 	ProxyTag = "mesherproxy"
@@ -82,24 +84,39 @@ func SetLocalServiceAddress(inv *invocation.Invocation) error {
 }
 
 //Handle is a function
-func Handle(ctx *dubbo.InvokeContext) error {
+func Handle(ctx *dubbo.InvokeContext) (err error) {
 	interfaceName := ctx.Req.GetAttachment(dubbo.PathKey, "")
+	inv := new(invocation.Invocation)
+	inv.SourceServiceID = runtime.ServiceID
+	inv.SourceMicroService = ctx.Req.GetAttachment(common.HeaderSourceName, "")
+	inv.SchemaID = interfaceName
+	inv.OperationID = ctx.Req.GetMethodName()
+	if g := ctx.Req.GetGenericCall(); g != nil {
+		//route and record a generic invocation by the real method, request is still sent as it is
+		inv.OperationID = g.Method
+	}
+	inv.Protocol = "dubbo"
+	defer func(begin time.Time) {
+		RecordMetrics(inv, ctx, err, time.Since(begin))
+	}(time.Now())
+
 	svc := schema.GetSvcByInterface(interfaceName)
 	if svc == nil {
+		ctx.Rsp.SetStatus(dubbo.ServiceNotFound)
 		return &util.BaseError{ErrMsg: "can't find the svc by " + interfaceName}
 	}
 
-	inv := new(invocation.Invocation)
-	inv.SourceServiceID = runtime.ServiceID
-	inv.SourceMicroService = ctx.Req.GetAttachment(common.HeaderSourceName, "")
+	if ctx.Req.GetAttachments() == nil {
+		ctx.Req.SetAttachments(make(map[string]string))
+	}
+	//attachments are headers of dubbo, tracing handlers propagate context through them
 	inv.Args = ctx.Req
 	inv.Ctx = context.WithValue(context.Background(), chassisCommon.ContextHeaderKey{}, ctx.Req.GetAttachments())
 	inv.MicroServiceName = svc.ServiceName
-	inv.RouteTags = utiltags.NewDefaultTag(svc.Version, svc.AppID)
-	inv.Protocol = "dubbo"
+	Route(inv, ctx.Req, svc)
 	inv.URLPath = ""
 	inv.Reply = &dubboclient.WrapResponse{nil} //&rest.Response{Resp: &ctx.Response}
-	var err error
+
 	err = SetLocalServiceAddress(inv) //select local service
 	if err != nil {
 		openlog.Warn(err.Error())
@@ -140,6 +157,37 @@ func Handle(ctx *dubbo.InvokeContext) error {
 	return nil
 }
 
+//RecordMetrics record dubbo metrics and access log of an invocation,
+//a response status other than dubbo.Ok is a failure
+func RecordMetrics(inv *invocation.Invocation, ctx *dubbo.InvokeContext, err error, latency time.Duration) {
+	status := dubbo.ServerError
+	if ctx.Rsp != nil {
+		status = ctx.Rsp.GetStatus()
+	}
+	if err != nil && status == dubbo.Ok {
+		status = dubbo.ServerError
+	}
+	labelValues := map[string]string{
+		metrics.LServiceName: inv.MicroServiceName,
+		metrics.LApp:         inv.RouteTags.AppID(),
+		metrics.LVersion:     inv.RouteTags.Version(),
+		metrics.LInterface:   inv.SchemaID,
+		metrics.LMethod:      inv.OperationID,
+		metrics.LStatus:      strconv.Itoa(int(status)),
+	}
+	metrics.RecordRPCLatency(inv.Protocol, labelValues, latency.Seconds())
+	metrics.RecordRPCStatus(inv.Protocol, labelValues, status != dubbo.Ok)
+	accesslog.Log(&accesslog.Record{
+		Protocol:  inv.Protocol,
+		Source:    inv.SourceMicroService,
+		Service:   inv.MicroServiceName,
+		Operation: inv.SchemaID + "." + inv.OperationID,
+		Status:    labelValues[metrics.LStatus],
+		Latency:   latency,
+		Tags:      map[string]string{"remote": ctx.RemoteAddr},
+	})
+}
+
 func handleDubboRequest(inv *invocation.Invocation, ctx *dubbo.InvokeContext, ir *invocation.Response) error {
 	if ir != nil {
 		if ir.Err != nil {
@@ -0,0 +1,95 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubboproxy
+
+import (
+	"fmt"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/dubbo"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/go-chassis/go-chassis/v2/core/router"
+	"github.com/go-chassis/go-chassis/v2/pkg/runtime"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/go-chassis/openlog"
+)
+
+//instance tags which dubbo group and version of request are routed to
+const (
+	TagGroup   = "dubbo.group"
+	TagVersion = "dubbo.version"
+	//KeyRouteByGroupVersion enables routing dubbo group and version to instance tags
+	KeyRouteByGroupVersion = "mesher.dubbo.routeByGroupVersion"
+)
+
+//defaultInterfaceVersion is sent by dubbo consumer which does not set version
+const defaultInterfaceVersion = "0.0.0"
+
+//Route decides the instance tags of a dubbo invocation.
+//Attachments of request are the headers matched by route rules of the service,
+//the registered version and app of service are used if no rule matches.
+//Group and version of request are added as instance tags if routeByGroupVersion is enabled
+func Route(inv *invocation.Invocation, req *dubbo.Request, svc *registry.MicroService) {
+	RouteAttachments(inv, req.GetAttachments(), req.GetAttachment(dubbo.GroupKey, ""),
+		req.GetAttachment(dubbo.VersionKey, ""), svc)
+}
+
+//RouteAttachments decides the instance tags of a call to svc like Route,
+//it is shared by protocols carrying dubbo attachments, like triple
+func RouteAttachments(inv *invocation.Invocation, attachments map[string]string, group, version string,
+	svc *registry.MicroService) {
+	inv.RouteTags = utiltags.NewDefaultTag(svc.Version, svc.AppID)
+	if router.DefaultRouter != nil {
+		//route rule may not change attachments sent to provider
+		headers := make(map[string]string, len(attachments))
+		for k, v := range attachments {
+			headers[k] = v
+		}
+		source := &registry.SourceInfo{
+			Name: inv.SourceMicroService,
+			Tags: map[string]string{common.BuildinTagApp: runtime.App, common.BuildinTagVersion: runtime.Version},
+		}
+		if err := router.Route(headers, source, inv); err != nil {
+			openlog.Warn(fmt.Sprintf("route dubbo request of %s failed: %s", inv.MicroServiceName, err))
+		}
+	}
+	inv.RouteTags = GroupVersionTags(inv.RouteTags, group, version)
+}
+
+//GroupVersionTags adds group and version of a request to tags if routeByGroupVersion is enabled
+func GroupVersionTags(t utiltags.Tags, group, version string) utiltags.Tags {
+	if version == defaultInterfaceVersion {
+		version = ""
+	}
+	if !archaius.GetBool(KeyRouteByGroupVersion, false) || (group == "" && version == "") {
+		return t
+	}
+	kv := make(map[string]string, len(t.KV)+2)
+	for k, v := range t.KV {
+		kv[k] = v
+	}
+	if group != "" {
+		kv[TagGroup] = group
+	}
+	if version != "" {
+		kv[TagVersion] = version
+	}
+	return utiltags.Tags{KV: kv, Label: utiltags.LabelOfTags(kv)}
+}
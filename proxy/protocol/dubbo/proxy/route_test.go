@@ -0,0 +1,83 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dubboproxy
+
+import (
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/dubbo"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/core/config"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/go-chassis/go-chassis/v2/core/router"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRouter struct {
+	rules map[string][]*config.RouteRule
+}
+
+func (r *fakeRouter) Init(router.Options) error                                { return nil }
+func (r *fakeRouter) SetRouteRule(rules map[string][]*config.RouteRule)        { r.rules = rules }
+func (r *fakeRouter) ListRouteRule() map[string][]*config.RouteRule            { return r.rules }
+func (r *fakeRouter) FetchRouteRuleByServiceName(s string) []*config.RouteRule { return r.rules[s] }
+
+func TestRoute(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	defer func(r router.Router) { router.DefaultRouter = r }(router.DefaultRouter)
+	router.DefaultRouter = &fakeRouter{rules: map[string][]*config.RouteRule{
+		"hello": {{
+			Precedence: 1,
+			Match:      config.Match{Headers: map[string]map[string]string{"tenant": {"exact": "gray"}}},
+			Routes:     []*config.RouteTag{{Weight: 100, Tags: map[string]string{"version": "2.0"}}},
+		}},
+	}}
+	svc := &registry.MicroService{ServiceName: "hello", Version: "1.0", AppID: "app"}
+
+	req := dubbo.NewDubboRequest()
+	inv := &invocation.Invocation{MicroServiceName: "hello"}
+	Route(inv, req, svc)
+	assert.Equal(t, "1.0", inv.RouteTags.Version())
+	assert.Equal(t, "app", inv.RouteTags.AppID())
+
+	// attachment matches route rule
+	req.SetAttachment("tenant", "gray")
+	Route(inv, req, svc)
+	assert.Equal(t, map[string]string{"version": "2.0"}, inv.RouteTags.KV)
+
+	// group and version
+	req.SetAttachment("tenant", "")
+	req.SetAttachment(dubbo.GroupKey, "g1")
+	req.SetAttachment(dubbo.VersionKey, "1.2.0")
+	Route(inv, req, svc)
+	assert.Equal(t, 2, len(inv.RouteTags.KV))
+
+	archaius.Set(KeyRouteByGroupVersion, true)
+	defer archaius.Delete(KeyRouteByGroupVersion)
+	Route(inv, req, svc)
+	assert.Equal(t, "g1", inv.RouteTags.KV[TagGroup])
+	assert.Equal(t, "1.2.0", inv.RouteTags.KV[TagVersion])
+	assert.Equal(t, "1.0", inv.RouteTags.Version())
+	assert.Equal(t, "app:app|dubbo.group:g1|dubbo.version:1.2.0|version:1.0", inv.RouteTags.Label)
+
+	req.SetAttachment(dubbo.GroupKey, "")
+	req.SetAttachment(dubbo.VersionKey, "0.0.0")
+	Route(inv, req, svc)
+	assert.Equal(t, 2, len(inv.RouteTags.KV))
+}
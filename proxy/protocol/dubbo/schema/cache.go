@@ -19,122 +19,237 @@ package schema
 
 import (
 	"fmt"
-	"github.com/go-chassis/openlog"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/apache/servicecomb-mesher/proxy/config"
 	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/go-chassis/openlog"
 	"github.com/patrickmn/go-cache"
 )
 
+//interface cache config keys
+const (
+	KeyInterfaceTTL         = "mesher.dubbo.interfaceCache.ttl"
+	KeyInterfaceNegativeTTL = "mesher.dubbo.interfaceCache.negativeTTL"
+)
+
 var (
-	svcToInterfaceCache *cache.Cache
-	protoCache          *cache.Cache
-	//DefaultInterval is default refresh interval time
-	DefaultInterval = 30 * time.Second
+	protoCache *cache.Cache
+	svcCache   *InterfaceCache
+	//DefaultTTL is how long a resolved interface is served before it is looked up again
+	DefaultTTL = 5 * time.Minute
+	//DefaultNegativeTTL is how long an interface not found in registry is remembered
+	DefaultNegativeTTL = 5 * time.Second
 	//DefaultExpireTime is default expire time
 	DefaultExpireTime = 0 * time.Second
-
-	refresher *refreshTicker
 )
 
 func init() {
-	svcToInterfaceCache = cache.New(DefaultExpireTime, 0)
 	protoCache = cache.New(DefaultExpireTime, 0)
+	svcCache = NewInterfaceCache(DefaultTTL, DefaultNegativeTTL, lookupInterface)
+}
+
+func lookupInterface(interfaceName string) *registry.MicroService {
+	if registry.DefaultContractDiscoveryService == nil {
+		return nil
+	}
+	subscribeOnRegistered(svcCache)
+	svc := registry.DefaultContractDiscoveryService.GetMicroServicesByInterface(interfaceName)
+	if len(svc) == 0 {
+		return nil
+	}
+	return svc[0]
+}
 
-	refresher = newRefresher(DefaultInterval)
-	refresher.Run()
+//InterfaceEntry is a row of the interface to service table
+type InterfaceEntry struct {
+	Interface string    `json:"interface"`
+	Found     bool      `json:"found"`
+	Service   string    `json:"service,omitempty"`
+	Version   string    `json:"version,omitempty"`
+	AppID     string    `json:"appId,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	ExpireAt  time.Time `json:"expireAt,omitempty"`
 }
 
-func newInterfaceJob(interfaceName string) Job {
-	return Job{Fn: func() {
-		svc := registry.DefaultContractDiscoveryService.GetMicroServicesByInterface(interfaceName)
-		if svc != nil {
-			svcKey := strings.Join([]string{svc[0].ServiceName, svc[0].Version, svc[0].AppID}, "/")
-			openlog.Info(fmt.Sprintf("refresh cache svc [%s] for interface %s", svcKey, interfaceName))
-			svcToInterfaceCache.Set(interfaceName, svc[0], 0)
-		}
-	}}
+type interfaceEntry struct {
+	svc        *registry.MicroService
+	updated    time.Time
+	expire     time.Time
+	refreshing bool
+}
+
+func (e *interfaceEntry) valid(now time.Time) bool {
+	return e.expire.IsZero() || now.Before(e.expire)
+}
+
+//InterfaceCache maps dubbo interface to the micro service providing it.
+//A miss has nothing to serve, so it waits for the registry lookup, concurrent misses of an interface share one lookup.
+//Later lookups are triggered by invalidation on registry events or expiration,
+//a known service is served while it is refreshed in background.
+//Interfaces not found are remembered for the negative TTL, so that a bad interface name does not flood registry
+type InterfaceCache struct {
+	mu          sync.Mutex
+	entries     map[string]*interfaceEntry
+	loading     map[string]*loadCall
+	ttl         time.Duration
+	negativeTTL time.Duration
+	lookup      func(interfaceName string) *registry.MicroService
 }
 
-func newRefresher(t time.Duration) *refreshTicker {
-	return &refreshTicker{
-		jobs: Queue{
-			tick: t,
-			cond: sync.NewCond(&sync.Mutex{}),
-			q:    make([]Job, 0)},
+//loadCall is a lookup of a missed interface shared by concurrent callers
+type loadCall struct {
+	done chan struct{}
+	svc  *registry.MicroService
+}
+
+//NewInterfaceCache is a function which creates a cache, ttl 0 means a found service never expires,
+//negativeTTL 0 disables negative caching
+func NewInterfaceCache(ttl, negativeTTL time.Duration, lookup func(interfaceName string) *registry.MicroService) *InterfaceCache {
+	return &InterfaceCache{
+		entries:     make(map[string]*interfaceEntry),
+		loading:     make(map[string]*loadCall),
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		lookup:      lookup,
 	}
 }
 
-type refreshTicker struct {
-	jobs Queue
+//SetTTL is a method to change the TTLs of entries set after it
+func (c *InterfaceCache) SetTTL(ttl, negativeTTL time.Duration) {
+	c.mu.Lock()
+	c.ttl = ttl
+	c.negativeTTL = negativeTTL
+	c.mu.Unlock()
 }
 
-//Queue is a struct
-type Queue struct {
-	tick time.Duration
-	cond *sync.Cond
-	q    []Job
+//Get is a method to get the service of interface, it returns nil if no service provides it
+func (c *InterfaceCache) Get(interfaceName string) *registry.MicroService {
+	c.mu.Lock()
+	e, ok := c.entries[interfaceName]
+	if ok && e.valid(time.Now()) {
+		c.mu.Unlock()
+		return e.svc
+	}
+	if ok && e.svc != nil {
+		c.refreshAsync(interfaceName, e)
+		c.mu.Unlock()
+		return e.svc
+	}
+	if l, ok := c.loading[interfaceName]; ok {
+		c.mu.Unlock()
+		<-l.done
+		return l.svc
+	}
+	l := &loadCall{done: make(chan struct{})}
+	c.loading[interfaceName] = l
+	c.mu.Unlock()
+
+	l.svc = c.Refresh(interfaceName)
+	c.mu.Lock()
+	delete(c.loading, interfaceName)
+	c.mu.Unlock()
+	close(l.done)
+	return l.svc
 }
 
-//Job is a struct
-type Job struct {
-	Fn   JobFunc
-	Next time.Time
+//Refresh is a method to look up registry for the interface and cache the result
+func (c *InterfaceCache) Refresh(interfaceName string) *registry.MicroService {
+	svc := c.lookup(interfaceName)
+	c.set(interfaceName, svc)
+	return svc
 }
 
-//JobFunc is a type of func()
-type JobFunc func()
+func (c *InterfaceCache) set(interfaceName string, svc *registry.MicroService) {
+	now := time.Now()
+	e := &interfaceEntry{svc: svc, updated: now}
+	c.mu.Lock()
+	if svc != nil && c.ttl > 0 {
+		e.expire = now.Add(c.ttl)
+	}
+	if svc == nil {
+		e.expire = now.Add(c.negativeTTL)
+	}
+	old := c.entries[interfaceName]
+	c.entries[interfaceName] = e
+	c.mu.Unlock()
 
-func (tc *refreshTicker) Add(job Job) { tc.jobs.Push(job) }
-func (tc *refreshTicker) Run()        { go tc.run() }
+	if svc != nil && (old == nil || old.svc == nil || serviceKey(old.svc) != serviceKey(svc)) {
+		openlog.Info(fmt.Sprintf("cached svc [%s] for interface %s", serviceKey(svc), interfaceName))
+	}
+}
 
-func (tc *refreshTicker) run() {
-	var timer *time.Timer
-	var top Job
-	for {
-		top = tc.jobs.Top()
-		timer = time.NewTimer(top.Next.Sub(time.Now()))
-		//TODO: if top is earlier than now
-		select {
-		case <-timer.C:
-			top = tc.jobs.Pop()
-			go top.Fn()
-			tc.jobs.Push(top)
+//refreshAsync looks up the interface in background, it must be called with lock held
+func (c *InterfaceCache) refreshAsync(interfaceName string, e *interfaceEntry) {
+	if e.refreshing {
+		return
+	}
+	e.refreshing = true
+	go c.Refresh(interfaceName)
+}
+
+//Invalidate is a method to invalidate the interfaces of a service and the interfaces not found yet,
+//it is called when instances of the service change in registry. Interfaces of the service are refreshed
+//in background at next call and served in the meanwhile, interfaces not found are looked up again at next call.
+//It returns the interfaces invalidated
+func (c *InterfaceCache) Invalidate(service string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	var names []string
+	for name, e := range c.entries {
+		switch {
+		case e.svc == nil:
+			delete(c.entries, name)
+		case e.svc.ServiceName == service:
+			e.expire = now
+		default:
+			continue
 		}
+		names = append(names, name)
 	}
+	return names
 }
 
-//Push is a method to add new job
-func (q *Queue) Push(x Job) {
-	q.cond.L.Lock()
-	defer q.cond.L.Unlock()
-	x.Next = time.Now().Add(q.tick)
-	q.q = append(q.q, x)
-	q.cond.Signal()
+//Delete is a method to remove an interface from cache
+func (c *InterfaceCache) Delete(interfaceName string) {
+	c.mu.Lock()
+	delete(c.entries, interfaceName)
+	c.mu.Unlock()
 }
 
-//Top is a method to get then latest job
-func (q *Queue) Top() Job {
-	q.cond.L.Lock()
-	defer q.cond.L.Unlock()
-	for len(q.q) == 0 {
-		q.cond.Wait()
+//Table is a method to list entries of cache ordered by interface
+func (c *InterfaceCache) Table() []InterfaceEntry {
+	c.mu.Lock()
+	table := make([]InterfaceEntry, 0, len(c.entries))
+	for name, e := range c.entries {
+		row := InterfaceEntry{Interface: name, Found: e.svc != nil, UpdatedAt: e.updated, ExpireAt: e.expire}
+		if e.svc != nil {
+			row.Service = e.svc.ServiceName
+			row.Version = e.svc.Version
+			row.AppID = e.svc.AppID
+		}
+		table = append(table, row)
 	}
+	c.mu.Unlock()
+	sort.Slice(table, func(i, j int) bool { return table[i].Interface < table[j].Interface })
+	return table
+}
 
-	return q.q[0]
+func serviceKey(svc *registry.MicroService) string {
+	return strings.Join([]string{svc.ServiceName, svc.Version, svc.AppID}, "/")
 }
 
-//Pop is a method to get next job
-func (q *Queue) Pop() Job {
-	q.cond.L.Lock()
-	defer q.cond.L.Unlock()
-	for len(q.q) == 0 {
-		q.cond.Wait()
-	}
+//InterfaceTable is a function to list the interface to service table
+func InterfaceTable() []InterfaceEntry {
+	return svcCache.Table()
+}
 
-	x := q.q[0]
-	q.q = q.q[1:]
-	return x
+//InitInterfaceCache is a function which reads TTLs from config,
+//registry events are subscribed at the first lookup, after mesher is registered
+func InitInterfaceCache() {
+	svcCache.SetTTL(config.GetDuration(KeyInterfaceTTL, DefaultTTL), config.GetDuration(KeyInterfaceNegativeTTL, DefaultNegativeTTL))
 }
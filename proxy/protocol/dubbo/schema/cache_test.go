@@ -19,33 +19,129 @@ package schema
 
 import (
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/go-chassis/go-chassis/v2/core/registry"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestFreshTicker(t *testing.T) {
-	r1 := newRefresher(time.Second * 2)
-	r1.Run()
+type fakeLookup struct {
+	mu    sync.Mutex
+	svc   map[string]*registry.MicroService
+	calls int
+}
 
-	a1, a2 := 0, 0
-	var wg sync.WaitGroup
-	wg.Add(2)
+func (f *fakeLookup) lookup(interfaceName string) *registry.MicroService {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	return f.svc[interfaceName]
+}
 
-	r1.Add(Job{Fn: func() {
-		a1++
-		wg.Done()
-	}})
+func (f *fakeLookup) set(interfaceName string, svc *registry.MicroService) {
+	f.mu.Lock()
+	f.svc[interfaceName] = svc
+	f.mu.Unlock()
+}
 
-	r1.Add(Job{Fn: func() {
-		a2++
-		wg.Done()
-	}})
+func (f *fakeLookup) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
 
-	wg.Wait()
+func TestInterfaceCache(t *testing.T) {
+	f := &fakeLookup{svc: map[string]*registry.MicroService{}}
+	c := NewInterfaceCache(time.Hour, time.Hour, f.lookup)
+
+	// negative result is cached
+	assert.Nil(t, c.Get("com.foo.Hello"))
+	assert.Nil(t, c.Get("com.foo.Hello"))
+	assert.Equal(t, 1, f.count())
+
+	// a provider is deployed, registry event invalidates negative entries at once
+	f.set("com.foo.Hello", &registry.MicroService{ServiceName: "hello", Version: "1.0", AppID: "app"})
+	assert.Equal(t, []string{"com.foo.Hello"}, c.Invalidate("hello"))
+	assert.NotNil(t, c.Get("com.foo.Hello"))
+	assert.Equal(t, 2, f.count())
+
+	table := c.Table()
+	assert.Len(t, table, 1)
+	assert.Equal(t, InterfaceEntry{Interface: "com.foo.Hello", Found: true, Service: "hello", Version: "1.0", AppID: "app",
+		UpdatedAt: table[0].UpdatedAt, ExpireAt: table[0].ExpireAt}, table[0])
+
+	// event of other service does not invalidate it
+	assert.Empty(t, c.Invalidate("other"))
+	c.Get("com.foo.Hello")
+	assert.Equal(t, 2, f.count())
+
+	// new version of provider is served after it is refreshed in background
+	f.set("com.foo.Hello", &registry.MicroService{ServiceName: "hello", Version: "2.0", AppID: "app"})
+	c.Invalidate("hello")
+	assert.Equal(t, "1.0", c.Get("com.foo.Hello").Version)
+	assert.Eventually(t, func() bool { return c.Get("com.foo.Hello").Version == "2.0" }, time.Second, time.Millisecond)
+	assert.Equal(t, 3, f.count())
 
-	assert.Equal(t, a1, 1)
-	assert.Equal(t, a2, 1)
+	c.Delete("com.foo.Hello")
+	assert.Empty(t, c.Table())
+}
+
+func TestInterfaceCache_TTL(t *testing.T) {
+	f := &fakeLookup{svc: map[string]*registry.MicroService{"a": {ServiceName: "v1"}}}
+	c := NewInterfaceCache(time.Millisecond, 0, f.lookup)
+
+	assert.Equal(t, "v1", c.Get("a").ServiceName)
+	time.Sleep(5 * time.Millisecond)
+	// expired service is served while it is refreshed in background
+	f.set("a", &registry.MicroService{ServiceName: "v2"})
+	assert.Equal(t, "v1", c.Get("a").ServiceName)
+	assert.Eventually(t, func() bool { return c.Get("a").ServiceName == "v2" }, time.Second, time.Millisecond)
+
+	// no negative caching
+	n := f.count()
+	assert.Nil(t, c.Get("b"))
+	assert.Nil(t, c.Get("b"))
+	assert.Equal(t, n+2, f.count())
+
+	// ttl 0 never expires
+	c.SetTTL(0, 0)
+	c.Refresh("a")
+	assert.True(t, c.Table()[0].ExpireAt.IsZero())
+}
+
+func TestInterfaceCache_Miss(t *testing.T) {
+	release := make(chan struct{})
+	var calls int32
+	c := NewInterfaceCache(time.Hour, time.Hour, func(string) *registry.MicroService {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &registry.MicroService{ServiceName: "hello"}
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, "hello", c.Get("com.foo.Hello").ServiceName)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	// concurrent misses share one lookup
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
 
+func TestInvalidate(t *testing.T) {
+	registry.EnableRegistryCache()
+	f := &fakeLookup{svc: map[string]*registry.MicroService{"com.foo.Hello": {ServiceName: "hello"}}}
+	c := NewInterfaceCache(time.Hour, time.Hour, f.lookup)
+	c.Get("com.foo.Hello")
+	registry.SchemaInterfaceIndexedCache.Set("com.foo.Hello", "cached schema", 0)
+	invalidate(c, "hello")
+	// registry reads schemas again at next lookup
+	_, ok := registry.SchemaInterfaceIndexedCache.Get("com.foo.Hello")
+	assert.False(t, ok)
 }
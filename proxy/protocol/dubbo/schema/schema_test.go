@@ -180,7 +180,7 @@ func Test_GetSvcByInterface(t *testing.T) {
 	v := GetSvcByInterface("hello")
 	assert.NotNil(t, v)
 
-	svcToInterfaceCache.Set("hello", &registry.MicroService{}, 0)
+	svcCache.set("hello", &registry.MicroService{})
 	// case has value
 	v = GetSvcByInterface("hello")
 	assert.NotNil(t, v)
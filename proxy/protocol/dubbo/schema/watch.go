@@ -0,0 +1,100 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	chassisconfig "github.com/go-chassis/go-chassis/v2/core/config"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/go-chassis/go-chassis/v2/core/registry/servicecenter"
+	chassisTLS "github.com/go-chassis/go-chassis/v2/core/tls"
+	"github.com/go-chassis/go-chassis/v2/pkg/runtime"
+	"github.com/go-chassis/openlog"
+	"github.com/go-chassis/sc-client"
+)
+
+var subscribeOnce sync.Once
+
+//subscribeOnRegistered subscribes registry events once mesher is registered, events are sent to a registered service
+func subscribeOnRegistered(c *InterfaceCache) {
+	if runtime.ServiceID == "" || chassisconfig.GlobalDefinition == nil {
+		return
+	}
+	subscribeOnce.Do(func() { subscribe(c) })
+}
+
+//subscribe watches instance events of the services mesher depends on in service center,
+//interfaces of a service are invalidated when an event of it comes.
+//A schema can only change with a new version of service in service center, whose instances come with events too.
+//Services not depended yet have no event, interfaces not found rely on the negative TTL
+func subscribe(c *InterfaceCache) {
+	if chassisconfig.GetServiceDiscoveryType() != servicecenter.ServiceCenter {
+		openlog.Warn("registry is not service center, dubbo interface cache relies on TTL only")
+		return
+	}
+	client, err := newWatchClient()
+	if err != nil {
+		openlog.Error("dubbo interface cache relies on TTL only, create registry client failed: " + err.Error())
+		return
+	}
+	//chassis watches with its own client, a client watches a service only once
+	if err := watch(client, runtime.ServiceID, c); err != nil {
+		openlog.Error("dubbo interface cache relies on TTL only, watch registry failed: " + err.Error())
+	}
+}
+
+//watch watches instance events sent to service, the client reconnects if connection is broken
+func watch(client *sc.Client, serviceID string, c *InterfaceCache) error {
+	return client.WatchMicroService(serviceID, func(e *sc.MicroServiceInstanceChangedEvent) {
+		if e.Key == nil {
+			return
+		}
+		openlog.Debug(fmt.Sprintf("instance of %s %s, invalidate dubbo interfaces", e.Key.ServiceName, e.Action))
+		invalidate(c, e.Key.ServiceName)
+	})
+}
+
+//invalidate invalidates interfaces of service, they are also removed from the interface index of registry,
+//so that the next lookup reads schemas from registry
+func invalidate(c *InterfaceCache, service string) {
+	for _, name := range c.Invalidate(service) {
+		if registry.SchemaInterfaceIndexedCache != nil {
+			registry.SchemaInterfaceIndexedCache.Delete(name)
+		}
+	}
+}
+
+func newWatchClient() (*sc.Client, error) {
+	hosts, scheme, err := registry.URIs2Hosts(strings.Split(chassisconfig.GetServiceDiscoveryAddress(), ","))
+	if err != nil {
+		return nil, err
+	}
+	opts := registry.Options{Addrs: hosts}
+	if scheme == common.HTTPS {
+		opts.TLSConfig, _, err = chassisTLS.GetTLSConfigByService(registry.SDTag, "", common.Consumer)
+		if err != nil {
+			return nil, err
+		}
+		opts.EnableSSL = true
+	}
+	return sc.NewClient(servicecenter.ToSCOptions(opts))
+}
@@ -0,0 +1,62 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/go-chassis/sc-client"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	events := make(chan string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/microservices/mesher/watcher") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for e := range events {
+			conn.WriteMessage(websocket.TextMessage, []byte(e))
+		}
+	}))
+	defer srv.Close()
+	defer close(events)
+
+	f := &fakeLookup{svc: map[string]*registry.MicroService{"com.foo.Hello": {ServiceName: "hello", Version: "1.0"}}}
+	c := NewInterfaceCache(time.Hour, time.Hour, f.lookup)
+	c.Get("com.foo.Hello")
+	client, err := sc.NewClient(sc.Options{Endpoints: []string{srv.Listener.Addr().String()}})
+	assert.NoError(t, err)
+	assert.NoError(t, watch(client, "mesher", c))
+
+	f.set("com.foo.Hello", &registry.MicroService{ServiceName: "hello", Version: "2.0"})
+	events <- `{"action":"CREATE","key":{"serviceName":"hello","version":"2.0"},"instance":{"instanceId":"1","status":"UP"}}`
+	assert.Eventually(t, func() bool { return c.Get("com.foo.Hello").Version == "2.0" }, 3*time.Second, 10*time.Millisecond)
+}
@@ -25,6 +25,8 @@ import (
 	"github.com/go-chassis/openlog"
 	"net"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 //SndTask is a struct
@@ -58,6 +60,9 @@ type ProcessTask struct {
 //Svc is a method
 func (this ProcessTask) Svc(arg interface{}) interface{} {
 	if this.conn != nil {
+		if this.conn.mgr != nil && !this.req.IsHeartbeat() {
+			defer this.conn.mgr.release()
+		}
 		this.conn.ProcessBody(this.req, this.bufBody)
 	}
 	return nil
@@ -67,27 +72,48 @@ func (this ProcessTask) Svc(arg interface{}) interface{} {
 type DubboConnection struct {
 	msgque     *util.MsgQueue
 	remoteAddr string
-	conn       *net.TCPConn
+	conn       net.Conn
 	codec      dubbo.DubboCodec
 	mtx        sync.Mutex
 	routineMgr *util.RoutineManager
 	closed     bool
+	id         int
+	mgr        *ConnectionMgr
+	//lastRead is unix nano time of the last received frame
+	lastRead      int64
+	lastHeartbeat time.Time
 }
 
 //NewDubboConnetction is a function to create new dubbo connection
-func NewDubboConnetction(conn *net.TCPConn, routineMgr *util.RoutineManager) *DubboConnection {
+func NewDubboConnetction(conn net.Conn, routineMgr *util.RoutineManager) *DubboConnection {
 	tmp := new(DubboConnection)
 	tmp.conn = conn
 	tmp.codec = dubbo.DubboCodec{}
 	tmp.msgque = util.NewMsgQueue()
 	tmp.remoteAddr = conn.RemoteAddr().String()
 	tmp.closed = false
+	tmp.routineMgr = routineMgr
 	if routineMgr == nil {
 		tmp.routineMgr = util.NewRoutineManager()
 	}
+	tmp.lastRead = time.Now().UnixNano()
 	return tmp
 }
 
+//LastRead is a method which returns the time of the last received frame
+func (this *DubboConnection) LastRead() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&this.lastRead))
+}
+
+//SendHeartbeat is a method to send a heartbeat request to client
+func (this *DubboConnection) SendHeartbeat() {
+	req := dubbo.NewDubboRequest()
+	req.SetEvent(dubbo.HeartBeatEvent)
+	if err := this.msgque.Enqueue(req); err != nil {
+		openlog.Warn("send heartbeat failed: " + err.Error())
+	}
+}
+
 //Open is a function to open a connection
 func (this *DubboConnection) Open() {
 	this.routineMgr.Spawn(SndTask{}, this, fmt.Sprintf("Snd-%s->%s", this.conn.LocalAddr().String(), this.conn.RemoteAddr().String()))
@@ -104,57 +130,72 @@ func (this *DubboConnection) Close() {
 	this.closed = true
 	this.msgque.Deavtive()
 	this.conn.Close()
+	if this.mgr != nil {
+		this.mgr.remove(this.id)
+	}
 }
 
 //MsgRecvLoop is a method receive data
 func (this *DubboConnection) MsgRecvLoop() {
-	//通知处理应答消息
+	maxPayload := dubbo.DefaultMaxPayload
+	if this.mgr != nil {
+		maxPayload = this.mgr.opts.MaxPayload
+	}
+	//header buffer is reused by every frame, decoded request does not refer to it
+	header := make([]byte, dubbo.HeaderLength)
 	for {
-		//先处理消息头
-		buf := make([]byte, dubbo.HeaderLength)
-		size, err := this.conn.Read(buf)
+		body, err := dubbo.ReadFrame(this.conn, header, maxPayload)
 		if err != nil {
-			if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
-				openlog.Error("Dubbo server Recv head: " + err.Error())
-				continue
-			}
-			openlog.Error("Dubbo server Recv head: " + err.Error())
+			//通知关闭连接
+			openlog.Error("Dubbo server Recv: " + err.Error())
 			break
 		}
-
-		if size < dubbo.HeaderLength {
-			openlog.Info("Invalid msg head")
-			continue
-		}
+		atomic.StoreInt64(&this.lastRead, time.Now().UnixNano())
 		req := new(dubbo.Request)
 		bodyLen := 0
-		ret := this.codec.DecodeDubboReqHead(req, buf, &bodyLen)
+		ret := this.codec.DecodeDubboReqHead(req, header, &bodyLen)
+		if ret == dubbo.InvalidFragement && header[2]&dubbo.FlagRequest == 0 {
+			//response of heartbeat sent by server, nothing to do but drop it
+			continue
+		}
 		if ret != dubbo.Success {
 			openlog.Info("Invalid msg head")
 			continue
 		}
-		body := make([]byte, bodyLen)
-		count := 0
-		for {
-			redBuff := body[count:]
-			size, err = this.conn.Read(redBuff)
-
-			if err != nil {
-				//通知关闭连接
-				openlog.Error("Recv: " + err.Error())
-				goto exitloop
-			}
-			count += size
-			if count == bodyLen {
-				break
-			}
+		if req.IsHeartbeat() {
+			this.routineMgr.Spawn(ProcessTask{this, req, body}, nil, fmt.Sprintf("ProcessTask-%d", req.GetMsgID()))
+			continue
+		}
+		if this.mgr == nil {
+			this.routineMgr.Spawn(ProcessTask{this, req, body}, nil, fmt.Sprintf("ProcessTask-%d", req.GetMsgID()))
+			continue
+		}
+		if !this.mgr.acquire() {
+			this.rejectRequest(req)
+			continue
+		}
+		if !this.mgr.pool.Submit(ProcessTask{this, req, body}, nil) {
+			this.mgr.release()
+			this.rejectRequest(req)
 		}
-		this.routineMgr.Spawn(ProcessTask{this, req, body}, nil, fmt.Sprintf("ProcessTask-%d", req.GetMsgID()))
 	}
-exitloop:
 	this.Close()
 }
 
+//rejectRequest is a method to reply a request at once when pending requests or worker queue reach the limit
+func (this *DubboConnection) rejectRequest(req *dubbo.Request) {
+	openlog.Warn(fmt.Sprintf("dubbo server is busy, reject request from %s", this.remoteAddr))
+	if !req.IsTwoWay() {
+		return
+	}
+	rsp := &dubbo.DubboRsp{}
+	rsp.Init()
+	rsp.SetID(req.GetMsgID())
+	rsp.SetStatus(dubbo.ServerThreadPoolExhaustedError)
+	rsp.SetErrorMsg("too many pending requests")
+	this.msgque.Enqueue(rsp)
+}
+
 //ProcessBody is a method to process the body of response
 func (this *DubboConnection) ProcessBody(req *dubbo.Request, bufBody []byte) {
 	var buffer util.ReadBuffer
@@ -183,7 +224,9 @@ func (this *DubboConnection) HandleMsg(req *dubbo.Request) {
 		if err != nil {
 			ctx.Rsp.SetErrorMsg(err.Error())
 			openlog.Error("request: " + err.Error())
-			ctx.Rsp.SetStatus(dubbo.ServerError)
+			if ctx.Rsp.GetStatus() == dubbo.Ok {
+				ctx.Rsp.SetStatus(dubbo.ServerError)
+			}
 		}
 		ctx.Req.SetMsgID(srcMsgID)
 		ctx.Rsp.SetID(srcMsgID)
@@ -202,11 +245,16 @@ func (this *DubboConnection) MsgSndLoop() {
 			openlog.Error("MsgSndLoop Dequeue: " + err.Error())
 			break
 		}
-		var buffer util.WriteBuffer
-		buffer.Init(0)
-		this.codec.EncodeDubboRsp(msg.(*dubbo.DubboRsp), &buffer)
-		bs := buffer.GetValidData()
-		_, err = this.conn.Write(bs /*buffer.GetValidData()*/)
+		buffer := util.AcquireWriteBuffer()
+		switch m := msg.(type) {
+		case *dubbo.Request:
+			//heartbeat sent by server
+			this.codec.EncodeHeartbeatReq(m.GetMsgID(), buffer)
+		case *dubbo.DubboRsp:
+			this.codec.EncodeDubboRsp(m, buffer)
+		}
+		_, err = this.conn.Write(buffer.GetValidData())
+		util.ReleaseWriteBuffer(buffer)
 		if err != nil {
 			openlog.Error("Send exception: " + err.Error())
 			break
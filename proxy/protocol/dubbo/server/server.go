@@ -19,15 +19,23 @@ package server
 
 import (
 	"fmt"
+	mesherconfig "github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/sniff"
+	"github.com/go-chassis/go-archaius"
 	"github.com/go-chassis/go-chassis/v2/core/config"
 	"github.com/go-chassis/go-chassis/v2/core/config/schema"
 	"github.com/go-chassis/openlog"
 	"gopkg.in/yaml.v2"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/client"
+	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/dubbo"
 	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/proxy"
+	dubboschema "github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/schema"
 	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/utils"
 	"github.com/go-chassis/go-chassis/v2/core/server"
 )
@@ -36,36 +44,172 @@ const (
 	NAME = "dubbo"
 )
 
+//Config keys of dubbo server, durations are in format like "60s"
+const (
+	KeyIdleTimeout        = "mesher.dubbo.idleTimeout"
+	KeyHeartbeatInterval  = "mesher.dubbo.heartbeatInterval"
+	KeyMaxConnections     = "mesher.dubbo.maxConnections"
+	KeyMaxPendingRequests = "mesher.dubbo.maxPendingRequests"
+	KeyMaxPayload         = "mesher.dubbo.maxPayload"
+	KeyWorkers            = "mesher.dubbo.workers"
+	KeyQueueSize          = "mesher.dubbo.queueSize"
+)
+
+//Default values of connection options, same as dubbo
+const (
+	DefaultIdleTimeout       = 180 * time.Second
+	DefaultHeartbeatInterval = 60 * time.Second
+)
+
+//ReapInterval is the interval to check idle connections and send heartbeats
+var ReapInterval = time.Second
+
+//ConnOptions limits connections of a listener, zero value means no limit
+type ConnOptions struct {
+	//IdleTimeout closes a connection which has received nothing for this long
+	IdleTimeout time.Duration
+	//HeartbeatInterval sends heartbeat if a connection has received nothing for this long
+	HeartbeatInterval time.Duration
+	MaxConnections    int
+	//MaxPendingRequests limits requests in processing of all connections
+	MaxPendingRequests int
+	//MaxPayload limits body length of a frame, connection sending a larger frame is closed
+	MaxPayload int
+	//Workers is the count of routines processing requests of all connections
+	Workers int
+	//QueueSize limits requests waiting for a worker, requests beyond it are rejected
+	QueueSize int
+}
+
+//ReadConnOptions reads connection options from config
+func ReadConnOptions() ConnOptions {
+	return ConnOptions{
+		IdleTimeout:        mesherconfig.GetDuration(KeyIdleTimeout, DefaultIdleTimeout),
+		HeartbeatInterval:  mesherconfig.GetDuration(KeyHeartbeatInterval, DefaultHeartbeatInterval),
+		MaxConnections:     archaius.GetInt(KeyMaxConnections, 0),
+		MaxPendingRequests: archaius.GetInt(KeyMaxPendingRequests, 0),
+		MaxPayload:         archaius.GetInt(KeyMaxPayload, dubbo.DefaultMaxPayload),
+		Workers:            archaius.GetInt(KeyWorkers, util.DefaultWorkers),
+		QueueSize:          archaius.GetInt(KeyQueueSize, util.DefaultQueueSize),
+	}
+}
+
 //ConnectionMgr -------连接管理
 type ConnectionMgr struct {
-	conns map[int]*DubboConnection
-	count int
+	mtx      sync.Mutex
+	conns    map[int]*DubboConnection
+	count    int
+	opts     ConnOptions
+	listener string
+	pending  int32
+	pool     *util.WorkerPool
 }
 
 //NewConnectMgr is a function which new connection manager and returns it
-func NewConnectMgr() *ConnectionMgr {
+func NewConnectMgr(listener string, opts ConnOptions) *ConnectionMgr {
 	tmp := new(ConnectionMgr)
 	tmp.count = 0
 	tmp.conns = make(map[int]*DubboConnection)
+	tmp.opts = opts
+	tmp.listener = listener
+	tmp.pool = util.NewWorkerPool(opts.Workers, opts.QueueSize)
 	return tmp
 }
 
-//GetConnection is a method to get connection
-func (this *ConnectionMgr) GetConnection(conn *net.TCPConn) *DubboConnection {
+//GetConnection is a method to get connection,
+//it returns nil if connections reach the limit
+func (this *ConnectionMgr) GetConnection(conn net.Conn) *DubboConnection {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	if this.opts.MaxConnections > 0 && len(this.conns) >= this.opts.MaxConnections {
+		return nil
+	}
 	dubbConn := NewDubboConnetction(conn, nil)
-	key := this.count
-	this.conns[key] = dubbConn
+	dubbConn.id = this.count
+	dubbConn.mgr = this
+	this.conns[dubbConn.id] = dubbConn
 	this.count++
+	metrics.RecordListenerGauge(NAME, metrics.LActiveConnections, this.listener, float64(len(this.conns)))
 	return dubbConn
 }
 
+//remove is a method to forget a closed connection
+func (this *ConnectionMgr) remove(id int) {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	delete(this.conns, id)
+	metrics.RecordListenerGauge(NAME, metrics.LActiveConnections, this.listener, float64(len(this.conns)))
+}
+
+//Count is a method which returns the count of opened connections
+func (this *ConnectionMgr) Count() int {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	return len(this.conns)
+}
+
+//list returns a snapshot of connections, so that they can be closed without holding the lock
+func (this *ConnectionMgr) list() []*DubboConnection {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	conns := make([]*DubboConnection, 0, len(this.conns))
+	for _, v := range this.conns {
+		conns = append(conns, v)
+	}
+	return conns
+}
+
+//acquire is a method to take a pending request slot, it returns false if pending requests reach the limit
+func (this *ConnectionMgr) acquire() bool {
+	n := atomic.AddInt32(&this.pending, 1)
+	if this.opts.MaxPendingRequests > 0 && int(n) > this.opts.MaxPendingRequests {
+		atomic.AddInt32(&this.pending, -1)
+		return false
+	}
+	return true
+}
+
+//release is a method to give back a pending request slot
+func (this *ConnectionMgr) release() {
+	atomic.AddInt32(&this.pending, -1)
+}
+
+//Reap is a method which closes idle connections and sends heartbeats to quiet connections,
+//it records queue depth and pending requests at the same time
+func (this *ConnectionMgr) Reap(now time.Time) {
+	depth := 0
+	for _, c := range this.list() {
+		idle := now.Sub(c.LastRead())
+		if this.opts.IdleTimeout > 0 && idle >= this.opts.IdleTimeout {
+			openlog.Info(fmt.Sprintf("close idle dubbo connection %s, idle %s", c.remoteAddr, idle))
+			c.Close()
+			continue
+		}
+		if this.opts.HeartbeatInterval > 0 && idle >= this.opts.HeartbeatInterval &&
+			now.Sub(c.lastHeartbeat) >= this.opts.HeartbeatInterval {
+			c.lastHeartbeat = now
+			c.SendHeartbeat()
+		}
+		depth += c.msgque.Len()
+	}
+	metrics.RecordListenerGauge(NAME, metrics.LQueueDepth, this.listener, float64(depth+this.pool.QueueLen()))
+	metrics.RecordListenerGauge(NAME, metrics.LPendingRequests, this.listener,
+		float64(atomic.LoadInt32(&this.pending)))
+}
+
 //DeactiveAllConn is a function to close all connection
 func (this *ConnectionMgr) DeactiveAllConn() {
-	for _, v := range this.conns {
+	for _, v := range this.list() {
 		v.Close()
 	}
 }
 
+//Stop is a method to close all connection and stop workers
+func (this *ConnectionMgr) Stop() {
+	this.DeactiveAllConn()
+	this.pool.Stop()
+}
+
 func init() {
 	server.InstallPlugin(NAME, newServer)
 }
@@ -87,6 +231,7 @@ type DubboServer struct {
 	mux        sync.RWMutex
 	exit       chan chan error
 	routineMgr *util.RoutineManager
+	stop       chan struct{}
 }
 
 func (d *DubboServer) String() string {
@@ -96,7 +241,12 @@ func (d *DubboServer) String() string {
 //Init is a method to initialize the server
 func (d *DubboServer) Init() error {
 	initSchema()
-	d.connMgr = NewConnectMgr()
+	dubboschema.InitInterfaceCache()
+	opts := ReadConnOptions()
+	d.connMgr = NewConnectMgr(d.opts.Address, opts)
+	//frames from providers share the same limit
+	dubboclient.MaxPayload = opts.MaxPayload
+	d.stop = make(chan struct{})
 	openlog.Info("Dubbo server init success.")
 	return nil
 }
@@ -108,7 +258,8 @@ func (d *DubboServer) Register(schema interface{}, options ...server.RegisterOpt
 
 //Stop is a method to disconnect all connection
 func (d *DubboServer) Stop() error {
-	d.connMgr.DeactiveAllConn()
+	close(d.stop)
+	d.connMgr.Stop()
 	d.routineMgr.Done()
 	return nil
 }
@@ -128,40 +279,56 @@ func (d *DubboServer) Start() error {
 	if ip == nil {
 		return &util.BaseError{"invalid host"}
 	}
-	tcpAddr, err := net.ResolveTCPAddr("tcp", d.opts.Address)
-	if err != nil {
-		openlog.Error("ResolveTCPAddr err: " + err.Error())
-		return err
-	}
-	l, err := net.ListenTCP("tcp", tcpAddr)
+	l, err := sniff.Listen("tcp", d.opts.Address, NAME)
 	if err != nil {
 		openlog.Error("listening failed, reason: " + err.Error())
 		return err
 	}
 	d.routineMgr.Spawn(d, l, "Acceptloop")
+	go d.reapLoop()
 	return nil
 }
 
+func (d *DubboServer) reapLoop() {
+	ticker := time.NewTicker(ReapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case now := <-ticker.C:
+			d.connMgr.Reap(now)
+		}
+	}
+}
+
 //Svc is a method
 func (d *DubboServer) Svc(arg interface{}) interface{} {
-	d.AcceptLoop(arg.(*net.TCPListener))
+	d.AcceptLoop(arg.(net.Listener))
 	return nil
 }
 
 //AcceptLoop is a method
-func (d *DubboServer) AcceptLoop(l *net.TCPListener) {
+func (d *DubboServer) AcceptLoop(l net.Listener) {
 	timer := time.NewTimer(time.Second * 3)
 	defer timer.Stop()
 	for {
-		conn, err := l.AcceptTCP()
+		conn, err := l.Accept()
 		if err != nil {
 			select {
 			case <-timer.C:
 				openlog.Info("Sleep three second")
 			}
 			timer.Reset(time.Second * 3)
+			continue
 		}
 		dubbConn := d.connMgr.GetConnection(conn)
+		if dubbConn == nil {
+			openlog.Warn(fmt.Sprintf("dubbo connections reach the limit %d, reject %s",
+				d.connMgr.opts.MaxConnections, conn.RemoteAddr()))
+			conn.Close()
+			continue
+		}
 		dubbConn.Open()
 	}
 }
@@ -20,10 +20,13 @@ package server
 import (
 	dubboclient "github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/client"
 	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/dubbo"
+	"io"
+	"net"
 	"sync"
 	"time"
 
 	//_ "github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/client"
+	"github.com/go-chassis/go-archaius"
 	"github.com/go-chassis/go-chassis/v2/core/config"
 	"github.com/go-chassis/go-chassis/v2/core/config/model"
 	"github.com/go-chassis/go-chassis/v2/core/lager"
@@ -35,6 +38,7 @@ import (
 
 func init() {
 	lager.Init(&lager.Options{LoggerLevel: "DEBUG"})
+	archaius.Init(archaius.WithMemorySource())
 }
 
 func TestDubboServer_Start(t *testing.T) {
@@ -150,3 +154,80 @@ func TestDubboServer(t *testing.T) {
 	err = s.Stop()
 	assert.NoError(t, err)
 }
+
+func tcpPair(t *testing.T) (*net.TCPConn, *net.TCPConn) {
+	l, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	defer l.Close()
+	client, err := net.DialTCP("tcp", nil, l.Addr().(*net.TCPAddr))
+	assert.NoError(t, err)
+	server, err := l.AcceptTCP()
+	assert.NoError(t, err)
+	return client, server
+}
+
+func TestConnectionMgr(t *testing.T) {
+	t.Run("connections are limited and removed on close", func(t *testing.T) {
+		mgr := NewConnectMgr("test", ConnOptions{MaxConnections: 1})
+		c1, s1 := tcpPair(t)
+		defer c1.Close()
+		c2, s2 := tcpPair(t)
+		defer c2.Close()
+		defer s2.Close()
+		conn := mgr.GetConnection(s1)
+		assert.NotNil(t, conn)
+		assert.Nil(t, mgr.GetConnection(s2))
+		conn.Close()
+		assert.Equal(t, 0, mgr.Count())
+		assert.NotNil(t, mgr.GetConnection(s2))
+	})
+	t.Run("idle connections are closed", func(t *testing.T) {
+		mgr := NewConnectMgr("test", ConnOptions{IdleTimeout: 100 * time.Millisecond})
+		c, s := tcpPair(t)
+		defer c.Close()
+		mgr.GetConnection(s)
+		mgr.Reap(time.Now())
+		assert.Equal(t, 1, mgr.Count())
+		mgr.Reap(time.Now().Add(time.Second))
+		assert.Equal(t, 0, mgr.Count())
+	})
+	t.Run("heartbeat is sent to quiet connections", func(t *testing.T) {
+		mgr := NewConnectMgr("test", ConnOptions{HeartbeatInterval: 100 * time.Millisecond})
+		c, s := tcpPair(t)
+		defer c.Close()
+		mgr.GetConnection(s).Open()
+		mgr.Reap(time.Now().Add(time.Second))
+
+		c.SetReadDeadline(time.Now().Add(3 * time.Second))
+		head := make([]byte, dubbo.HeaderLength)
+		_, err := io.ReadFull(c, head)
+		assert.NoError(t, err)
+		req := &dubbo.Request{}
+		bodyLen := 0
+		codec := dubbo.DubboCodec{}
+		assert.Equal(t, dubbo.Success, codec.DecodeDubboReqHead(req, head, &bodyLen))
+		assert.True(t, req.IsHeartbeat())
+		assert.True(t, req.IsTwoWay())
+		mgr.DeactiveAllConn()
+		assert.Equal(t, 0, mgr.Count())
+	})
+	t.Run("pending requests are limited", func(t *testing.T) {
+		mgr := NewConnectMgr("test", ConnOptions{MaxPendingRequests: 1})
+		assert.True(t, mgr.acquire())
+		assert.False(t, mgr.acquire())
+		mgr.release()
+		assert.True(t, mgr.acquire())
+	})
+}
+
+func TestReadConnOptions(t *testing.T) {
+	archaius.Set(KeyIdleTimeout, "10s")
+	archaius.Set(KeyMaxConnections, 3)
+	defer archaius.Delete(KeyIdleTimeout)
+	defer archaius.Delete(KeyMaxConnections)
+	opts := ReadConnOptions()
+	assert.Equal(t, 10*time.Second, opts.IdleTimeout)
+	assert.Equal(t, DefaultHeartbeatInterval, opts.HeartbeatInterval)
+	assert.Equal(t, 3, opts.MaxConnections)
+	assert.Equal(t, 0, opts.MaxPendingRequests)
+}
@@ -18,8 +18,11 @@
 package util
 
 import (
+	"bytes"
 	"github.com/go-chassis/gohessian"
+	"io"
 	"reflect"
+	"sync"
 
 	"fmt"
 	"github.com/go-chassis/openlog"
@@ -44,8 +47,35 @@ func init() {
 const (
 	DefaultGrowSize   = 4096
 	DefaultBufferSize = 1024
+	//MaxPooledBufferSize is the max capacity of a write buffer put back to pool
+	MaxPooledBufferSize = 1024 * 1024
 )
 
+//writeBufferPool keeps buffers of encoding, which are released once written to connection,
+//read buffers are not pooled, decoded messages refer to them until they are forwarded
+var writeBufferPool = sync.Pool{
+	New: func() interface{} {
+		b := new(WriteBuffer)
+		b.Init(0)
+		return b
+	},
+}
+
+//AcquireWriteBuffer is a function to get an empty write buffer from pool
+func AcquireWriteBuffer() *WriteBuffer {
+	b := writeBufferPool.Get().(*WriteBuffer)
+	b.Reset()
+	return b
+}
+
+//ReleaseWriteBuffer is a function to put a write buffer back to pool, the data of it can not be used any more
+func ReleaseWriteBuffer(b *WriteBuffer) {
+	if b == nil || b.capacity > MaxPooledBufferSize {
+		return
+	}
+	writeBufferPool.Put(b)
+}
+
 //ReadBuffer is a struct
 type ReadBuffer struct {
 	buffer   []byte
@@ -71,6 +101,11 @@ func (b *WriteBuffer) Init(size int) {
 	b.capacity = size
 }
 
+//Reset is a method to empty the buffer and keep the memory
+func (b *WriteBuffer) Reset() {
+	b.wrInd = 0
+}
+
 //Write is a method to write into buffer
 func (b *WriteBuffer) Write(p []byte) (n int, err error) {
 	result := b.WriteBytes(p)
@@ -169,7 +204,11 @@ func (b *ReadBuffer) ReadByte() (byte, error) {
 		openlog.Error(err.Error())
 		return byte(0), err
 	}
-	return byte(tmp.(int32)), nil
+	v, ok := tmp.(int32)
+	if !ok {
+		return byte(0), &BaseError{"not a byte"}
+	}
+	return byte(v), nil
 }
 
 //ReadBytes is a method to read data from buffer
@@ -179,25 +218,36 @@ func (b *ReadBuffer) ReadBytes(len int) []byte {
 	return b.buffer[start:b.rdInd]
 }
 
-//ReadObject is a method to read buffer and return object
-func (b *ReadBuffer) ReadObject() (interface{}, error) {
+//ReadObject is a method to read buffer and return object, a malformed buffer returns error instead of panic
+func (b *ReadBuffer) ReadObject() (obj interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			obj = nil
+			err = &BaseError{fmt.Sprintf("decode object failed: %v", r)}
+		}
+	}()
+	if err := CheckObject(b.buffer[b.rdInd:b.length]); err != nil {
+		return nil, &BaseError{fmt.Sprintf("decode object failed: %v", err)}
+	}
 	gh := hessian.NewGoHessian(TypMap, nil)
-	obj, err := gh.ToObject2(b)
-	return obj, err
+	return gh.ToObject2(b)
 }
 
 //ReadString is a method to read buffer and return as string
 func (b *ReadBuffer) ReadString() string {
-	gh := hessian.NewGoHessian(nil, nil)
-	obj, err := gh.ToObject2(b)
+	obj, err := b.ReadObject()
 	if obj == nil || err != nil {
 		return ""
 	}
-	return obj.(string)
+	s, _ := obj.(string)
+	return s
 }
 
 //ReadMap is a method to read buffer and return as a map
 func (b *ReadBuffer) ReadMap() (map[string]string, error) {
+	if err := CheckObject(b.buffer[b.rdInd:b.length]); err != nil {
+		return nil, &BaseError{fmt.Sprintf("decode map failed: %v", err)}
+	}
 	gh := hessian.NewGoHessian(nil, nil)
 	obj, err := gh.ToObject2(b)
 	if err != nil {
@@ -212,20 +262,53 @@ func (b *ReadBuffer) ReadMap() (map[string]string, error) {
 	}
 }
 
-//Read 实现io.Reader
+//Read 实现io.Reader, it returns io.EOF at the end, so that decoder stops on a truncated buffer
 func (b *ReadBuffer) Read(p []byte) (n int, err error) {
-	size := len(p)
-	if b.length > (b.rdInd + size) {
-		copy(p, b.buffer[b.rdInd:b.rdInd+size])
-		b.rdInd = b.rdInd + size
-		return size, nil
-	} else if b.length == b.rdInd {
-		return 0, nil
-	} else {
-		cpysize := b.length - b.rdInd
-		copy(p, b.buffer[b.rdInd:b.length])
-		b.rdInd = b.length - 1
-		return cpysize, nil
+	if b.rdInd >= b.length {
+		return 0, io.EOF
 	}
+	n = copy(p, b.buffer[b.rdInd:b.length])
+	b.rdInd += n
+	return n, nil
+}
 
+//ReadIndex is a method to get the offset of the next byte to read
+func (b *ReadBuffer) ReadIndex() int {
+	return b.rdInd
+}
+
+//ObjectDecoder decodes successive hessian objects of one stream with a shared decoder,
+//so that an object can refer to the class definitions of the objects before it
+type ObjectDecoder struct {
+	data    []byte
+	r       *bytes.Reader
+	scanner hessianScanner
+	d       interface {
+		ReadObject() (interface{}, error)
+	}
+}
+
+//NewObjectDecoder is a function which creates an object decoder on the data
+func NewObjectDecoder(data []byte) *ObjectDecoder {
+	r := bytes.NewReader(data)
+	return &ObjectDecoder{data: data, r: r, d: hessian.NewDecoder(r, TypMap)}
+}
+
+//Offset is a method to get the offset of the next object in data
+func (o *ObjectDecoder) Offset() int {
+	return int(o.r.Size()) - o.r.Len()
+}
+
+//ReadObject is a method to read next object, a malformed stream returns error instead of panic
+func (o *ObjectDecoder) ReadObject() (obj interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			obj = nil
+			err = &BaseError{fmt.Sprintf("decode object failed: %v", r)}
+		}
+	}()
+	if err := o.scanner.check(o.data[o.Offset():]); err != nil {
+		return nil, &BaseError{fmt.Sprintf("decode object failed: %v", err)}
+	}
+	return o.d.ReadObject()
 }
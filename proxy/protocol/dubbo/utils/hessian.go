@@ -0,0 +1,354 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"errors"
+)
+
+//MaxObjectDepth is the max nesting depth of lists, maps and objects in one hessian value
+const MaxObjectDepth = 128
+
+//ErrObjectLength means a hessian value declares more elements than the bytes left in the data
+var ErrObjectLength = errors.New("hessian length exceeds the data")
+
+//ErrObjectDepth means a hessian value nests deeper than MaxObjectDepth
+var ErrObjectDepth = errors.New("hessian value nests too deep")
+
+//errScanStop means the scanner can not follow the data any further,
+//the decoder is left to report the real error
+var errScanStop = errors.New("stop scanning")
+
+//hessianScanner walks hessian values the same way the gohessian decoder consumes them, without decoding.
+//The decoder allocates lists and class definitions with the counts read from wire,
+//so the scanner checks every count against the bytes left before the decoder runs
+type hessianScanner struct {
+	data    []byte
+	pos     int
+	depth   int
+	classes []int
+}
+
+//CheckObject is a function which checks the first hessian value of data before it is decoded,
+//it returns error if a count of the value can not fit in data or the value nests too deep
+func CheckObject(data []byte) error {
+	s := &hessianScanner{}
+	return s.check(data)
+}
+
+//check scans the first value of data, class definitions are kept for the values behind
+func (s *hessianScanner) check(data []byte) error {
+	s.data = data
+	s.pos = 0
+	s.depth = 0
+	err := s.value()
+	if err == errScanStop {
+		return nil
+	}
+	return err
+}
+
+func (s *hessianScanner) readByte() (byte, error) {
+	if s.pos >= len(s.data) {
+		return 0, errScanStop
+	}
+	b := s.data[s.pos]
+	s.pos++
+	return b, nil
+}
+
+func (s *hessianScanner) skip(n int) error {
+	if n < 0 || n > len(s.data)-s.pos {
+		return errScanStop
+	}
+	s.pos += n
+	return nil
+}
+
+//count checks that n elements, each taking at least one byte, fit in the bytes left
+func (s *hessianScanner) count(n int) error {
+	if n < 0 {
+		return errScanStop
+	}
+	if n > len(s.data)-s.pos {
+		return ErrObjectLength
+	}
+	return nil
+}
+
+func (s *hessianScanner) readInt() (int, error) {
+	tag, err := s.readByte()
+	if err != nil {
+		return 0, err
+	}
+	return s.intValue(tag)
+}
+
+//intValue follows the decoder which takes the offsets of compact integers in byte arithmetic
+func (s *hessianScanner) intValue(tag byte) (int, error) {
+	switch {
+	case tag >= 0x80 && tag <= 0xbf:
+		return int(tag - 0x90), nil
+	case tag >= 0xc0 && tag <= 0xcf:
+		b, err := s.readByte()
+		if err != nil {
+			return 0, err
+		}
+		return int(tag-0xc8)<<8 + int(b), nil
+	case tag >= 0xd0 && tag <= 0xd7:
+		if err := s.skip(2); err != nil {
+			return 0, err
+		}
+		bf := s.data[s.pos-2 : s.pos]
+		return int(tag-0xd4)<<16 + int(bf[1])<<8 + int(bf[0]), nil
+	case tag == 'I':
+		if err := s.skip(4); err != nil {
+			return 0, err
+		}
+		bf := s.data[s.pos-4 : s.pos]
+		return int(int32(bf[0])<<24 + int32(bf[1])<<16 + int32(bf[2])<<8 + int32(bf[3])), nil
+	}
+	return 0, errScanStop
+}
+
+func isStringTag(tag byte) bool {
+	return tag <= 0x1f || (tag >= 0x30 && tag <= 0x33) || tag == 'R' || tag == 'S'
+}
+
+func (s *hessianScanner) readString() error {
+	tag, err := s.readByte()
+	if err != nil {
+		return err
+	}
+	return s.stringValue(tag)
+}
+
+func (s *hessianScanner) stringValue(tag byte) error {
+	if !isStringTag(tag) {
+		return errScanStop
+	}
+	for {
+		var n int
+		switch {
+		case tag <= 0x1f:
+			n = int(tag)
+		case tag >= 0x30 && tag <= 0x33:
+			b, err := s.readByte()
+			if err != nil {
+				return err
+			}
+			n = (int(tag)-0x30)<<8 + int(b)
+		default:
+			b, err := s.readByte()
+			if err != nil {
+				return err
+			}
+			n = int(tag)<<8 + int(b)
+		}
+		if err := s.skip(n); err != nil {
+			return err
+		}
+		if tag != 'R' {
+			return nil
+		}
+		next, err := s.readByte()
+		if err != nil {
+			return err
+		}
+		if next != 'R' && next != 'S' {
+			return errScanStop
+		}
+		tag = next
+	}
+}
+
+func (s *hessianScanner) binaryValue(tag byte) error {
+	for {
+		var n int
+		switch {
+		case tag >= 0x20 && tag <= 0x2f:
+			n = int(tag) - 0x20
+		case tag == 'A' || tag == 'B':
+			if err := s.skip(2); err != nil {
+				return err
+			}
+			n = int(s.data[s.pos-1])
+		default:
+			return errScanStop
+		}
+		if err := s.skip(n); err != nil {
+			return err
+		}
+		if tag != 'A' {
+			return nil
+		}
+		next, err := s.readByte()
+		if err != nil {
+			return err
+		}
+		if next != 'A' && next != 'B' {
+			return errScanStop
+		}
+		tag = next
+	}
+}
+
+//readType consumes a list or map type which is a string or a type reference
+func (s *hessianScanner) readType() error {
+	tag, err := s.readByte()
+	if err != nil {
+		return err
+	}
+	if isStringTag(tag) {
+		return s.stringValue(tag)
+	}
+	_, err = s.readInt()
+	return err
+}
+
+func (s *hessianScanner) values(n int) error {
+	if err := s.count(n); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if err := s.value(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *hessianScanner) object(idx int) error {
+	if idx < 0 || idx >= len(s.classes) {
+		return errScanStop
+	}
+	return s.values(s.classes[idx])
+}
+
+func (s *hessianScanner) classDef() error {
+	if err := s.readString(); err != nil {
+		return err
+	}
+	n, err := s.readInt()
+	if err != nil {
+		return err
+	}
+	if err := s.count(n); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if err := s.readString(); err != nil {
+			return err
+		}
+	}
+	s.classes = append(s.classes, n)
+	return nil
+}
+
+func (s *hessianScanner) value() error {
+	s.depth++
+	defer func() { s.depth-- }()
+	if s.depth > MaxObjectDepth {
+		return ErrObjectDepth
+	}
+	tag, err := s.readByte()
+	if err != nil {
+		return err
+	}
+	switch {
+	case tag == 'Z' || tag == 'N' || tag == 'T' || tag == 'F':
+		return nil
+	case tag == 'M':
+		if err := s.readType(); err != nil {
+			return err
+		}
+		if err := s.value(); err != nil {
+			return err
+		}
+		return s.value()
+	case tag == 'H':
+		for {
+			if s.pos < len(s.data) && s.data[s.pos] == 'Z' {
+				s.pos++
+				return nil
+			}
+			if err := s.value(); err != nil {
+				return err
+			}
+			if err := s.value(); err != nil {
+				return err
+			}
+		}
+	case tag == 'C':
+		if err := s.classDef(); err != nil {
+			return err
+		}
+		//the decoder reads the instance behind a definition as the same value
+		return s.value()
+	case tag == 'O':
+		idx, err := s.readInt()
+		if err != nil {
+			return err
+		}
+		return s.object(idx)
+	case tag >= 0x60 && tag <= 0x6f:
+		return s.object(int(tag) - 0x60)
+	case (tag >= 0x80 && tag <= 0xd7) || tag == 'I':
+		_, err := s.intValue(tag)
+		return err
+	case (tag >= 0xd8 && tag <= 0xef) || tag == 0x5b || tag == 0x5c:
+		return nil
+	case (tag >= 0xf4 && tag <= 0xff) || tag == 0x5d:
+		return s.skip(1)
+	case (tag >= 0x38 && tag <= 0x3f) || tag == 0x5e:
+		return s.skip(2)
+	case tag == 0x59:
+		return s.skip(4)
+	case tag == 'L' || tag == 'D' || tag == 'J':
+		return s.skip(8)
+	case tag == 0x5f:
+		_, err := s.readInt()
+		return err
+	case isStringTag(tag):
+		return s.stringValue(tag)
+	case (tag >= 0x20 && tag <= 0x2f) || tag == 'A' || tag == 'B':
+		return s.binaryValue(tag)
+	case (tag >= 0x70 && tag <= 0x77) || tag == 'U' || tag == 'V':
+		if err := s.readType(); err != nil {
+			return err
+		}
+		if tag >= 0x70 && tag <= 0x77 {
+			return s.values(int(tag) - 0x70)
+		}
+		n, err := s.readInt()
+		if err != nil {
+			return err
+		}
+		return s.values(n)
+	case (tag >= 0x78 && tag <= 0x7f) || tag == 'W' || tag == 'X':
+		if tag >= 0x78 {
+			return s.values(int(tag) - 0x78)
+		}
+		n, err := s.readInt()
+		if err != nil {
+			return err
+		}
+		return s.values(n)
+	}
+	return errScanStop
+}
@@ -0,0 +1,77 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"testing"
+
+	"github.com/go-chassis/gohessian"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckObject(t *testing.T) {
+	gh := hessian.NewGoHessian(nil, nil)
+	for _, v := range []interface{}{
+		"str", int32(-5), int32(100000), int64(7), 1.5, true,
+		[]interface{}{"a", int32(1), []interface{}{"b"}},
+		map[string]interface{}{"k": "v", "n": int32(2)},
+	} {
+		data, err := gh.ToBytes(v)
+		assert.NoError(t, err)
+		assert.NoError(t, CheckObject(data), "%v", v)
+		// truncated data is left to the decoder
+		assert.NoError(t, CheckObject(data[:len(data)-1]), "%v", v)
+	}
+
+	// untyped fixed list declares 0x7fffffff elements
+	assert.Equal(t, ErrObjectLength, CheckObject([]byte{'X', 'I', 0x7f, 0xff, 0xff, 0xff, 'N'}))
+	// typed fixed list, compact int 0x8f is 255 for decoder
+	assert.Equal(t, ErrObjectLength, CheckObject([]byte{'V', 0x01, 'a', 0x8f, 'N'}))
+	// class definition with too many fields
+	assert.Equal(t, ErrObjectLength, CheckObject([]byte{'C', 0x01, 'A', 'I', 0x00, 0xff, 0xff, 0xff}))
+	// object with the fields of its definition
+	assert.NoError(t, CheckObject([]byte{'C', 0x01, 'A', 0x92, 0x01, 'a', 0x01, 'b', 0x60, 'N', 'T'}))
+
+	deep := make([]byte, 0, MaxObjectDepth+2)
+	for i := 0; i <= MaxObjectDepth; i++ {
+		deep = append(deep, 0x79)
+	}
+	deep = append(deep, 'N')
+	assert.Equal(t, ErrObjectDepth, CheckObject(deep))
+	assert.NoError(t, CheckObject(deep[len(deep)-MaxObjectDepth:]))
+}
+
+func TestReadBuffer_ReadObjectLength(t *testing.T) {
+	var rbf ReadBuffer
+	rbf.SetBuffer([]byte{'X', 'I', 0x7f, 0xff, 0xff, 0xff, 'N'})
+	obj, err := rbf.ReadObject()
+	assert.Error(t, err)
+	assert.Nil(t, obj)
+	assert.Equal(t, "", rbf.ReadString())
+
+	d := NewObjectDecoder([]byte{0x01, 'a', 0x78 + 2, 'N', 'T', 0x57, 'I', 0x7f, 0xff, 0xff, 0xff})
+	obj, err = d.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, "a", obj)
+	assert.Equal(t, 2, d.Offset())
+	obj, err = d.ReadObject()
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{nil, true}, obj)
+	_, err = d.ReadObject()
+	assert.Error(t, err)
+}
@@ -84,6 +84,13 @@ func (this *MsgQueue) Dequeue() (interface{}, error) {
 	return v, nil
 }
 
+//Len is a method which returns the count of messages in queue
+func (this *MsgQueue) Len() int {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
+	return this.msgCount
+}
+
 //isEmpty is a method which checks whether queue is empty
 func (this *MsgQueue) isEmpty() bool {
 	if this.msgCount == 0 {
@@ -118,6 +125,8 @@ func (this *MsgQueue) waitNotFullCond() int {
 
 //Deavtive is a method
 func (this *MsgQueue) Deavtive() {
+	this.mtx.Lock()
+	defer this.mtx.Unlock()
 	this.state = Deactived
 	this.notEmptyCond.Broadcast()
 	this.notFullCond.Broadcast()
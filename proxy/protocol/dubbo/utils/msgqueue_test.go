@@ -45,6 +45,7 @@ func TestMsgQueue(t *testing.T) {
 		eMSG := "msg to send"
 		err := q.Enqueue(eMSG)
 		assert.NoError(t, err)
+		assert.Equal(t, 1, q.Len())
 
 		assert.Equal(t, false, q.isEmpty())
 		assert.Equal(t, false, q.isFull())
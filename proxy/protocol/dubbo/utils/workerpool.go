@@ -0,0 +1,97 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"sync"
+)
+
+//Default size of worker pool, same as fixed thread pool of dubbo
+const (
+	DefaultWorkers   = 200
+	DefaultQueueSize = 1000
+)
+
+type poolTask struct {
+	task RoutineTask
+	args interface{}
+}
+
+//WorkerPool runs tasks with a fixed number of routines and a bounded queue,
+//so that a burst of requests can not spawn unlimited routines
+type WorkerPool struct {
+	tasks chan poolTask
+	quit  chan struct{}
+	once  sync.Once
+}
+
+//NewWorkerPool is a function which starts workers and returns the pool, a size less than 1 uses the default
+func NewWorkerPool(workers, queueSize int) *WorkerPool {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	p := &WorkerPool{
+		tasks: make(chan poolTask, queueSize),
+		quit:  make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *WorkerPool) work() {
+	for {
+		select {
+		case <-p.quit:
+			return
+		case t := <-p.tasks:
+			t.task.Svc(t.args)
+		}
+	}
+}
+
+//Submit is a method to queue a task, it returns false if the queue is full or the pool is stopped
+func (p *WorkerPool) Submit(task RoutineTask, args interface{}) bool {
+	select {
+	case <-p.quit:
+		return false
+	default:
+	}
+	select {
+	case p.tasks <- poolTask{task: task, args: args}:
+		return true
+	default:
+		return false
+	}
+}
+
+//QueueLen is a method which returns the count of tasks waiting for a worker
+func (p *WorkerPool) QueueLen() int {
+	return len(p.tasks)
+}
+
+//Stop is a method to stop all workers, a worker exits after its running task, queued tasks are dropped
+func (p *WorkerPool) Stop() {
+	p.once.Do(func() {
+		close(p.quit)
+	})
+}
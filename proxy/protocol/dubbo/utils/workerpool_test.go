@@ -0,0 +1,57 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type chanTask struct {
+	done chan interface{}
+}
+
+func (c *chanTask) Svc(args interface{}) interface{} {
+	c.done <- args
+	return nil
+}
+
+func TestWorkerPool(t *testing.T) {
+	p := NewWorkerPool(1, 1)
+	task := &chanTask{done: make(chan interface{})}
+
+	// the worker blocks on the first task, the second one waits in queue
+	assert.True(t, p.Submit(task, 1))
+	assert.Eventually(t, func() bool { return p.QueueLen() == 0 }, time.Second, time.Millisecond)
+	assert.True(t, p.Submit(task, 2))
+	assert.Equal(t, 1, p.QueueLen())
+	assert.False(t, p.Submit(task, 3))
+
+	assert.Equal(t, 1, <-task.done)
+	assert.Equal(t, 2, <-task.done)
+
+	p.Stop()
+	p.Stop()
+	assert.False(t, p.Submit(task, 4))
+
+	p = NewWorkerPool(0, 0)
+	assert.Equal(t, DefaultQueueSize, cap(p.tasks))
+	p.Stop()
+}
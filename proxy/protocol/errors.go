@@ -17,7 +17,13 @@
 
 package protocol
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
 
 var (
 	//ErrNilResult is of type error
@@ -26,6 +32,10 @@ var (
 	ErrUnknown = ProxyError{"Unknown Error,instance is not selected, error is nil"}
 	//ErrUnExpectedHandlerChainResponse is of type string which returns unexpected handler error
 	ErrUnExpectedHandlerChainResponse = ProxyError{"Response from Handler chain is nil,better to check if handler chain is empty, or some handler just return a nil response"}
+	//ErrInvalidReq means args of invocation is not the request type of the protocol client
+	ErrInvalidReq = errors.New("request arg is not the request type of protocol")
+	//ErrInvalidResp means reply of invocation is not the response type of the protocol client
+	ErrInvalidResp = errors.New("response arg is not the response type of protocol")
 )
 
 //ProxyError is a struct
@@ -36,3 +46,35 @@ type ProxyError struct {
 func (e ProxyError) Error() string {
 	return e.Message
 }
+
+//HTTPError is an error of handler with http status and headers written to client,
+//like WWW-Authenticate of auth handlers
+type HTTPError struct {
+	Status  int
+	Header  http.Header
+	Message string
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+//GRPCStatus converts the error to grpc status
+func (e *HTTPError) GRPCStatus() *status.Status {
+	c := codes.Unknown
+	switch e.Status {
+	case http.StatusBadRequest:
+		c = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		c = codes.Unauthenticated
+	case http.StatusForbidden:
+		c = codes.PermissionDenied
+	case http.StatusNotFound:
+		c = codes.NotFound
+	case http.StatusTooManyRequests:
+		c = codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		c = codes.Unavailable
+	}
+	return status.New(c, e.Message)
+}
@@ -19,6 +19,9 @@ package protocol
 
 import (
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
 	"testing"
 )
 
@@ -27,3 +30,13 @@ func TestProxyError_Error(t *testing.T) {
 	roxyError := ProxyError{errorMSG}
 	assert.Equal(t, errorMSG, roxyError.Error())
 }
+
+func TestHTTPError_GRPCStatus(t *testing.T) {
+	err := &HTTPError{Status: http.StatusUnauthorized, Message: "token is missing"}
+	s, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, codes.Unauthenticated, s.Code())
+	assert.Equal(t, "token is missing", s.Message())
+	err.Status = http.StatusTeapot
+	assert.Equal(t, codes.Unknown, err.GRPCStatus().Code())
+}
@@ -0,0 +1,130 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/common"
+	"github.com/apache/servicecomb-mesher/proxy/ingress"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/accesslog"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
+	chassisCommon "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	utiltags "github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/go-chassis/openlog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ingressLimiter = ingress.NewRateLimiter()
+
+//HandleIngressTraffic is api gateway grpc handler, it forwards requests of external grpc clients
+//to the service of matching grpc ingress rule
+func HandleIngressTraffic(w http.ResponseWriter, r *http.Request) {
+	prepareRequest(r)
+	inv := consumerPreHandler(r)
+	defer logIngressAccess(inv, w, r, r.URL.Path, time.Now())
+	defer func() { ingress.RecordConsumer(Name, inv, w.Header().Get("Grpc-Status")) }()
+	h := make(map[string]string)
+	for k := range r.Header {
+		h[k] = r.Header.Get(k)
+	}
+	//transfer header into ctx
+	inv.Ctx = context.WithValue(inv.Ctx, chassisCommon.ContextHeaderKey{}, h)
+	pc, err := handler.GetChain(chassisCommon.Provider, common.ChainProviderIncoming)
+	if err != nil {
+		WriteErrorResponse(inv, w, r, http.StatusBadGateway, err)
+		openlog.Error("Get chain failed: " + err.Error())
+		return
+	}
+	var invRsp *invocation.Response
+	pc.Next(inv, func(ir *invocation.Response) {
+		invRsp = ir
+	})
+	if invRsp != nil && invRsp.Err != nil {
+		WriteErrorResponse(inv, w, r, invRsp.Status, invRsp.Err)
+		return
+	}
+	rule, err := ingress.DefaultFetcher.Fetch(Name, r.Method, r.Host, r.URL.Path, r.URL.Query(), r.Header)
+	if err == ingress.ErrNotMatch {
+		WriteErrorResponse(inv, w, r, http.StatusNotFound, status.Error(codes.Unimplemented, err.Error()))
+		return
+	}
+	if err != nil {
+		WriteErrorResponse(inv, w, r, http.StatusInternalServerError, status.Error(codes.Internal, err.Error()))
+		return
+	}
+	consumer := ingress.Consumer(inv.Ctx)
+	if q := ingressLimiter.Allow(rule, ingress.LimitKey(rule, r, consumer)); q != nil && !q.Allowed {
+		metrics.RecordRateLimited(Name, map[string]string{metrics.LHost: rule.Host, metrics.LAPIPath: ingress.APIPath(rule),
+			metrics.LConsumer: consumer})
+		WriteErrorResponse(inv, w, r, http.StatusTooManyRequests,
+			status.Error(codes.ResourceExhausted, ingress.ErrRateLimited.Error()))
+		return
+	}
+	service := &rule.Service
+	if len(rule.Services) != 0 {
+		service = ingress.SelectService(rule, r.Header.Get(rule.Sticky.Header))
+	}
+	inv.MicroServiceName = service.Name
+	if len(service.Tags) != 0 {
+		inv.RouteTags = utiltags.Tags{KV: service.Tags, Label: utiltags.LabelOfTags(service.Tags)}
+	}
+	r.URL.Path, _ = ingress.Rewrite(rule, service, r.URL.Path, "")
+	inv.URLPath = r.URL.Path
+	if service.Port.Value != "" {
+		h[XForwardedPort] = service.Port.Value
+	}
+	c, err := handler.GetChain(chassisCommon.Consumer, common.ChainConsumerOutgoing)
+	if err != nil {
+		WriteErrorResponse(inv, w, r, http.StatusBadGateway, err)
+		openlog.Error("Get chain failed: " + err.Error())
+		return
+	}
+	invRsp = nil
+	c.Next(inv, func(ir *invocation.Response) {
+		//Send the request to the destination
+		invRsp = ir
+	})
+	resp, err := handleRequest(w, r, inv, invRsp)
+	if err != nil {
+		openlog.Error("Handle request failed: " + err.Error())
+		return
+	}
+	RecordStatus(inv, resp.StatusCode)
+}
+
+//logIngressAccess logs the request, path is the method before rewriting
+func logIngressAccess(inv *invocation.Invocation, w http.ResponseWriter, r *http.Request, path string, begin time.Time) {
+	if !accesslog.Enabled() {
+		return
+	}
+	accesslog.Log(&accesslog.Record{
+		Protocol:  Name,
+		Source:    r.RemoteAddr,
+		Service:   inv.MicroServiceName,
+		Operation: path,
+		Status:    w.Header().Get("Grpc-Status"),
+		Latency:   time.Since(begin),
+		Tags:      ingress.AccessLogTags(inv, r.Host),
+	})
+}
@@ -0,0 +1,70 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/ingress"
+	_ "github.com/apache/servicecomb-mesher/proxy/ingress/servicecomb"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestHandleIngressTraffic(t *testing.T) {
+	assert.NoError(t, metrics.Init())
+	handler.CreateChains(common.Provider, map[string]string{"incoming": ""})
+	handler.CreateChains(common.Consumer, map[string]string{"outgoing": ""})
+	assert.NoError(t, archaius.Init(archaius.WithMemorySource()))
+	assert.NoError(t, archaius.Set("mesher.ingress.rule.grpc", `
+- grpc:
+    service: helloworld.Greeter
+  limit: 1
+  rateLimit:
+    key: header
+    header: x-user
+  service:
+    name: greeter
+`))
+	assert.NoError(t, ingress.Init())
+	ingressLimiter = ingress.NewRateLimiter()
+
+	send := func(path, user string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "http://example.com"+path, nil)
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("x-user", user)
+		w := httptest.NewRecorder()
+		HandleIngressTraffic(w, req)
+		return w
+	}
+	code := func(w *httptest.ResponseRecorder) string {
+		return w.Header().Get("Grpc-Status")
+	}
+	//there is no transport in the chain, so it fails after routing
+	assert.Equal(t, strconv.Itoa(int(codes.Unknown)), code(send("/helloworld.Greeter/SayHello", "jason")))
+	assert.Equal(t, strconv.Itoa(int(codes.ResourceExhausted)), code(send("/helloworld.Greeter/SayHello", "jason")))
+	assert.Equal(t, strconv.Itoa(int(codes.Unknown)), code(send("/helloworld.Greeter/SayHello", "tom")))
+	assert.Equal(t, strconv.Itoa(int(codes.Unimplemented)), code(send("/helloworld.Store/Buy", "tom")))
+}
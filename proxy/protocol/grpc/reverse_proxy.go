@@ -43,7 +43,6 @@ import (
 )
 
 var dr = resolver.GetDestinationResolver("http")
-var sr = resolver.GetSourceResolver()
 
 //constants for headers
 const (
@@ -146,9 +145,8 @@ func RemoteRequestHandler(w http.ResponseWriter, r *http.Request) {
 	inv := providerPreHandler(r)
 
 	if inv.SourceMicroService == "" {
-		source := stringutil.SplitFirstSep(r.RemoteAddr, ":")
 		//Resolve Source
-		si := sr.Resolve(source)
+		si := resolver.ResolveSource(resolver.RequestSource(r))
 		if si != nil {
 			inv.SourceMicroService = si.Name
 		}
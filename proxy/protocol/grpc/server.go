@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/apache/servicecomb-mesher/proxy/common"
+	"github.com/apache/servicecomb-mesher/proxy/ingress"
 	"github.com/apache/servicecomb-mesher/proxy/resolver"
 	chassisRuntime "github.com/go-chassis/go-chassis/v2/pkg/runtime"
 	"github.com/go-chassis/openlog"
@@ -30,6 +31,7 @@ import (
 	"strings"
 
 	"github.com/apache/servicecomb-mesher/proxy/pkg/runtime"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/sniff"
 	chassisCom "github.com/go-chassis/go-chassis/v2/core/common"
 	"github.com/go-chassis/go-chassis/v2/core/server"
 	chassisTLS "github.com/go-chassis/go-chassis/v2/core/tls"
@@ -41,18 +43,31 @@ const (
 )
 
 func init() {
-	server.InstallPlugin(Name, newServer)
+	server.InstallPlugin(Name, NewHTTP2Server(Name, LocalRequestHandler, RemoteRequestHandler, HandleIngressTraffic))
 }
 
-func newServer(opts server.Options) server.ProtocolServer {
-	return &httpServer{
-		opts: opts,
+//NewHTTP2Server returns a function to create a http2 based protocol server,
+//local handler serves requests from local service, remote handler serves requests from other mesher,
+//edge handler serves requests from external clients in edge mode, if it is nil, local handler is used
+func NewHTTP2Server(name string, local, remote, edge http.HandlerFunc) func(opts server.Options) server.ProtocolServer {
+	return func(opts server.Options) server.ProtocolServer {
+		return &httpServer{
+			name:   name,
+			opts:   opts,
+			local:  local,
+			remote: remote,
+			edge:   edge,
+		}
 	}
 }
 
 type httpServer struct {
+	name   string
 	opts   server.Options
 	server *http2.Server
+	local  http.HandlerFunc
+	remote http.HandlerFunc
+	edge   http.HandlerFunc
 }
 
 func (hs *httpServer) Register(schema interface{}, options ...server.RegisterOption) (string, error) {
@@ -75,6 +90,12 @@ func (hs *httpServer) Start() error {
 	switch runtime.Role {
 	case common.RoleSidecar:
 		err = hs.startSidecar(host, port)
+	case common.RoleEdge:
+		if hs.edge != nil {
+			err = hs.startEdge()
+			break
+		}
+		err = hs.startPerHost()
 	default:
 		err = hs.startPerHost()
 	}
@@ -98,7 +119,7 @@ func (hs *httpServer) startSidecar(host, port string) error {
 			sslTag, mesherSSLConfig.VerifyPeer, mesherSSLConfig.CipherPlugin))
 	}
 
-	err := hs.listenAndServe("127.0.0.1"+":"+port, mesherTLSConfig, http.HandlerFunc(LocalRequestHandler))
+	err := hs.listenAndServe("127.0.0.1"+":"+port, mesherTLSConfig, hs.local)
 	if err != nil {
 		return err
 	}
@@ -124,7 +145,7 @@ func (hs *httpServer) startSidecar(host, port string) error {
 				sslTag, serverSSLConfig.VerifyPeer, serverSSLConfig.CipherPlugin))
 		}
 
-		err = hs.listenAndServe(hs.opts.Address, serverTLSConfig, http.HandlerFunc(RemoteRequestHandler))
+		err = hs.listenAndServe(hs.opts.Address, serverTLSConfig, hs.remote)
 		if err != nil {
 			return err
 		}
@@ -146,16 +167,40 @@ func (hs *httpServer) startPerHost() error {
 			sslTag, mesherSSLConfig.VerifyPeer, mesherSSLConfig.CipherPlugin))
 	}
 
-	err = hs.listenAndServe(hs.opts.Address, mesherTLSConfig, http.HandlerFunc(LocalRequestHandler))
+	err = hs.listenAndServe(hs.opts.Address, mesherTLSConfig, hs.local)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+//startEdge serves external clients by ingress rules, TLS is terminated by mesher if it is configured
+func (hs *httpServer) startEdge() error {
+	if ingress.DefaultFetcher == nil {
+		if err := ingress.Init(); err != nil {
+			return err
+		}
+	}
+	tlsConfig, sslConfig, err := chassisTLS.GetTLSConfigByService(
+		chassisRuntime.ServiceName, hs.name, chassisCom.Provider)
+	if err != nil {
+		if !chassisTLS.IsSSLConfigNotExist(err) {
+			return err
+		}
+	} else {
+		sslTag := genTag(chassisRuntime.ServiceName, hs.name, chassisCom.Provider)
+		openlog.Warn(fmt.Sprintf("%s TLS mode, verify peer: %t, cipher plugin: %s.",
+			sslTag, sslConfig.VerifyPeer, sslConfig.CipherPlugin))
+		//grpc clients require h2 negotiated by ALPN
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.NextProtos = []string{http2.NextProtoTLS}
+	}
+	return hs.listenAndServe(hs.opts.Address, tlsConfig, hs.edge)
+}
+
 func (hs *httpServer) listenAndServe(addr string, t *tls.Config, h http.HandlerFunc) error {
 
-	ln, err := net.Listen("tcp", addr)
+	ln, err := sniff.ListenHTTP2("tcp", addr, hs.name)
 	if err != nil {
 		return err
 	}
@@ -176,7 +221,7 @@ func (hs *httpServer) listenAndServe(addr string, t *tls.Config, h http.HandlerF
 			if err != nil {
 				panic(err)
 			}
-			hs.server.ServeConn(conn, opts)
+			go hs.server.ServeConn(conn, opts)
 		}
 	}()
 	return nil
@@ -187,7 +232,7 @@ func (hs *httpServer) Stop() error {
 }
 
 func (hs *httpServer) String() string {
-	return Name
+	return hs.name
 }
 
 func genTag(s ...string) string {
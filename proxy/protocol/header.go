@@ -0,0 +1,49 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package protocol
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+)
+
+//SetHeader sets header of request sent to backend, both in http request and in invocation context,
+//req can be nil for protocols which only carry headers in context
+func SetHeader(inv *invocation.Invocation, req *http.Request, name, value string) {
+	if req != nil {
+		req.Header.Set(name, value)
+	}
+	common.FromContext(inv.Ctx)[name] = value
+}
+
+//DelHeader deletes header of request sent to backend, both from http request and from invocation context.
+//Auth handlers delete headers they forward before validating, because the same headers from client can not be trusted
+func DelHeader(inv *invocation.Invocation, req *http.Request, name string) {
+	if req != nil {
+		req.Header.Del(name)
+	}
+	h := common.FromContext(inv.Ctx)
+	for k := range h {
+		if strings.EqualFold(k, name) {
+			delete(h, k)
+		}
+	}
+}
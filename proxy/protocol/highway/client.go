@@ -0,0 +1,305 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package highway
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/openlog"
+)
+
+var (
+	//ErrTimeout means response does not come back in time
+	ErrTimeout = errors.New("highway call timeout")
+	//ErrConnClosed means connection is closed before response comes back
+	ErrConnClosed = errors.New("highway connection closed")
+)
+
+func init() {
+	client.InstallPlugin(Name, NewClient)
+}
+
+//Client is a highway client, calls to an endpoint share one connection,
+//each call gets a new msg ID on the connection and response gets back the original one
+type Client struct {
+	opts client.Options
+	mu   sync.Mutex
+	conn *clientConn
+}
+
+//NewClient return a new client of highway
+func NewClient(opts client.Options) (client.ProtocolClient, error) {
+	return &Client{
+		opts: opts,
+	}, nil
+}
+
+//Call sends the request in args of invocation to addr and waits for the response,
+//headers set by handler chain are sent in context of request header
+func (c *Client) Call(ctx context.Context, addr string, inv *invocation.Invocation, rsp interface{}) error {
+	req, ok := inv.Args.(*Request)
+	if !ok {
+		return protocol.ErrInvalidReq
+	}
+	resp, ok := rsp.(*Response)
+	if !ok {
+		return protocol.ErrInvalidResp
+	}
+	cc, err := c.getConn(addr)
+	if err != nil {
+		return err
+	}
+	h := *req.Header
+	h.Context = make(map[string]string, len(req.Header.Context))
+	for k, v := range req.Header.Context {
+		h.Context[k] = v
+	}
+	for k, v := range inv.Headers() {
+		h.Context[k] = v
+	}
+	m, err := cc.send(ctx, &Request{MsgID: req.MsgID, Header: &h, Body: req.Body}, c.timeout())
+	if err != nil {
+		return err
+	}
+	*resp = *m
+	return nil
+}
+
+func (c *Client) timeout() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.opts.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return c.opts.Timeout
+}
+
+func (c *Client) getConn(addr string) (*clientConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil && !c.conn.isClosed() {
+		return c.conn, nil
+	}
+	timeout := c.opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.TLSConfig != nil {
+		conn = tls.Client(conn, c.opts.TLSConfig)
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	if c.opts.TLSConfig != nil {
+		if err := conn.(*tls.Conn).Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	maxFrame := maxFrameSize()
+	if archaius.GetBool(KeyLogin, false) {
+		if err := login(conn, maxFrame); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	conn.SetDeadline(time.Time{})
+	c.conn = newClientConn(conn, maxFrame)
+	return c.conn, nil
+}
+
+//login sends login request before any call, the connection is not shared yet
+func login(conn net.Conn, maxFrame int) error {
+	req := &Request{
+		Header: &RequestHeader{MsgType: MsgTypeLogin},
+		Body:   loginBody().Marshal(),
+	}
+	if _, err := conn.Write(req.Encode()); err != nil {
+		return err
+	}
+	f, err := ReadFrame(conn, maxFrame)
+	if err != nil {
+		return err
+	}
+	rsp, err := DecodeResponse(f)
+	if err != nil {
+		return err
+	}
+	if rsp.Header.StatusCode != StatusOK {
+		return fmt.Errorf("highway login failed: %d %s", rsp.Header.StatusCode, rsp.Header.ReasonPhrase)
+	}
+	return nil
+}
+
+//Status returns status code of response
+func (c *Client) Status(rsp interface{}) (status int, err error) {
+	resp, ok := rsp.(*Response)
+	if !ok || resp.Header == nil {
+		return 0, protocol.ErrInvalidResp
+	}
+	return int(resp.Header.StatusCode), nil
+}
+
+//String return name
+func (c *Client) String() string {
+	return Name
+}
+
+//Close closes the connection
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		c.conn.close(ErrConnClosed)
+		c.conn = nil
+	}
+	return nil
+}
+
+//ReloadConfigs reload config
+func (c *Client) ReloadConfigs(opts client.Options) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.opts = client.EqualOpts(c.opts, opts)
+}
+
+//GetOptions return opts
+func (c *Client) GetOptions() client.Options {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.opts
+}
+
+//clientConn is an upstream connection shared by calls
+type clientConn struct {
+	conn     net.Conn
+	maxFrame int
+	wmu      sync.Mutex
+	mu       sync.Mutex
+	msgID    int64
+	pending  map[int64]chan *Response
+	closed   bool
+}
+
+func newClientConn(conn net.Conn, maxFrame int) *clientConn {
+	c := &clientConn{
+		conn:     conn,
+		maxFrame: maxFrame,
+		pending:  make(map[int64]chan *Response),
+	}
+	go c.readLoop()
+	return c
+}
+
+func (c *clientConn) readLoop() {
+	for {
+		f, err := ReadFrame(c.conn, c.maxFrame)
+		if err != nil {
+			c.close(err)
+			return
+		}
+		m, err := DecodeResponse(f)
+		if err != nil {
+			c.close(err)
+			return
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[m.MsgID]
+		delete(c.pending, m.MsgID)
+		c.mu.Unlock()
+		if !ok {
+			openlog.Warn(fmt.Sprintf("drop highway response of unknown msg ID %d", m.MsgID))
+			continue
+		}
+		ch <- m
+	}
+}
+
+//send writes req with a new msg ID, the response has the msg ID of req
+func (c *clientConn) send(ctx context.Context, req *Request, timeout time.Duration) (*Response, error) {
+	fwd := *req
+	ch := make(chan *Response, 1)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrConnClosed
+	}
+	c.msgID++
+	fwd.MsgID = c.msgID
+	c.pending[fwd.MsgID] = ch
+	c.mu.Unlock()
+
+	c.wmu.Lock()
+	_, err := c.conn.Write(fwd.Encode())
+	c.wmu.Unlock()
+	if err != nil {
+		c.close(err)
+		return nil, err
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case m, ok := <-ch:
+		if !ok {
+			return nil, ErrConnClosed
+		}
+		m.MsgID = req.MsgID
+		return m, nil
+	case <-timer.C:
+		err = ErrTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	c.mu.Lock()
+	delete(c.pending, fwd.MsgID)
+	c.mu.Unlock()
+	return nil, err
+}
+
+func (c *clientConn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+//close closes connection and wakes up all waiting calls
+func (c *clientConn) close(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.conn.Close()
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	openlog.Debug("highway connection closed: " + err.Error())
+}
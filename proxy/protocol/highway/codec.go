@@ -0,0 +1,427 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package highway
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+)
+
+//Magic starts every highway frame
+const Magic = "CSE.TCP"
+
+//HeaderSize is the size of fixed frame header: magic, msg ID, total length and header length
+const HeaderSize = len(Magic) + 8 + 4 + 4
+
+//Message types of request header
+const (
+	MsgTypeRequest int32 = 0
+	MsgTypeLogin   int32 = 1
+)
+
+//LoginProtocol is the protocol name sent in login request
+const LoginProtocol = "highway.v1"
+
+var (
+	//ErrMagic means data is not a highway frame
+	ErrMagic = errors.New("highway frame magic mismatch")
+	//ErrFrameSize means frame length is negative or exceeds max frame size
+	ErrFrameSize = errors.New("highway frame size is invalid")
+	//ErrInvalidMessage means a protobuf header can not be decoded
+	ErrInvalidMessage = errors.New("highway header is invalid")
+)
+
+//Frame is a highway frame, header and body are protobuf encoded
+type Frame struct {
+	MsgID  int64
+	Header []byte
+	Body   []byte
+}
+
+//ReadFrame reads a frame, total length of header and body can not exceed max
+func ReadFrame(r io.Reader, max int) (*Frame, error) {
+	var h [HeaderSize]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return nil, err
+	}
+	if string(h[:len(Magic)]) != Magic {
+		return nil, ErrMagic
+	}
+	b := h[len(Magic):]
+	total := int32(binary.BigEndian.Uint32(b[8:]))
+	headerLen := int32(binary.BigEndian.Uint32(b[12:]))
+	if total < 0 || int(total) > max || headerLen < 0 || headerLen > total {
+		return nil, ErrFrameSize
+	}
+	data := make([]byte, total)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return &Frame{
+		MsgID:  int64(binary.BigEndian.Uint64(b)),
+		Header: data[:headerLen],
+		Body:   data[headerLen:],
+	}, nil
+}
+
+//Encode returns bytes of frame
+func (f *Frame) Encode() []byte {
+	b := make([]byte, HeaderSize, HeaderSize+len(f.Header)+len(f.Body))
+	copy(b, Magic)
+	binary.BigEndian.PutUint64(b[len(Magic):], uint64(f.MsgID))
+	binary.BigEndian.PutUint32(b[len(Magic)+8:], uint32(len(f.Header)+len(f.Body)))
+	binary.BigEndian.PutUint32(b[len(Magic)+12:], uint32(len(f.Header)))
+	b = append(b, f.Header...)
+	return append(b, f.Body...)
+}
+
+//Request is a highway request
+type Request struct {
+	MsgID  int64
+	Header *RequestHeader
+	Body   []byte
+}
+
+//Encode returns frame bytes of request
+func (r *Request) Encode() []byte {
+	f := &Frame{MsgID: r.MsgID, Header: r.Header.Marshal(), Body: r.Body}
+	return f.Encode()
+}
+
+//DecodeRequest decodes header of a request frame
+func DecodeRequest(f *Frame) (*Request, error) {
+	h, err := UnmarshalRequestHeader(f.Header)
+	if err != nil {
+		return nil, err
+	}
+	return &Request{MsgID: f.MsgID, Header: h, Body: f.Body}, nil
+}
+
+//Response is a highway response
+type Response struct {
+	MsgID  int64
+	Header *ResponseHeader
+	Body   []byte
+}
+
+//Encode returns frame bytes of response
+func (r *Response) Encode() []byte {
+	f := &Frame{MsgID: r.MsgID, Header: r.Header.Marshal(), Body: r.Body}
+	return f.Encode()
+}
+
+//DecodeResponse decodes header of a response frame
+func DecodeResponse(f *Frame) (*Response, error) {
+	h, err := UnmarshalResponseHeader(f.Header)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{MsgID: f.MsgID, Header: h, Body: f.Body}, nil
+}
+
+//NewErrorResponse returns a response of req with status and error message,
+//the message is the reason phrase and also the body, like java chassis does
+func NewErrorResponse(req *Request, status int32, msg string) *Response {
+	return &Response{
+		MsgID: req.MsgID,
+		Header: &ResponseHeader{
+			StatusCode:   status,
+			ReasonPhrase: msg,
+		},
+		Body: NewErrorBody(msg),
+	}
+}
+
+//RequestHeader is the header of a request frame
+type RequestHeader struct {
+	MsgType          int32
+	Flags            int32
+	DestMicroservice string
+	SchemaID         string
+	OperationName    string
+	Context          map[string]string
+}
+
+//Marshal encodes header to protobuf
+func (h *RequestHeader) Marshal() []byte {
+	var b []byte
+	b = appendInt32(b, 1, h.MsgType)
+	b = appendInt32(b, 2, h.Flags)
+	b = appendString(b, 3, h.DestMicroservice)
+	b = appendString(b, 4, h.SchemaID)
+	b = appendString(b, 5, h.OperationName)
+	return appendMap(b, 6, h.Context)
+}
+
+//UnmarshalRequestHeader decodes a request header, unknown fields are skipped
+func UnmarshalRequestHeader(b []byte) (*RequestHeader, error) {
+	h := &RequestHeader{}
+	err := decodeFields(b, func(num int, f field) error {
+		switch num {
+		case 1:
+			h.MsgType = int32(f.varint)
+		case 2:
+			h.Flags = int32(f.varint)
+		case 3:
+			h.DestMicroservice = string(f.bytes)
+		case 4:
+			h.SchemaID = string(f.bytes)
+		case 5:
+			h.OperationName = string(f.bytes)
+		case 6:
+			return decodeMapEntry(&h.Context, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+//ResponseHeader is the header of a response frame
+type ResponseHeader struct {
+	Flags        int32
+	StatusCode   int32
+	ReasonPhrase string
+	Context      map[string]string
+	//Headers is kept in raw protobuf, mesher does not read it
+	Headers []byte
+}
+
+//Marshal encodes header to protobuf
+func (h *ResponseHeader) Marshal() []byte {
+	var b []byte
+	b = appendInt32(b, 1, h.Flags)
+	b = appendInt32(b, 2, h.StatusCode)
+	b = appendString(b, 3, h.ReasonPhrase)
+	b = appendMap(b, 4, h.Context)
+	if h.Headers != nil {
+		b = appendBytes(b, 5, h.Headers)
+	}
+	return b
+}
+
+//UnmarshalResponseHeader decodes a response header, unknown fields are skipped
+func UnmarshalResponseHeader(b []byte) (*ResponseHeader, error) {
+	h := &ResponseHeader{}
+	err := decodeFields(b, func(num int, f field) error {
+		switch num {
+		case 1:
+			h.Flags = int32(f.varint)
+		case 2:
+			h.StatusCode = int32(f.varint)
+		case 3:
+			h.ReasonPhrase = string(f.bytes)
+		case 4:
+			return decodeMapEntry(&h.Context, f)
+		case 5:
+			h.Headers = f.bytes
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+//Login is the body of login request and login response
+type Login struct {
+	Protocol            string
+	ZipName             string
+	UseProtobufMapCodec bool
+}
+
+//Marshal encodes login to protobuf
+func (l *Login) Marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, l.Protocol)
+	b = appendString(b, 2, l.ZipName)
+	if l.UseProtobufMapCodec {
+		b = appendVarint(appendTag(b, 3, wireVarint), 1)
+	}
+	return b
+}
+
+//UnmarshalLogin decodes a login request or response
+func UnmarshalLogin(b []byte) (*Login, error) {
+	l := &Login{}
+	err := decodeFields(b, func(num int, f field) error {
+		switch num {
+		case 1:
+			l.Protocol = string(f.bytes)
+		case 2:
+			l.ZipName = string(f.bytes)
+		case 3:
+			l.UseProtobufMapCodec = f.varint != 0
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+//NewErrorBody encodes message as the first string field,
+//it is how java chassis encodes CommonExceptionData
+func NewErrorBody(message string) []byte {
+	return appendString(nil, 1, message)
+}
+
+const (
+	wireVarint = 0
+	wire64     = 1
+	wireBytes  = 2
+	wire32     = 5
+)
+
+type field struct {
+	wire   int
+	varint uint64
+	bytes  []byte
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendTag(b []byte, num, wire int) []byte {
+	return appendVarint(b, uint64(num)<<3|uint64(wire))
+}
+
+//appendInt32 omits zero value like proto3, negative value takes 10 bytes
+func appendInt32(b []byte, num int, v int32) []byte {
+	if v == 0 {
+		return b
+	}
+	return appendVarint(appendTag(b, num, wireVarint), uint64(int64(v)))
+}
+
+func appendBytes(b []byte, num int, v []byte) []byte {
+	b = appendVarint(appendTag(b, num, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendString(b []byte, num int, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = appendVarint(appendTag(b, num, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+//appendMap encodes entries in key order, so that same map gets same bytes
+func appendMap(b []byte, num int, m map[string]string) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var e []byte
+		e = appendString(e, 1, k)
+		e = appendString(e, 2, m[k])
+		b = appendBytes(b, num, e)
+	}
+	return b
+}
+
+func consumeVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		v |= uint64(b[i]&0x7f) << (7 * uint(i))
+		if b[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
+
+//decodeFields calls fn with each field of a protobuf message
+func decodeFields(b []byte, fn func(num int, f field) error) error {
+	for len(b) > 0 {
+		tag, n := consumeVarint(b)
+		if n == 0 || tag>>3 == 0 {
+			return ErrInvalidMessage
+		}
+		b = b[n:]
+		f := field{wire: int(tag & 7)}
+		switch f.wire {
+		case wireVarint:
+			f.varint, n = consumeVarint(b)
+			if n == 0 {
+				return ErrInvalidMessage
+			}
+		case wire64:
+			n = 8
+		case wire32:
+			n = 4
+		case wireBytes:
+			l, m := consumeVarint(b)
+			if m == 0 || l > uint64(len(b)-m) {
+				return ErrInvalidMessage
+			}
+			f.bytes = b[m : m+int(l)]
+			n = m + int(l)
+		default:
+			return ErrInvalidMessage
+		}
+		if n > len(b) {
+			return ErrInvalidMessage
+		}
+		b = b[n:]
+		if err := fn(int(tag>>3), f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeMapEntry(m *map[string]string, f field) error {
+	if f.wire != wireBytes {
+		return ErrInvalidMessage
+	}
+	var k, v string
+	err := decodeFields(f.bytes, func(num int, e field) error {
+		switch num {
+		case 1:
+			k = string(e.bytes)
+		case 2:
+			v = string(e.bytes)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if *m == nil {
+		*m = make(map[string]string)
+	}
+	(*m)[k] = v
+	return nil
+}
@@ -0,0 +1,118 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package highway
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrame(t *testing.T) {
+	f := &Frame{MsgID: 7, Header: []byte{1, 2}, Body: []byte{3, 4, 5}}
+	b := f.Encode()
+	assert.Equal(t, HeaderSize+5, len(b))
+	assert.Equal(t, Magic, string(b[:7]))
+
+	got, err := ReadFrame(bytes.NewReader(b), DefaultMaxFrameSize)
+	assert.NoError(t, err)
+	assert.Equal(t, f, got)
+
+	_, err = ReadFrame(bytes.NewReader(b), 4)
+	assert.Equal(t, ErrFrameSize, err)
+	_, err = ReadFrame(bytes.NewReader(b[:len(b)-1]), DefaultMaxFrameSize)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	bad := append([]byte{}, b...)
+	bad[0] = 'X'
+	_, err = ReadFrame(bytes.NewReader(bad), DefaultMaxFrameSize)
+	assert.Equal(t, ErrMagic, err)
+
+	//header longer than frame
+	bad = append([]byte{}, b...)
+	binary.BigEndian.PutUint32(bad[19:], 6)
+	_, err = ReadFrame(bytes.NewReader(bad), DefaultMaxFrameSize)
+	assert.Equal(t, ErrFrameSize, err)
+}
+
+func TestRequestHeader(t *testing.T) {
+	h := &RequestHeader{
+		MsgType:          MsgTypeRequest,
+		Flags:            -1,
+		DestMicroservice: "app:order",
+		SchemaID:         "OrderEndpoint",
+		OperationName:    "create",
+		Context:          map[string]string{"x-cse-context": "{}", "traceId": "1"},
+	}
+	b := h.Marshal()
+	got, err := UnmarshalRequestHeader(b)
+	assert.NoError(t, err)
+	assert.Equal(t, h, got)
+	//keys of context are sorted
+	assert.Equal(t, b, got.Marshal())
+
+	//header written by java chassis: destMicroservice "a", schemaId "s", operationName "o",
+	//an unknown fixed64 field 9 and context {"k": "v"}
+	java := []byte{0x1a, 1, 'a', 0x22, 1, 's', 0x2a, 1, 'o', 0x49, 0, 0, 0, 0, 0, 0, 0, 0,
+		0x32, 6, 0x0a, 1, 'k', 0x12, 1, 'v'}
+	got, err = UnmarshalRequestHeader(java)
+	assert.NoError(t, err)
+	assert.Equal(t, &RequestHeader{DestMicroservice: "a", SchemaID: "s", OperationName: "o",
+		Context: map[string]string{"k": "v"}}, got)
+
+	for _, b := range [][]byte{{0x1a, 5, 'a'}, {0x08}, {0x0f}, {0x00, 1}, {0x32, 1, 0x08}} {
+		_, err = UnmarshalRequestHeader(b)
+		assert.Equal(t, ErrInvalidMessage, err, "%v", b)
+	}
+}
+
+func TestResponseHeader(t *testing.T) {
+	h := &ResponseHeader{
+		StatusCode:   590,
+		ReasonPhrase: "Internal Server Error",
+		Context:      map[string]string{"k": "v"},
+		Headers:      []byte{0x0a, 0},
+	}
+	got, err := UnmarshalResponseHeader(h.Marshal())
+	assert.NoError(t, err)
+	assert.Equal(t, h, got)
+}
+
+func TestLogin(t *testing.T) {
+	l := &Login{Protocol: LoginProtocol, ZipName: "snappy", UseProtobufMapCodec: true}
+	got, err := UnmarshalLogin(l.Marshal())
+	assert.NoError(t, err)
+	assert.Equal(t, l, got)
+}
+
+func TestNewErrorResponse(t *testing.T) {
+	req := &Request{MsgID: 3, Header: &RequestHeader{}}
+	rsp := NewErrorResponse(req, StatusConsumerInner, "no instance")
+	assert.Equal(t, int64(3), rsp.MsgID)
+	assert.Equal(t, int32(StatusConsumerInner), rsp.Header.StatusCode)
+	assert.Equal(t, "no instance", rsp.Header.ReasonPhrase)
+	assert.Equal(t, append([]byte{0x0a, 11}, "no instance"...), rsp.Body)
+
+	f, err := ReadFrame(bytes.NewReader(rsp.Encode()), DefaultMaxFrameSize)
+	assert.NoError(t, err)
+	got, err := DecodeResponse(f)
+	assert.NoError(t, err)
+	assert.Equal(t, rsp, got)
+}
@@ -0,0 +1,106 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+//Package highway proxies ServiceComb highway protocol which is the binary RPC protocol of java chassis.
+//Only frame header is decoded, destination micro service, schema and operation of request header
+//are used for routing, metrics and rate limiting, body is protobuf encoded arguments and forwarded as it is
+package highway
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/go-chassis/go-archaius"
+	chassisCommon "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/openlog"
+)
+
+//Name is the protocol name
+const Name = chassisCommon.ProtocolHighway
+
+//Config keys of highway protocol
+const (
+	//KeyServices is the prefix of mapping from destination micro service of request header to
+	//micro service name, like mesher.highway.services.order: order-v2
+	KeyServices = "mesher.highway.services"
+	//KeyLogin decides whether client logins after connected, provider of java chassis
+	//registers endpoint with login=true
+	KeyLogin = "mesher.highway.login"
+	//KeyProtobufMapCodec is the codec of map negotiated by login, consumer and provider must agree on it
+	KeyProtobufMapCodec = "mesher.highway.protobufMapCodec"
+	KeyMaxFrameSize     = "mesher.highway.maxFrameSize"
+)
+
+//Status codes of highway response, they are same as http
+const (
+	StatusOK = 200
+	//StatusConsumerInner is used by java chassis for errors raised in consumer
+	StatusConsumerInner = 490
+	//StatusProducerInner is used by java chassis for errors raised in provider
+	StatusProducerInner = 590
+)
+
+//DefaultMaxFrameSize is the default max length of header and body
+const DefaultMaxFrameSize = 10 * 1024 * 1024
+
+//DefaultTimeout is used when client has no timeout
+const DefaultTimeout = 30 * time.Second
+
+//ErrNoDestination means destination micro service of request header is empty
+var ErrNoDestination = errors.New("destination micro service of highway request is empty")
+
+func init() {
+	resolver.SetDefaultDestinationResolver(Name, &ServiceNameResolver{})
+}
+
+//ServiceNameResolver resolves destination micro service of request header to micro service name,
+//java chassis uses app:service for micro service of other application, app is removed
+type ServiceNameResolver struct {
+}
+
+//Resolve returns the micro service name, host is the destination micro service
+func (r *ServiceNameResolver) Resolve(remoteIP, host, rawURI string, header map[string]string) (string, string, error) {
+	if host == "" {
+		return "", "", ErrNoDestination
+	}
+	if s := archaius.GetString(KeyServices+"."+host, ""); s != "" {
+		return s, "", nil
+	}
+	if i := strings.LastIndex(host, ":"); i != -1 {
+		host = host[i+1:]
+	}
+	return host, "", nil
+}
+
+func maxFrameSize() int {
+	n := archaius.GetInt(KeyMaxFrameSize, DefaultMaxFrameSize)
+	if n <= 0 {
+		openlog.Warn(fmt.Sprintf("invalid %s %d, use default %d", KeyMaxFrameSize, n, DefaultMaxFrameSize))
+		return DefaultMaxFrameSize
+	}
+	return n
+}
+
+//loginBody returns the login of this mesher, it is sent by client and replied by server
+func loginBody() *Login {
+	return &Login{
+		Protocol:            LoginProtocol,
+		UseProtobufMapCodec: archaius.GetBool(KeyProtobufMapCodec, false),
+	}
+}
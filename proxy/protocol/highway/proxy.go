@@ -0,0 +1,154 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package highway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/common"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/accesslog"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/apache/servicecomb-mesher/proxy/util"
+	chassisCommon "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
+	"github.com/go-chassis/go-chassis/v2/pkg/runtime"
+	utiltags "github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/go-chassis/go-chassis/v2/third_party/forked/afex/hystrix-go/hystrix"
+)
+
+//HandleLocal sends a request from local application to the service resolved by destination resolver
+func HandleLocal(remoteAddr string, src *resolver.Source, req *Request) *Response {
+	inv := newInvocation(req)
+	inv.SourceServiceID = runtime.ServiceID
+	inv.SourceMicroService = runtime.ServiceName
+	service, _, err := resolver.GetDestinationResolver(Name).Resolve(src.IP,
+		req.Header.DestMicroservice, "", req.Header.Context)
+	if err != nil {
+		return finish(inv, req, remoteAddr, time.Now(), nil, StatusConsumerInner, err)
+	}
+	inv.MicroServiceName = service
+	return invoke(inv, req, remoteAddr, StatusConsumerInner, chassisCommon.Consumer, common.ChainConsumerOutgoing)
+}
+
+//HandleRemote sends a request from other mesher or java chassis consumer to local service,
+//the port of local service is set by --service-ports, like highway:7070
+func HandleRemote(remoteAddr string, src *resolver.Source, req *Request) *Response {
+	inv := newInvocation(req)
+	inv.MicroServiceName = runtime.ServiceName
+	inv.RouteTags = utiltags.NewDefaultTag(runtime.Version, runtime.App)
+	if si := resolver.ResolveSource(src); si != nil {
+		inv.SourceMicroService = si.Name
+	}
+	if err := util.SetLocalServiceAddress(inv, ""); err != nil {
+		return finish(inv, req, remoteAddr, time.Now(), nil, StatusProducerInner, err)
+	}
+	return invoke(inv, req, remoteAddr, StatusProducerInner, chassisCommon.Provider, common.ChainProviderIncoming)
+}
+
+//newInvocation maps request header to invocation, context of request header becomes headers of invocation
+func newInvocation(req *Request) *invocation.Invocation {
+	headers := make(map[string]string, len(req.Header.Context))
+	for k, v := range req.Header.Context {
+		headers[k] = v
+	}
+	return &invocation.Invocation{
+		Protocol:    Name,
+		SchemaID:    req.Header.SchemaID,
+		OperationID: req.Header.OperationName,
+		Args:        req,
+		Reply:       &Response{},
+		Ctx:         context.WithValue(context.Background(), chassisCommon.ContextHeaderKey{}, headers),
+	}
+}
+
+func invoke(inv *invocation.Invocation, req *Request, remoteAddr string, inner int32,
+	chainType, chainName string) *Response {
+	begin := time.Now()
+	c, err := handler.GetChain(chainType, chainName)
+	if err != nil {
+		return finish(inv, req, remoteAddr, begin, nil, inner, err)
+	}
+	var ir *invocation.Response
+	c.Next(inv, func(r *invocation.Response) {
+		ir = r
+	})
+	if ir == nil {
+		return finish(inv, req, remoteAddr, begin, nil, inner, protocol.ErrUnExpectedHandlerChainResponse)
+	}
+	if ir.Err != nil {
+		return finish(inv, req, remoteAddr, begin, nil, errorStatus(ir, inner), ir.Err)
+	}
+	resp, _ := inv.Reply.(*Response)
+	if resp == nil || resp.Header == nil {
+		return finish(inv, req, remoteAddr, begin, nil, inner, protocol.ErrNilResult)
+	}
+	return finish(inv, req, remoteAddr, begin, resp, 0, nil)
+}
+
+//errorStatus returns status code of an error raised by handler chain, errors like rate limit and
+//fault injection carry their own status, other errors are inner errors of consumer or provider
+func errorStatus(ir *invocation.Response, inner int32) int32 {
+	switch ir.Err.(type) {
+	case loadbalancer.LBError:
+		return StatusConsumerInner
+	case hystrix.CircuitError:
+		return http.StatusServiceUnavailable
+	}
+	if ir.Status >= 400 {
+		return int32(ir.Status)
+	}
+	return inner
+}
+
+//finish records metrics and access log of a request and returns the response,
+//an error is returned to caller with status and error message
+func finish(inv *invocation.Invocation, req *Request, remoteAddr string, begin time.Time,
+	rsp *Response, status int32, err error) *Response {
+	if err != nil {
+		rsp = NewErrorResponse(req, status, err.Error())
+	}
+	rsp.MsgID = req.MsgID
+	code := rsp.Header.StatusCode
+	latency := time.Since(begin)
+	labelValues := map[string]string{
+		metrics.LServiceName: inv.MicroServiceName,
+		metrics.LApp:         inv.RouteTags.AppID(),
+		metrics.LVersion:     inv.RouteTags.Version(),
+		metrics.LInterface:   inv.SchemaID,
+		metrics.LMethod:      inv.OperationID,
+		metrics.LStatus:      strconv.Itoa(int(code)),
+	}
+	metrics.RecordRPCLatency(Name, labelValues, latency.Seconds())
+	metrics.RecordRPCStatus(Name, labelValues, code < 200 || code >= 300)
+	accesslog.Log(&accesslog.Record{
+		Protocol:  Name,
+		Source:    inv.SourceMicroService,
+		Service:   inv.MicroServiceName,
+		Operation: inv.SchemaID + "." + inv.OperationID,
+		Status:    strconv.Itoa(int(code)),
+		Latency:   latency,
+		Tags:      map[string]string{"remote": remoteAddr},
+	})
+	return rsp
+}
@@ -0,0 +1,265 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package highway
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/cmd"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	chassisCommon "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/config"
+	"github.com/go-chassis/go-chassis/v2/core/config/model"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/server"
+	"github.com/stretchr/testify/assert"
+)
+
+//provider works like a java chassis provider, it replies every request with operation name as body
+//and context of request as context of response, except operation "slow",
+//logins it receives are sent to logins and msg IDs to ids
+type provider struct {
+	ln     net.Listener
+	logins chan *Login
+	ids    chan int64
+}
+
+func newProvider(t *testing.T) *provider {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	p := &provider{ln: ln, logins: make(chan *Login, 10), ids: make(chan int64, 100)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+	return p
+}
+
+func (p *provider) serve(conn net.Conn) {
+	defer conn.Close()
+	var wmu sync.Mutex
+	for {
+		f, err := ReadFrame(conn, DefaultMaxFrameSize)
+		if err != nil {
+			return
+		}
+		req, err := DecodeRequest(f)
+		if err != nil {
+			return
+		}
+		rsp := &Response{MsgID: req.MsgID, Header: &ResponseHeader{StatusCode: StatusOK}}
+		if req.Header.MsgType == MsgTypeLogin {
+			l, _ := UnmarshalLogin(req.Body)
+			p.logins <- l
+			rsp.Body = req.Body
+		} else {
+			p.ids <- req.MsgID
+			if req.Header.OperationName == "slow" {
+				continue
+			}
+			rsp.Header.Context = req.Header.Context
+			rsp.Body = []byte(req.Header.OperationName)
+		}
+		wmu.Lock()
+		conn.Write(rsp.Encode())
+		wmu.Unlock()
+	}
+}
+
+func newRequest(operation string) *Request {
+	return &Request{
+		MsgID: 1,
+		Header: &RequestHeader{
+			DestMicroservice: "app:order",
+			SchemaID:         "OrderEndpoint",
+			OperationName:    operation,
+			Context:          map[string]string{"k": "v"},
+		},
+		Body: []byte{0x0a, 1, 'x'},
+	}
+}
+
+func call(c client.ProtocolClient, addr, operation string) (*Response, error) {
+	inv := &invocation.Invocation{
+		Args: newRequest(operation),
+		Ctx:  context.WithValue(context.Background(), chassisCommon.ContextHeaderKey{}, map[string]string{}),
+	}
+	inv.SetHeader("traceId", "1")
+	resp := &Response{}
+	err := c.Call(context.Background(), addr, inv, resp)
+	return resp, err
+}
+
+func TestClient_Call(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	p := newProvider(t)
+	defer p.ln.Close()
+	addr := p.ln.Addr().String()
+	c, err := NewClient(client.Options{Timeout: time.Second})
+	assert.NoError(t, err)
+	defer c.Close()
+
+	//requests with same msg ID share one connection
+	var wg sync.WaitGroup
+	for _, op := range []string{"a", "b", "c", "d"} {
+		wg.Add(1)
+		go func(op string) {
+			defer wg.Done()
+			rsp, err := call(c, addr, op)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), rsp.MsgID)
+			assert.Equal(t, op, string(rsp.Body))
+			assert.Equal(t, map[string]string{"k": "v", "traceId": "1"}, rsp.Header.Context)
+			status, err := c.Status(rsp)
+			assert.NoError(t, err)
+			assert.Equal(t, StatusOK, status)
+		}(op)
+	}
+	wg.Wait()
+	seen := map[int64]bool{}
+	for i := 0; i < 4; i++ {
+		seen[<-p.ids] = true
+	}
+	assert.Equal(t, 4, len(seen))
+	assert.Equal(t, 0, len(p.logins))
+
+	_, err = call(c, addr, "slow")
+	assert.Equal(t, ErrTimeout, err)
+
+	err = c.Call(context.Background(), addr, &invocation.Invocation{}, &Response{})
+	assert.Equal(t, protocol.ErrInvalidReq, err)
+	err = c.Call(context.Background(), addr, &invocation.Invocation{Args: newRequest("a")}, nil)
+	assert.Equal(t, protocol.ErrInvalidResp, err)
+}
+
+func TestClient_Login(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	archaius.Set(KeyLogin, true)
+	archaius.Set(KeyProtobufMapCodec, true)
+	defer archaius.Delete(KeyLogin)
+	defer archaius.Delete(KeyProtobufMapCodec)
+	p := newProvider(t)
+	defer p.ln.Close()
+	c, err := NewClient(client.Options{Timeout: time.Second})
+	assert.NoError(t, err)
+	defer c.Close()
+
+	rsp, err := call(c, p.ln.Addr().String(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(rsp.Body))
+	assert.Equal(t, &Login{Protocol: LoginProtocol, UseProtobufMapCodec: true}, <-p.logins)
+	//login once for a connection
+	_, err = call(c, p.ln.Addr().String(), "b")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(p.logins))
+}
+
+func TestServiceNameResolver(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	r := &ServiceNameResolver{}
+	_, _, err := r.Resolve("", "", "", nil)
+	assert.Equal(t, ErrNoDestination, err)
+
+	archaius.Set(KeyServices+".order", "order-v2")
+	defer archaius.Delete(KeyServices + ".order")
+	s, _, err := r.Resolve("", "order", "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "order-v2", s)
+	s, _, err = r.Resolve("", "app:user", "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "user", s)
+}
+
+func TestServer_HandleRemote(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	config.GlobalDefinition = &model.GlobalCfg{}
+	p := newProvider(t)
+	defer p.ln.Close()
+	cmd.Configs = &cmd.ConfigFromCmd{PortsMap: map[string]string{Name: p.ln.Addr().String()}}
+	assert.NoError(t, handler.CreateChains(chassisCommon.Provider, map[string]string{
+		"incoming": handler.Transport,
+	}))
+
+	s := newServer(server.Options{Address: "127.0.0.1:0"}).(*highwayServer)
+	s.maxFrame = DefaultMaxFrameSize
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			s.serve(conn, HandleRemote)
+		}
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+	//java consumer logins first, mesher answers it
+	login := &Request{MsgID: 1, Header: &RequestHeader{MsgType: MsgTypeLogin},
+		Body: (&Login{Protocol: LoginProtocol, UseProtobufMapCodec: true}).Marshal()}
+	_, err = conn.Write(login.Encode())
+	assert.NoError(t, err)
+	rsp := readResponse(t, conn)
+	assert.Equal(t, int64(1), rsp.MsgID)
+	assert.Equal(t, int32(StatusOK), rsp.Header.StatusCode)
+	l, err := UnmarshalLogin(rsp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, &Login{Protocol: LoginProtocol}, l)
+
+	req := newRequest("create")
+	req.MsgID = 9
+	_, err = conn.Write(req.Encode())
+	assert.NoError(t, err)
+	rsp = readResponse(t, conn)
+	assert.Equal(t, int64(9), rsp.MsgID)
+	assert.Equal(t, int32(StatusOK), rsp.Header.StatusCode)
+	assert.Equal(t, "create", string(rsp.Body))
+	assert.Equal(t, "v", rsp.Header.Context["k"])
+	assert.Equal(t, 0, len(p.logins))
+}
+
+func TestHandleLocal_NoDestination(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	req := newRequest("a")
+	req.Header.DestMicroservice = ""
+	rsp := HandleLocal("127.0.0.1:5000", &resolver.Source{IP: "127.0.0.1"}, req)
+	assert.Equal(t, req.MsgID, rsp.MsgID)
+	assert.Equal(t, int32(StatusConsumerInner), rsp.Header.StatusCode)
+	assert.Equal(t, ErrNoDestination.Error(), rsp.Header.ReasonPhrase)
+}
+
+func readResponse(t *testing.T, conn net.Conn) *Response {
+	f, err := ReadFrame(conn, DefaultMaxFrameSize)
+	assert.NoError(t, err)
+	rsp, err := DecodeResponse(f)
+	assert.NoError(t, err)
+	return rsp
+}
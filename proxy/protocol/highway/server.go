@@ -0,0 +1,152 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package highway
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/apache/servicecomb-mesher/proxy/common"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/runtime"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	chassisCom "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/server"
+	chassisTLS "github.com/go-chassis/go-chassis/v2/core/tls"
+	chassisRuntime "github.com/go-chassis/go-chassis/v2/pkg/runtime"
+	"github.com/go-chassis/openlog"
+)
+
+func init() {
+	server.InstallPlugin(Name, newServer)
+}
+
+func newServer(opts server.Options) server.ProtocolServer {
+	return &highwayServer{
+		ConnServer: protocol.ConnServer{Protocol: Name},
+		opts:       opts,
+	}
+}
+
+//handleFunc handles a request and returns the response
+type handleFunc func(remoteAddr string, src *resolver.Source, req *Request) *Response
+
+type highwayServer struct {
+	protocol.ConnServer
+	opts     server.Options
+	maxFrame int
+}
+
+func (s *highwayServer) Register(schema interface{}, options ...server.RegisterOption) (string, error) {
+	return "", nil
+}
+
+//Start listens like http, in sidecar mode requests from local application come to 127.0.0.1 and
+//requests from other meshers come to external ip, in other mode all requests are sent to remote services
+func (s *highwayServer) Start() error {
+	s.maxFrame = maxFrameSize()
+	host, port, err := net.SplitHostPort(s.opts.Address)
+	if err != nil {
+		return err
+	}
+	if runtime.Role != common.RoleSidecar {
+		return s.listen(s.opts.Address, nil, HandleLocal)
+	}
+	if err := s.listen("127.0.0.1:"+port, nil, HandleLocal); err != nil {
+		return err
+	}
+	switch host {
+	case "0.0.0.0":
+		return errors.New("in sidecar mode, forbidden to listen on 0.0.0.0")
+	case "127.0.0.1":
+		openlog.Warn("highway listen on 127.0.0.1, it can only proxy for consumer. " +
+			"for provider, mesher must listen on external ip.")
+		return nil
+	}
+	tlsConfig, sslConfig, err := chassisTLS.GetTLSConfigByService(
+		chassisRuntime.ServiceName, Name, chassisCom.Provider)
+	if err != nil {
+		if !chassisTLS.IsSSLConfigNotExist(err) {
+			return err
+		}
+	} else {
+		openlog.Warn(fmt.Sprintf("%s.%s.%s TLS mode, verify peer: %t, cipher plugin: %s.",
+			chassisRuntime.ServiceName, Name, chassisCom.Provider, sslConfig.VerifyPeer, sslConfig.CipherPlugin))
+	}
+	return s.listen(s.opts.Address, tlsConfig, HandleRemote)
+}
+
+func (s *highwayServer) listen(addr string, t *tls.Config, h handleFunc) error {
+	return s.Listen(addr, t, func(conn net.Conn, listener string) {
+		s.serve(conn, h)
+	})
+}
+
+//serve reads requests of a connection and handles them concurrently, responses are written in the order they finish,
+//login is answered by mesher itself
+func (s *highwayServer) serve(conn net.Conn, h handleFunc) {
+	defer conn.Close()
+	var wmu sync.Mutex
+	write := func(rsp *Response) {
+		wmu.Lock()
+		defer wmu.Unlock()
+		if _, err := conn.Write(rsp.Encode()); err != nil {
+			openlog.Warn(fmt.Sprintf("write highway response to %s failed: %s", conn.RemoteAddr(), err))
+		}
+	}
+	remoteAddr := conn.RemoteAddr().String()
+	src := resolver.ConnSource(conn)
+	for {
+		f, err := ReadFrame(conn, s.maxFrame)
+		if err != nil {
+			if err != io.EOF {
+				openlog.Warn(fmt.Sprintf("read highway frame from %s failed: %s", remoteAddr, err))
+			}
+			return
+		}
+		req, err := DecodeRequest(f)
+		if err != nil {
+			openlog.Warn(fmt.Sprintf("decode highway request from %s failed: %s", remoteAddr, err))
+			return
+		}
+		if req.Header.MsgType == MsgTypeLogin {
+			write(onLogin(req))
+			continue
+		}
+		go func() {
+			write(h(remoteAddr, src, req))
+		}()
+	}
+}
+
+//onLogin replies the login of this mesher, so that consumer uses the same map codec as upstream connections
+func onLogin(req *Request) *Response {
+	if l, err := UnmarshalLogin(req.Body); err == nil {
+		openlog.Debug(fmt.Sprintf("highway login, protocol: %s, zip: %s, protobuf map codec: %t",
+			l.Protocol, l.ZipName, l.UseProtobufMapCodec))
+	}
+	return &Response{
+		MsgID:  req.MsgID,
+		Header: &ResponseHeader{StatusCode: StatusOK},
+		Body:   loginBody().Marshal(),
+	}
+}
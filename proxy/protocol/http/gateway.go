@@ -19,15 +19,23 @@ package http
 
 import (
 	"github.com/apache/servicecomb-mesher/proxy/common"
+	"github.com/apache/servicecomb-mesher/proxy/config"
 	"github.com/apache/servicecomb-mesher/proxy/ingress"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/accesslog"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
 	"github.com/go-chassis/go-chassis/v2/client/rest"
 	chassiscommon "github.com/go-chassis/go-chassis/v2/core/common"
 	"github.com/go-chassis/go-chassis/v2/core/handler"
 	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	utiltags "github.com/go-chassis/go-chassis/v2/pkg/util/tags"
 	"github.com/go-chassis/openlog"
 	"net/http"
+	"strconv"
+	"time"
 )
 
+var ingressLimiter = ingress.NewRateLimiter()
+
 func handleIncomingTraffic(inv *invocation.Invocation) (*invocation.Response, error) {
 	c, err := handler.GetChain(chassiscommon.Provider, common.ChainProviderIncoming)
 	if err != nil {
@@ -42,8 +50,11 @@ func handleIncomingTraffic(inv *invocation.Invocation) (*invocation.Response, er
 }
 
 //HandleIngressTraffic is api gateway http handler
-func HandleIngressTraffic(w http.ResponseWriter, r *http.Request) {
+func HandleIngressTraffic(rw http.ResponseWriter, r *http.Request) {
+	w := &statusWriter{ResponseWriter: rw, status: http.StatusOK}
 	inv := &invocation.Invocation{}
+	defer logIngressAccess(inv, r, w, time.Now())
+	defer func() { ingress.RecordConsumer("http", inv, strconv.Itoa(w.status)) }()
 	inv.Reply = rest.NewResponse()
 	inv.Protocol = "rest"
 	inv.Args = r
@@ -62,24 +73,43 @@ func HandleIngressTraffic(w http.ResponseWriter, r *http.Request) {
 			handleErrorResponse(inv, w, invResp.Status, invResp.Err)
 			return
 		}
+		//handlers like oidc set cookies in response
+		if header, ok := invResp.Result.(http.Header); ok {
+			copyHeader(w.Header(), header)
+		}
+	}
+	rule, err := ingress.DefaultFetcher.Fetch("http", r.Method, r.Host, r.URL.Path, r.URL.Query(), r.Header)
+	if err == ingress.ErrNotMatch {
+		handleErrorResponse(inv, w, http.StatusNotFound, err)
+		return
 	}
-	rule, err := ingress.DefaultFetcher.Fetch("http", r.Host, r.URL.Path, r.Header)
 	if err != nil {
 		handleErrorResponse(inv, w, http.StatusInternalServerError, err)
 		return
 	}
-	inv.MicroServiceName = rule.Service.Name
-	targetAPI := r.URL.Path
-	if rule.Service.RedirectPath != "" {
-		targetAPI = rule.Service.RedirectPath
+	consumer := ingress.Consumer(inv.Ctx)
+	if q := ingressLimiter.Allow(rule, ingress.LimitKey(rule, r, consumer)); q != nil {
+		ingress.SetQuotaHeaders(w.Header(), q)
+		if !q.Allowed {
+			metrics.RecordRateLimited("http", map[string]string{metrics.LHost: rule.Host, metrics.LAPIPath: rule.APIPath,
+				metrics.LConsumer: consumer})
+			handleErrorResponse(inv, w, http.StatusTooManyRequests, ingress.ErrRateLimited)
+			return
+		}
+	}
+	service := selectService(w, r, rule)
+	inv.MicroServiceName = service.Name
+	if len(service.Tags) != 0 {
+		inv.RouteTags = utiltags.Tags{KV: service.Tags, Label: utiltags.LabelOfTags(service.Tags)}
 	}
-	newReq, err := http.NewRequest(r.Method, "http://"+inv.MicroServiceName+targetAPI, r.Body)
+	newReq, err := http.NewRequest(r.Method, "http://"+inv.MicroServiceName, r.Body)
 	if err != nil {
 		handleErrorResponse(inv, w, http.StatusInternalServerError, err)
 		return
 	}
+	newReq.URL.Path, newReq.URL.RawQuery = ingress.Rewrite(rule, service, r.URL.Path, r.URL.RawQuery)
 	inv.Args = newReq
-	h[XForwardedPort] = rule.Service.Port.Value
+	h[XForwardedPort] = service.Port.Value
 	c, err := handler.GetChain(chassiscommon.Consumer, common.ChainConsumerOutgoing)
 	if err != nil {
 		handleErrorResponse(inv, w, http.StatusBadGateway, err)
@@ -98,3 +128,57 @@ func HandleIngressTraffic(w http.ResponseWriter, r *http.Request) {
 	}
 	RecordStatus(inv, resp.StatusCode)
 }
+
+//selectService selects the service of rule, the sticky key is read from header or cookie,
+//if sticky cookie is absent, the selected service is saved in it
+func selectService(w http.ResponseWriter, r *http.Request, rule *config.IngressRule) *config.Service {
+	if len(rule.Services) == 0 {
+		return &rule.Service
+	}
+	if rule.Sticky.Header != "" {
+		return ingress.SelectService(rule, r.Header.Get(rule.Sticky.Header))
+	}
+	if rule.Sticky.Cookie != "" {
+		if c, err := r.Cookie(rule.Sticky.Cookie); err == nil && c.Value != "" {
+			return ingress.SelectService(rule, c.Value)
+		}
+		s := ingress.SelectService(rule, "")
+		http.SetCookie(w, &http.Cookie{Name: rule.Sticky.Cookie, Value: s.Name, Path: "/", HttpOnly: true})
+		return s
+	}
+	return ingress.SelectService(rule, "")
+}
+
+func logIngressAccess(inv *invocation.Invocation, r *http.Request, w *statusWriter, begin time.Time) {
+	if !accesslog.Enabled() {
+		return
+	}
+	accesslog.Log(&accesslog.Record{
+		Protocol:  "http",
+		Source:    r.RemoteAddr,
+		Service:   inv.MicroServiceName,
+		Operation: r.Method + " " + r.URL.Path,
+		Status:    strconv.Itoa(w.status),
+		Latency:   time.Since(begin),
+		Tags:      ingress.AccessLogTags(inv, r.Host),
+	})
+}
+
+//statusWriter remembers the status code written to client
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+//WriteHeader records status code
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+//Flush flushes data to client if underlying writer supports it
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -1,6 +1,11 @@
 package http
 
 import (
+	mesherconfig "github.com/apache/servicecomb-mesher/proxy/config"
+	mhandler "github.com/apache/servicecomb-mesher/proxy/handler"
+	_ "github.com/apache/servicecomb-mesher/proxy/handler/apikey"
+	_ "github.com/apache/servicecomb-mesher/proxy/handler/jwt"
+	_ "github.com/apache/servicecomb-mesher/proxy/handler/oidc"
 	"github.com/apache/servicecomb-mesher/proxy/ingress"
 	_ "github.com/apache/servicecomb-mesher/proxy/ingress/servicecomb"
 	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
@@ -16,6 +21,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestHandleIngressTraffic(t *testing.T) {
@@ -68,3 +74,213 @@ func TestHandleIngressTraffic(t *testing.T) {
 	w := httptest.NewRecorder()
 	HandleIngressTraffic(w, req)
 }
+
+func TestSelectService(t *testing.T) {
+	rule := &mesherconfig.IngressRule{
+		Services: []mesherconfig.Service{
+			{Name: "legacy-orders", Weight: 1},
+			{Name: "orders-v2", Weight: 1},
+		},
+		Sticky: mesherconfig.Sticky{Cookie: "backend"},
+	}
+	req, _ := http.NewRequest(http.MethodGet, "http://foo.com/orders", nil)
+	w := httptest.NewRecorder()
+	s := selectService(w, req, rule)
+	cookies := w.Result().Cookies()
+	assert.Equal(t, 1, len(cookies))
+	assert.Equal(t, s.Name, cookies[0].Value)
+
+	for _, name := range []string{"legacy-orders", "orders-v2"} {
+		req.Header.Set("Cookie", "backend="+name)
+		w = httptest.NewRecorder()
+		assert.Equal(t, name, selectService(w, req, rule).Name)
+		assert.Empty(t, w.Result().Cookies())
+	}
+
+	rule.Sticky = mesherconfig.Sticky{Header: "X-User"}
+	req.Header.Set("X-User", "jason")
+	s = selectService(w, req, rule)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, s, selectService(w, req, rule))
+	}
+}
+
+func TestHandleIngressTraffic_RateLimit(t *testing.T) {
+	assert.NoError(t, metrics.Init())
+	handler.CreateChains(common.Provider, map[string]string{"incoming": ""})
+	handler.CreateChains(common.Consumer, map[string]string{"outgoing": ""})
+	archaius.Init(archaius.WithMemorySource())
+	config.GlobalDefinition = new(model.GlobalCfg)
+	config.HystrixConfig = &model.HystrixConfigWrapper{}
+	assert.NoError(t, control.Init(control.Options{}))
+	assert.NoError(t, archaius.Set("mesher.ingress.rule.http", `
+- host: limit.com
+  apiPath: /api
+  limit: 1
+  rateLimit:
+    key: header
+    header: X-User
+  service:
+    name: limited
+`))
+	assert.NoError(t, ingress.Init())
+	ingressLimiter = ingress.NewRateLimiter()
+
+	send := func(user string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "http://limit.com/api", nil)
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		HandleIngressTraffic(w, req)
+		return w
+	}
+	w := send("jason")
+	assert.NotEqual(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	w = send("jason")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Reset"))
+	assert.NotEqual(t, http.StatusTooManyRequests, send("tom").Code)
+
+	t.Run("live update", func(t *testing.T) {
+		assert.NoError(t, archaius.Set("mesher.ingress.rule.http", `
+- host: limit.com
+  apiPath: /api
+  limit: 1000
+  rateLimit:
+    key: header
+    header: X-User
+  service:
+    name: limited
+`))
+		assert.Eventually(t, func() bool {
+			return send("jason").Header().Get("X-RateLimit-Limit") == "1000"
+		}, 3*time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestHandleIngressTraffic_JWT(t *testing.T) {
+	assert.NoError(t, metrics.Init())
+	archaius.Init(archaius.WithMemorySource())
+	config.GlobalDefinition = new(model.GlobalCfg)
+	config.HystrixConfig = &model.HystrixConfigWrapper{}
+	assert.NoError(t, control.Init(control.Options{}))
+	mesherconfig.SetConfig(&mesherconfig.MesherConfig{Mesher: mesherconfig.Mesher{
+		JWT: mesherconfig.JWT{Secret: "secret", Realm: "shop"},
+	}})
+	defer mesherconfig.SetConfig(&mesherconfig.MesherConfig{})
+	assert.NoError(t, handler.CreateChains(common.Provider, map[string]string{"incoming": "jwt"}))
+	assert.NoError(t, mhandler.BuildConfigured())
+	defer handler.CreateChains(common.Provider, map[string]string{"incoming": ""})
+	handler.CreateChains(common.Consumer, map[string]string{"outgoing": ""})
+	assert.NoError(t, archaius.Set("mesher.ingress.rule.http", `
+- apiPath: /api
+  service:
+    name: api
+`))
+	assert.NoError(t, ingress.Init())
+
+	req, _ := http.NewRequest(http.MethodGet, "http://shop.com/api", nil)
+	w := httptest.NewRecorder()
+	HandleIngressTraffic(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `Bearer realm="shop"`, w.Header().Get("WWW-Authenticate"))
+
+	req.Header.Set("Authorization", "Bearer a.b.c")
+	w = httptest.NewRecorder()
+	HandleIngressTraffic(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+}
+
+func TestHandleIngressTraffic_OIDC(t *testing.T) {
+	assert.NoError(t, metrics.Init())
+	archaius.Init(archaius.WithMemorySource())
+	config.GlobalDefinition = new(model.GlobalCfg)
+	config.HystrixConfig = &model.HystrixConfigWrapper{}
+	assert.NoError(t, control.Init(control.Options{}))
+	mesherconfig.SetConfig(&mesherconfig.MesherConfig{Mesher: mesherconfig.Mesher{
+		OIDC: mesherconfig.OIDC{
+			Issuer:      "https://auth.com",
+			ClientID:    "shop",
+			RedirectURL: "https://shop.com/oauth2/callback",
+			AuthURL:     "https://auth.com/authorize",
+			TokenURL:    "https://auth.com/token",
+			JWKS:        "https://auth.com/jwks",
+			Cookie:      mesherconfig.OIDCCookie{Secret: "secret"},
+		},
+	}})
+	defer mesherconfig.SetConfig(&mesherconfig.MesherConfig{})
+	assert.NoError(t, handler.CreateChains(common.Provider, map[string]string{"incoming": "oidc"}))
+	assert.NoError(t, mhandler.BuildConfigured())
+	defer handler.CreateChains(common.Provider, map[string]string{"incoming": ""})
+	handler.CreateChains(common.Consumer, map[string]string{"outgoing": ""})
+	assert.NoError(t, archaius.Set("mesher.ingress.rule.http", `
+- apiPath: /
+  service:
+    name: web
+`))
+	assert.NoError(t, ingress.Init())
+
+	req, _ := http.NewRequest(http.MethodGet, "https://shop.com/orders", nil)
+	w := httptest.NewRecorder()
+	HandleIngressTraffic(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req.Header.Set("Accept", "text/html")
+	w = httptest.NewRecorder()
+	HandleIngressTraffic(w, req)
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Contains(t, w.Header().Get("Location"), "https://auth.com/authorize?")
+	assert.Contains(t, w.Header().Get("Set-Cookie"), "mesher_session_state_")
+}
+
+func TestHandleIngressTraffic_APIKey(t *testing.T) {
+	assert.NoError(t, metrics.Init())
+	archaius.Init(archaius.WithMemorySource())
+	config.GlobalDefinition = new(model.GlobalCfg)
+	config.HystrixConfig = &model.HystrixConfigWrapper{}
+	assert.NoError(t, control.Init(control.Options{}))
+	assert.NoError(t, archaius.Set("mesher.apikey.keys", `
+- id: k1
+  key: secret1
+  consumer: shop
+- id: k2
+  key: secret2
+  consumer: shop
+- id: k3
+  key: secret3
+  consumer: blog
+`))
+	assert.NoError(t, handler.CreateChains(common.Provider, map[string]string{"incoming": "apikey"}))
+	assert.NoError(t, mhandler.BuildConfigured())
+	defer handler.CreateChains(common.Provider, map[string]string{"incoming": ""})
+	handler.CreateChains(common.Consumer, map[string]string{"outgoing": ""})
+	assert.NoError(t, archaius.Set("mesher.ingress.rule.http", `
+- apiPath: /api
+  limit: 1
+  rateLimit:
+    key: consumer
+  service:
+    name: api
+`))
+	assert.NoError(t, ingress.Init())
+	ingressLimiter = ingress.NewRateLimiter()
+
+	send := func(key string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "http://shop.com/api", nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		HandleIngressTraffic(w, req)
+		return w
+	}
+	assert.Equal(t, http.StatusUnauthorized, send("").Code)
+	assert.NotEqual(t, http.StatusTooManyRequests, send("secret1").Code)
+	//keys of a consumer share the limit
+	assert.Equal(t, http.StatusTooManyRequests, send("secret2").Code)
+	assert.NotEqual(t, http.StatusTooManyRequests, send("secret3").Code)
+}
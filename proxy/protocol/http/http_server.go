@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"github.com/apache/servicecomb-mesher/proxy/ingress"
 	"github.com/apache/servicecomb-mesher/proxy/pkg/runtime"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/sniff"
 	"net"
 	"net/http"
 	"strings"
@@ -134,8 +135,10 @@ func (hs *httpServer) startSidecar(host, port string) error {
 }
 
 func (hs *httpServer) startCommonProxy() error {
-	if err := ingress.Init(); err != nil {
-		return err
+	if ingress.DefaultFetcher == nil {
+		if err := ingress.Init(); err != nil {
+			return err
+		}
 	}
 	mesherTLSConfig, mesherSSLConfig, err := chassisTLS.GetTLSConfigByService(
 		chassisRuntime.ServiceName, "rest", chassisCom.Provider)
@@ -156,7 +159,7 @@ func (hs *httpServer) startCommonProxy() error {
 }
 
 func (hs *httpServer) listenAndServe(addr string, t *tls.Config, h http.Handler) error {
-	ln, err := net.Listen("tcp4", addr)
+	ln, err := sniff.Listen("tcp4", addr, Name)
 	if err != nil {
 		return err
 	}
@@ -47,9 +47,6 @@ import (
 	"github.com/go-chassis/openlog"
 )
 
-var dr = resolver.GetDestinationResolver("http")
-var sr = resolver.GetSourceResolver()
-
 //constants for headers
 const (
 	XForwardedPort = "X-Forwarded-Port"
@@ -116,11 +113,24 @@ func LocalRequestHandler(w http.ResponseWriter, r *http.Request) {
 		h[k] = r.Header.Get(k)
 	}
 	//Resolve Destination
+	dr := resolver.GetDestinationResolver("http")
 	destination, port, err := dr.Resolve(remoteIP, r.Host, r.URL.String(), h)
 	if err != nil {
 		handleErrorResponse(inv, w, http.StatusBadRequest, err)
 		return
 	}
+	if pr, ok := dr.(resolver.PathRewriter); ok {
+		if p, ok := pr.RewritePath(r.URL.String()); ok {
+			r.URL.Path = p
+			r.URL.RawPath = ""
+			inv.URLPath = p
+		}
+	}
+	if tr, ok := dr.(resolver.TagsResolver); ok {
+		if tags, ok := tr.ResolveTags(remoteIP, r.Host, r.URL.String(), h); ok {
+			inv.RouteTags = tags
+		}
+	}
 	inv.MicroServiceName = destination
 	if port != "" {
 		h[XForwardedPort] = port
@@ -178,18 +188,21 @@ func RemoteRequestHandler(w http.ResponseWriter, r *http.Request) {
 	prepareRequest(r)
 	inv := providerPreHandler(r)
 
+	h := make(map[string]string)
+	for k := range r.Header {
+		h[k] = r.Header.Get(k)
+	}
 	if inv.SourceMicroService == "" {
-		source := stringutil.SplitFirstSep(r.RemoteAddr, ":")
 		//Resolve Source
-		si := sr.Resolve(source)
+		si := resolver.ResolveSource(&resolver.Source{
+			IP:     stringutil.SplitFirstSep(r.RemoteAddr, ":"),
+			Header: h,
+			TLS:    r.TLS,
+		})
 		if si != nil {
 			inv.SourceMicroService = si.Name
 		}
 	}
-	h := make(map[string]string)
-	for k := range r.Header {
-		h[k] = r.Header.Get(k)
-	}
 	//transfer header into ctx
 	inv.Ctx = context.WithValue(inv.Ctx, chassisCommon.ContextHeaderKey{}, h)
 	c, err := handler.GetChain(chassisCommon.Provider, common.ChainProviderIncoming)
@@ -246,6 +259,8 @@ func handleRequest(w http.ResponseWriter, inv *invocation.Invocation, ir *invoca
 				handleErrorResponse(inv, w, http.StatusServiceUnavailable, ir.Err)
 			case fault.Fault:
 				handleErrorResponse(inv, w, ir.Status, ir.Err)
+			case *protocol.HTTPError:
+				handleErrorResponse(inv, w, ir.Status, ir.Err)
 			default: //for other error, check response and response body, if there is body, just transparent response
 				resp, ok := inv.Reply.(*http.Response)
 
@@ -299,6 +314,9 @@ func handleRequest(w http.ResponseWriter, inv *invocation.Invocation, ir *invoca
 
 //handleErrorResponse return proxy errors, not err from real service
 func handleErrorResponse(inv *invocation.Invocation, w http.ResponseWriter, statusCode int, err error) {
+	if e, ok := err.(*protocol.HTTPError); ok {
+		copyHeader(w.Header(), e.Header)
+	}
 	w.WriteHeader(statusCode)
 	if err != nil {
 		_, err := w.Write([]byte(err.Error()))
@@ -19,6 +19,7 @@ package http
 
 import (
 	"bytes"
+	"errors"
 	"github.com/apache/servicecomb-mesher/proxy/cmd"
 	"github.com/go-chassis/go-chassis/v2/client/rest"
 	"github.com/go-chassis/go-chassis/v2/core/common"
@@ -29,8 +30,14 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/egress"
 	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/apache/servicecomb-mesher/proxy/resolver/pathprefix"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
 	"github.com/go-chassis/go-chassis/v2/core/lager"
+	"github.com/stretchr/testify/assert"
 )
 
 func init() {
@@ -42,6 +49,49 @@ func init() {
 
 	metrics.Init()
 
+	handler.RegisterHandler("capture", func() handler.Handler { return &captureHandler{} })
+}
+
+//captureHandler keeps the last invocation and stops the chain
+type captureHandler struct{}
+
+var captured *invocation.Invocation
+
+func (h *captureHandler) Handle(chain *handler.Chain, inv *invocation.Invocation, cb invocation.ResponseCallBack) {
+	captured = inv
+	cb(&invocation.Response{Status: http.StatusOK, Err: errors.New("captured")})
+}
+
+func (h *captureHandler) Name() string {
+	return "capture"
+}
+
+//noEgress has no egress rule
+type noEgress struct{}
+
+func (e *noEgress) Init(egress.Options) error                        { return nil }
+func (e *noEgress) SetEgressRule(map[string][]*config.EgressRule)    {}
+func (e *noEgress) FetchEgressRule() map[string][]*config.EgressRule { return nil }
+
+func TestLocalRequestHandler_PathResolver(t *testing.T) {
+	if egress.DefaultEgress == nil {
+		egress.DefaultEgress = &noEgress{}
+		defer func() { egress.DefaultEgress = nil }()
+	}
+	resolver.SetDefaultDestinationResolver("http", pathprefix.New())
+	defer resolver.SetDefaultDestinationResolver("http", &resolver.DefaultDestinationResolver{})
+	assert.NoError(t, handler.CreateChains(common.Consumer, map[string]string{"outgoing": "capture"}))
+	svr := httptest.NewServer(http.HandlerFunc(LocalRequestHandler))
+	defer svr.Close()
+
+	rsp, err := http.Get(svr.URL + "/orders/api/v1?id=1")
+	assert.NoError(t, err)
+	rsp.Body.Close()
+	assert.Equal(t, "orders", captured.MicroServiceName)
+	assert.Equal(t, "/api/v1", captured.URLPath)
+	req := captured.Args.(*http.Request)
+	assert.Equal(t, "/api/v1", req.URL.Path)
+	assert.Equal(t, "id=1", req.URL.RawQuery)
 }
 
 func TestLocalRequestHandler(t *testing.T) {
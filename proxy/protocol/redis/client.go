@@ -0,0 +1,163 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+)
+
+//DefaultTimeout is used when client has no timeout
+const DefaultTimeout = 30 * time.Second
+
+func init() {
+	client.InstallPlugin(Name, NewClient)
+}
+
+//Response holds the reply of a command
+type Response struct {
+	Value *Value
+}
+
+//Client is a redis client, it keeps idle connections to an endpoint up to pool size,
+//a connection sends one command and reads its reply at a time
+type Client struct {
+	opts client.Options
+	idle chan *conn
+}
+
+type conn struct {
+	net.Conn
+	r *Reader
+}
+
+//NewClient return a new client of redis
+func NewClient(opts client.Options) (client.ProtocolClient, error) {
+	size := opts.PoolSize
+	if size <= 0 {
+		size = client.DefaultPoolSize
+	}
+	return &Client{
+		opts: opts,
+		idle: make(chan *conn, size),
+	}, nil
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.opts.Timeout > 0 {
+		return c.opts.Timeout
+	}
+	return DefaultTimeout
+}
+
+func (c *Client) get(addr string) (*conn, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+	nc, err := net.DialTimeout("tcp", addr, c.timeout())
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.TLSConfig != nil {
+		tlsConn := tls.Client(nc, c.opts.TLSConfig)
+		tlsConn.SetDeadline(time.Now().Add(c.timeout()))
+		if err := tlsConn.Handshake(); err != nil {
+			nc.Close()
+			return nil, err
+		}
+		nc = tlsConn
+	}
+	return &conn{Conn: nc, r: NewReader(nc, maxBulkSize())}, nil
+}
+
+func (c *Client) put(cn *conn) {
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+}
+
+//Call sends the command in args of invocation to addr and reads the reply
+func (c *Client) Call(ctx context.Context, addr string, inv *invocation.Invocation, rsp interface{}) error {
+	cmd, ok := inv.Args.(*Command)
+	if !ok {
+		return protocol.ErrInvalidReq
+	}
+	resp, ok := rsp.(*Response)
+	if !ok {
+		return protocol.ErrInvalidResp
+	}
+	cn, err := c.get(addr)
+	if err != nil {
+		return err
+	}
+	cn.SetDeadline(time.Now().Add(c.timeout()))
+	if _, err := cn.Write(cmd.Append(nil)); err != nil {
+		cn.Close()
+		return err
+	}
+	v, err := cn.r.ReadValue()
+	if err != nil {
+		cn.Close()
+		return err
+	}
+	c.put(cn)
+	resp.Value = v
+	return nil
+}
+
+//Status returns 0, redis has no status
+func (c *Client) Status(rsp interface{}) (status int, err error) {
+	return 0, nil
+}
+
+//String return name
+func (c *Client) String() string {
+	return Name
+}
+
+//Close closes idle connections
+func (c *Client) Close() error {
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+//ReloadConfigs reload config
+func (c *Client) ReloadConfigs(opts client.Options) {
+	c.opts = client.EqualOpts(c.opts, opts)
+}
+
+//GetOptions return opts
+func (c *Client) GetOptions() client.Options {
+	return c.opts
+}
@@ -0,0 +1,167 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/common"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/accesslog"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/apache/servicecomb-mesher/proxy/util"
+	"github.com/go-chassis/go-archaius"
+	chassisCommon "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/pkg/runtime"
+	utiltags "github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/go-chassis/openlog"
+)
+
+//Status labels of redis metrics
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+//ErrNoDestination means target redis service is not set
+var ErrNoDestination = errors.New(KeyDestination + " is not set")
+
+//HandleLocal sends a command from local application to the redis service of mesher.redis.destination,
+//if key hash is enabled, the instance is picked by the first key of command
+func HandleLocal(remoteAddr string, src *resolver.Source, cmd *Command) *Value {
+	inv := newInvocation(cmd)
+	inv.SourceServiceID = runtime.ServiceID
+	inv.SourceMicroService = runtime.ServiceName
+	inv.MicroServiceName = archaius.GetString(KeyDestination, "")
+	if inv.MicroServiceName == "" {
+		return finish(inv, cmd, remoteAddr, time.Now(), nil, ErrNoDestination)
+	}
+	if archaius.GetBool(KeyKeyHash, false) {
+		if key := Key(cmd); key != "" {
+			inv.Strategy = StrategyKeyHash
+			inv.Metadata = map[string]interface{}{MetaKey: key}
+		}
+	}
+	return invoke(inv, cmd, remoteAddr, chassisCommon.Consumer, common.ChainConsumerOutgoing)
+}
+
+//HandleRemote sends a command from other mesher to local redis, the port of local redis is
+//set by --service-ports, like redis:6379
+func HandleRemote(remoteAddr string, src *resolver.Source, cmd *Command) *Value {
+	inv := newInvocation(cmd)
+	inv.MicroServiceName = runtime.ServiceName
+	inv.RouteTags = utiltags.NewDefaultTag(runtime.Version, runtime.App)
+	if si := resolver.ResolveSource(src); si != nil {
+		inv.SourceMicroService = si.Name
+	}
+	if err := util.SetLocalServiceAddress(inv, ""); err != nil {
+		openlog.Error(err.Error())
+		return finish(inv, cmd, remoteAddr, time.Now(), nil, err)
+	}
+	return invoke(inv, cmd, remoteAddr, chassisCommon.Provider, common.ChainProviderIncoming)
+}
+
+//newInvocation uses command name as operation, so that rate limit can be set on a command
+func newInvocation(cmd *Command) *invocation.Invocation {
+	return &invocation.Invocation{
+		Protocol:    Name,
+		OperationID: cmd.Name(),
+		Args:        cmd,
+		Reply:       &Response{},
+		Ctx:         context.WithValue(context.Background(), chassisCommon.ContextHeaderKey{}, map[string]string{}),
+	}
+}
+
+func invoke(inv *invocation.Invocation, cmd *Command, remoteAddr, chainType, chainName string) *Value {
+	begin := time.Now()
+	if err := CheckCommand(inv.OperationID); err != nil {
+		return finish(inv, cmd, remoteAddr, begin, nil, err)
+	}
+	c, err := handler.GetChain(chainType, chainName)
+	if err != nil {
+		return finish(inv, cmd, remoteAddr, begin, nil, err)
+	}
+	var ir *invocation.Response
+	c.Next(inv, func(r *invocation.Response) {
+		ir = r
+	})
+	if ir == nil {
+		return finish(inv, cmd, remoteAddr, begin, nil, protocol.ErrUnExpectedHandlerChainResponse)
+	}
+	if ir.Err != nil {
+		return finish(inv, cmd, remoteAddr, begin, nil, fmt.Errorf("mesher: %s", ir.Err))
+	}
+	resp, _ := inv.Reply.(*Response)
+	if resp == nil || resp.Value == nil {
+		return finish(inv, cmd, remoteAddr, begin, nil, protocol.ErrNilResult)
+	}
+	return finish(inv, cmd, remoteAddr, begin, resp.Value, nil)
+}
+
+//finish records metrics and access log of a command and returns the reply,
+//an error is returned to client as an error reply with ERR prefix
+func finish(inv *invocation.Invocation, cmd *Command, remoteAddr string, begin time.Time,
+	rsp *Value, err error) *Value {
+	if err != nil {
+		msg := err.Error()
+		if !strings.HasPrefix(msg, "ERR ") {
+			msg = "ERR " + msg
+		}
+		rsp = NewError(msg)
+	}
+	status := StatusOK
+	if rsp.IsError() {
+		status = StatusError
+	}
+	latency := time.Since(begin)
+	labelValues := map[string]string{
+		metrics.LServiceName: inv.MicroServiceName,
+		metrics.LApp:         inv.RouteTags.AppID(),
+		metrics.LVersion:     inv.RouteTags.Version(),
+		metrics.LMethod:      inv.OperationID,
+		metrics.LStatus:      status,
+	}
+	metrics.RecordRPCLatency(Name, labelValues, latency.Seconds())
+	metrics.RecordRPCStatus(Name, labelValues, status != StatusOK)
+	accesslog.Log(&accesslog.Record{
+		Protocol:  Name,
+		Source:    inv.SourceMicroService,
+		Service:   inv.MicroServiceName,
+		Operation: inv.OperationID,
+		Status:    status,
+		Latency:   latency,
+		Tags:      map[string]string{"remote": remoteAddr},
+	})
+	return rsp
+}
+
+func maxBulkSize() int64 {
+	n := archaius.GetInt64(KeyMaxBulkSize, DefaultMaxBulkSize)
+	if n <= 0 {
+		openlog.Warn(fmt.Sprintf("invalid %s %d, use default %d", KeyMaxBulkSize, n, DefaultMaxBulkSize))
+		return DefaultMaxBulkSize
+	}
+	return n
+}
@@ -0,0 +1,233 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redis
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/cmd"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	chassisCommon "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/config"
+	"github.com/go-chassis/go-chassis/v2/core/config/model"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/server"
+	"github.com/stretchr/testify/assert"
+)
+
+//fakeRedis is an in-process RESP server which supports PING, GET and SET
+type fakeRedis struct {
+	ln   net.Listener
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	f := &fakeRedis{ln: ln, data: map[string]string{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := NewReader(conn, 0)
+	for {
+		c, err := r.ReadCommand()
+		if err != nil {
+			return
+		}
+		var v *Value
+		f.mu.Lock()
+		switch c.Name() {
+		case "PING":
+			v = &Value{Type: SimpleString, Str: []byte("PONG")}
+		case "SET":
+			f.data[string(c.Args[1])] = string(c.Args[2])
+			v = &Value{Type: SimpleString, Str: []byte("OK")}
+		case "GET":
+			s, ok := f.data[string(c.Args[1])]
+			v = &Value{Type: BulkString, Str: []byte(s), Null: !ok}
+		default:
+			v = NewError("ERR unknown command '" + c.Name() + "'")
+		}
+		f.mu.Unlock()
+		if _, err := conn.Write(v.Append(nil)); err != nil {
+			return
+		}
+	}
+}
+
+func TestClient_Call(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	f := newFakeRedis(t)
+	defer f.ln.Close()
+	c, err := NewClient(client.Options{Timeout: time.Second, PoolSize: 2})
+	assert.NoError(t, err)
+	defer c.Close()
+
+	do := func(args ...string) *Value {
+		resp := &Response{}
+		err := c.Call(context.Background(), f.ln.Addr().String(), &invocation.Invocation{Args: NewCommand(args...)}, resp)
+		assert.NoError(t, err)
+		return resp.Value
+	}
+	assert.Equal(t, "OK", string(do("SET", "k", "v").Str))
+	assert.Equal(t, "v", string(do("GET", "k").Str))
+	assert.True(t, do("GET", "none").Null)
+	assert.True(t, do("FOO").IsError())
+
+	err = c.Call(context.Background(), f.ln.Addr().String(), &invocation.Invocation{}, &Response{})
+	assert.Equal(t, protocol.ErrInvalidReq, err)
+}
+
+func TestServer_HandleRemote(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	config.GlobalDefinition = &model.GlobalCfg{}
+	f := newFakeRedis(t)
+	defer f.ln.Close()
+	cmd.Configs = &cmd.ConfigFromCmd{PortsMap: map[string]string{Name: f.ln.Addr().String()}}
+	assert.NoError(t, handler.CreateChains(chassisCommon.Provider, map[string]string{
+		"incoming": handler.Transport,
+	}))
+	archaius.Set(KeyDenyCommands, "flushall")
+	defer archaius.Delete(KeyDenyCommands)
+
+	s := newServer(serverOptions()).(*redisServer)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			s.serve(conn, HandleRemote)
+		}
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+	//pipelined commands, the inline one included
+	var b []byte
+	b = NewCommand("SET", "k", "v").Append(b)
+	b = NewCommand("GET", "k").Append(b)
+	b = append(b, "FLUSHALL\r\nMULTI\r\nPING\r\n"...)
+	_, err = conn.Write(b)
+	assert.NoError(t, err)
+	r := NewReader(conn, 0)
+	var replies []string
+	for i := 0; i < 5; i++ {
+		v, err := r.ReadValue()
+		assert.NoError(t, err)
+		replies = append(replies, string(v.Append(nil)))
+	}
+	assert.Equal(t, "+OK\r\n", replies[0])
+	assert.Equal(t, "$1\r\nv\r\n", replies[1])
+	assert.True(t, strings.HasPrefix(replies[2], "-ERR command 'FLUSHALL' is not allowed"))
+	assert.True(t, strings.HasPrefix(replies[3], "-ERR command 'MULTI' is not supported"))
+	assert.Equal(t, "+PONG\r\n", replies[4])
+
+	_, err = conn.Write([]byte("QUIT\r\n"))
+	assert.NoError(t, err)
+	v, err := r.ReadValue()
+	assert.NoError(t, err)
+	assert.Equal(t, "OK", string(v.Str))
+}
+
+//selfSigned returns a certificate with common name
+func selfSigned(t *testing.T, name string) tls.Certificate {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, pub, key)
+	assert.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestServer_SourceTLS(t *testing.T) {
+	s := newServer(serverOptions()).(*redisServer)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{selfSigned(t, "mesher")},
+		ClientAuth:   tls.RequireAnyClientCert,
+	})
+	assert.NoError(t, err)
+	defer ln.Close()
+	sources := make(chan *resolver.Source, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			s.serve(conn, func(remoteAddr string, src *resolver.Source, cmd *Command) *Value {
+				sources <- src
+				return &Value{Type: SimpleString, Str: []byte("PONG")}
+			})
+		}
+	}()
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{
+		Certificates:       []tls.Certificate{selfSigned(t, "cart")},
+		InsecureSkipVerify: true,
+	})
+	assert.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write(NewCommand("PING").Append(nil))
+	assert.NoError(t, err)
+	src := <-sources
+	assert.Equal(t, "127.0.0.1", src.IP)
+	//tls state of connection is passed to source resolver
+	assert.NotNil(t, src.TLS)
+	assert.Equal(t, "cart", src.TLS.PeerCertificates[0].Subject.CommonName)
+}
+
+func TestHandleLocal_NoDestination(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	v := HandleLocal("127.0.0.1:5000", &resolver.Source{IP: "127.0.0.1"}, NewCommand("GET", "k"))
+	assert.Equal(t, "ERR "+ErrNoDestination.Error(), string(v.Str))
+}
+
+func serverOptions() server.Options {
+	return server.Options{Address: "127.0.0.1:0"}
+}
@@ -0,0 +1,207 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+//Package redis proxies redis commands in RESP. Every command is routed by itself,
+//so commands which keep state on a connection, like MULTI, SELECT and SUBSCRIBE, are not supported
+package redis
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+)
+
+//Name is the protocol name
+const Name = "redis"
+
+//Config keys of redis protocol, command lists are separated by comma
+const (
+	//KeyDestination is the target redis service
+	KeyDestination = "mesher.redis.destination"
+	//KeyAllowCommands limits commands to the list if it is not empty
+	KeyAllowCommands = "mesher.redis.commands.allow"
+	//KeyDenyCommands rejects commands in the list
+	KeyDenyCommands = "mesher.redis.commands.deny"
+	//KeyKeyHash selects instance by hash of key, for sharded redis
+	KeyKeyHash     = "mesher.redis.keyHash"
+	KeyMaxBulkSize = "mesher.redis.maxBulkSize"
+)
+
+//StrategyKeyHash is the load balance strategy which picks instance by hash of key
+const StrategyKeyHash = "KeyHash"
+
+//MetaKey is the metadata of invocation holding the key to hash
+const MetaKey = "redis.key"
+
+//unsupported commands keep state on a connection
+var unsupported = map[string]bool{
+	"AUTH": true, "SELECT": true, "HELLO": true, "RESET": true,
+	"MULTI": true, "EXEC": true, "DISCARD": true, "WATCH": true, "UNWATCH": true,
+	"SUBSCRIBE": true, "UNSUBSCRIBE": true, "PSUBSCRIBE": true, "PUNSUBSCRIBE": true,
+	"SSUBSCRIBE": true, "SUNSUBSCRIBE": true, "MONITOR": true, "SYNC": true, "PSYNC": true,
+}
+
+//keyless commands have no key in the first arg
+var keyless = map[string]bool{
+	"PING": true, "ECHO": true, "INFO": true, "TIME": true, "DBSIZE": true, "COMMAND": true,
+	"CLIENT": true, "CONFIG": true, "FLUSHALL": true, "FLUSHDB": true, "SCRIPT": true,
+	"RANDOMKEY": true, "KEYS": true, "SCAN": true, "LASTSAVE": true, "SLOWLOG": true,
+	"MEMORY": true, "LATENCY": true, "ROLE": true, "LOLWUT": true, "FUNCTION": true,
+	"PUBLISH": true, "PUBSUB": true,
+}
+
+func init() {
+	loadbalancer.InstallStrategy(StrategyKeyHash, newKeyHashStrategy)
+}
+
+//commandList is a parsed command list of config, it is parsed again only when config changes
+type commandList struct {
+	mu  sync.Mutex
+	key string
+	raw string
+	set map[string]bool
+}
+
+func (l *commandList) get() map[string]bool {
+	raw := archaius.GetString(l.key, "")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if raw != l.raw || (l.set == nil && raw != "") {
+		l.raw = raw
+		l.set = make(map[string]bool)
+		for _, c := range strings.Split(raw, ",") {
+			if c = strings.TrimSpace(c); c != "" {
+				l.set[strings.ToUpper(c)] = true
+			}
+		}
+	}
+	return l.set
+}
+
+var (
+	allowList = &commandList{key: KeyAllowCommands}
+	denyList  = &commandList{key: KeyDenyCommands}
+)
+
+//CheckCommand returns an error if a command is not supported or is not allowed by config
+func CheckCommand(name string) error {
+	if unsupported[name] {
+		return fmt.Errorf("ERR command '%s' is not supported by mesher", name)
+	}
+	if allow := allowList.get(); len(allow) > 0 && !allow[name] {
+		return fmt.Errorf("ERR command '%s' is not allowed by mesher", name)
+	}
+	if denyList.get()[name] {
+		return fmt.Errorf("ERR command '%s' is not allowed by mesher", name)
+	}
+	return nil
+}
+
+//Key returns the first key of a command, it is empty if command has no key
+func Key(c *Command) string {
+	name := c.Name()
+	if keyless[name] || len(c.Args) < 2 {
+		return ""
+	}
+	switch name {
+	case "EVAL", "EVALSHA", "EVAL_RO", "EVALSHA_RO", "FCALL", "FCALL_RO":
+		//EVAL script numkeys key [key ...]
+		if len(c.Args) < 4 {
+			return ""
+		}
+		if n, err := strconv.Atoi(string(c.Args[2])); err != nil || n <= 0 {
+			return ""
+		}
+		return string(c.Args[3])
+	}
+	return string(c.Args[1])
+}
+
+//HashTag returns the part of key to hash, like redis cluster, if key has a non empty {tag},
+//only the tag is hashed, so that keys with same tag go to same instance
+func HashTag(key string) string {
+	if s := strings.IndexByte(key, '{'); s >= 0 {
+		if e := strings.IndexByte(key[s+1:], '}'); e > 0 {
+			return key[s+1 : s+1+e]
+		}
+	}
+	return key
+}
+
+//KeyHashStrategy picks instance by rendezvous hash of key and instance ID,
+//so only keys of a removed instance move to other instances when instances change
+type KeyHashStrategy struct {
+	instances []*registry.MicroServiceInstance
+	key       string
+}
+
+func newKeyHashStrategy() loadbalancer.Strategy {
+	return &KeyHashStrategy{}
+}
+
+//ReceiveData receive data
+func (s *KeyHashStrategy) ReceiveData(inv *invocation.Invocation, instances []*registry.MicroServiceInstance,
+	serviceKey string) {
+	s.instances = instances
+	if k, ok := inv.Metadata[MetaKey].(string); ok {
+		s.key = HashTag(k)
+	}
+}
+
+//Pick return instance, it picks a random one if there is no key
+func (s *KeyHashStrategy) Pick() (*registry.MicroServiceInstance, error) {
+	if len(s.instances) == 0 {
+		return nil, loadbalancer.ErrNoneAvailableInstance
+	}
+	if s.key == "" {
+		return s.instances[rand.Intn(len(s.instances))], nil
+	}
+	k := hash64(s.key)
+	var picked *registry.MicroServiceInstance
+	var max uint64
+	for _, ins := range s.instances {
+		if score := mix(k, hash64(ins.InstanceID)); picked == nil || score > max {
+			picked, max = ins, score
+		}
+	}
+	return picked, nil
+}
+
+func hash64(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+//mix combines two hashes with the finalizer of splitmix64, so that every bit of both affects the score
+func mix(a, b uint64) uint64 {
+	x := a ^ (b * 0x9e3779b97f4a7c15)
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,93 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redis
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckCommand(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	assert.NoError(t, CheckCommand("GET"))
+	assert.Error(t, CheckCommand("MULTI"))
+
+	archaius.Set(KeyDenyCommands, "flushall, keys")
+	defer archaius.Delete(KeyDenyCommands)
+	assert.Error(t, CheckCommand("FLUSHALL"))
+	assert.Error(t, CheckCommand("KEYS"))
+	assert.NoError(t, CheckCommand("GET"))
+
+	archaius.Set(KeyAllowCommands, "get,set")
+	defer archaius.Delete(KeyAllowCommands)
+	assert.NoError(t, CheckCommand("GET"))
+	assert.Error(t, CheckCommand("DEL"))
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "k", Key(NewCommand("get", "k")))
+	assert.Equal(t, "", Key(NewCommand("PING", "hello")))
+	assert.Equal(t, "", Key(NewCommand("GET")))
+	assert.Equal(t, "k1", Key(NewCommand("EVAL", "return 1", "1", "k1")))
+	assert.Equal(t, "", Key(NewCommand("EVAL", "return 1", "0")))
+
+	assert.Equal(t, "user1", HashTag("{user1}.following"))
+	assert.Equal(t, "a{}b", HashTag("a{}b"))
+	assert.Equal(t, "foo", HashTag("foo"))
+}
+
+func TestKeyHashStrategy(t *testing.T) {
+	var instances []*registry.MicroServiceInstance
+	for i := 0; i < 5; i++ {
+		instances = append(instances, &registry.MicroServiceInstance{InstanceID: fmt.Sprintf("ins%d", i)})
+	}
+	pick := func(key string, ins []*registry.MicroServiceInstance) string {
+		s := newKeyHashStrategy()
+		s.ReceiveData(&invocation.Invocation{Metadata: map[string]interface{}{MetaKey: key}}, ins, "redis")
+		i, err := s.Pick()
+		assert.NoError(t, err)
+		return i.InstanceID
+	}
+	picked := map[string]string{}
+	used := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		picked[key] = pick(key, instances)
+		assert.Equal(t, picked[key], pick(key, instances))
+		used[picked[key]] = true
+	}
+	assert.Equal(t, 5, len(used))
+	//keys of other instances stay when an instance is removed
+	for key, id := range picked {
+		if id != "ins0" {
+			assert.Equal(t, id, pick(key, instances[1:]))
+		}
+	}
+	//keys with same tag go to same instance
+	assert.Equal(t, pick("{u1}.a", instances), pick("{u1}.b", instances))
+
+	s := newKeyHashStrategy()
+	s.ReceiveData(&invocation.Invocation{}, nil, "redis")
+	_, err := s.Pick()
+	assert.Error(t, err)
+}
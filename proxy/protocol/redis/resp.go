@@ -0,0 +1,334 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redis
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+)
+
+//Types of RESP values
+const (
+	SimpleString = '+'
+	Error        = '-'
+	Integer      = ':'
+	BulkString   = '$'
+	Array        = '*'
+)
+
+//Limits of RESP reader
+const (
+	//DefaultMaxBulkSize is the max length of a bulk string, same as redis proto-max-bulk-len
+	DefaultMaxBulkSize = 512 * 1024 * 1024
+	//MaxLineSize is the max length of a simple string, an error, a length line and an inline command
+	MaxLineSize = 64 * 1024
+	//MaxDepth is the max nesting depth of arrays
+	MaxDepth = 128
+)
+
+var (
+	//ErrProtocol means data is not valid RESP
+	ErrProtocol = errors.New("redis protocol error")
+	//ErrBulkSize means a bulk string is larger than the limit
+	ErrBulkSize = errors.New("redis bulk string exceeds the limit")
+)
+
+//Value is a RESP value
+type Value struct {
+	Type byte
+	//Str holds simple string, error and bulk string
+	Str   []byte
+	Int   int64
+	Array []*Value
+	//Null is set for null bulk string and null array
+	Null bool
+}
+
+//NewError returns an error value
+func NewError(msg string) *Value {
+	return &Value{Type: Error, Str: []byte(msg)}
+}
+
+//IsError returns whether value is an error
+func (v *Value) IsError() bool {
+	return v.Type == Error
+}
+
+//Append appends encoded value to b
+func (v *Value) Append(b []byte) []byte {
+	b = append(b, v.Type)
+	switch v.Type {
+	case SimpleString, Error:
+		b = append(b, v.Str...)
+	case Integer:
+		b = strconv.AppendInt(b, v.Int, 10)
+	case BulkString:
+		if v.Null {
+			return append(b, "-1\r\n"...)
+		}
+		b = strconv.AppendInt(b, int64(len(v.Str)), 10)
+		b = append(b, '\r', '\n')
+		b = append(b, v.Str...)
+	case Array:
+		if v.Null {
+			return append(b, "-1\r\n"...)
+		}
+		b = strconv.AppendInt(b, int64(len(v.Array)), 10)
+		b = append(b, '\r', '\n')
+		for _, e := range v.Array {
+			b = e.Append(b)
+		}
+		return b
+	}
+	return append(b, '\r', '\n')
+}
+
+//Command is a redis command, the first arg is command name
+type Command struct {
+	Args [][]byte
+}
+
+//NewCommand returns a command of args
+func NewCommand(args ...string) *Command {
+	c := &Command{}
+	for _, a := range args {
+		c.Args = append(c.Args, []byte(a))
+	}
+	return c
+}
+
+//Name returns command name in upper case
+func (c *Command) Name() string {
+	if len(c.Args) == 0 {
+		return ""
+	}
+	return string(bytes.ToUpper(c.Args[0]))
+}
+
+//Append appends command as an array of bulk strings to b
+func (c *Command) Append(b []byte) []byte {
+	b = append(b, Array)
+	b = strconv.AppendInt(b, int64(len(c.Args)), 10)
+	b = append(b, '\r', '\n')
+	for _, a := range c.Args {
+		b = append(b, BulkString)
+		b = strconv.AppendInt(b, int64(len(a)), 10)
+		b = append(b, '\r', '\n')
+		b = append(b, a...)
+		b = append(b, '\r', '\n')
+	}
+	return b
+}
+
+//Reader reads RESP values
+type Reader struct {
+	r       *bufio.Reader
+	maxBulk int64
+}
+
+//NewReader returns a reader, zero maxBulk means DefaultMaxBulkSize
+func NewReader(r io.Reader, maxBulk int64) *Reader {
+	if maxBulk <= 0 {
+		maxBulk = DefaultMaxBulkSize
+	}
+	return &Reader{r: bufio.NewReaderSize(r, MaxLineSize), maxBulk: maxBulk}
+}
+
+//Buffered returns the count of bytes that can be read without blocking
+func (r *Reader) Buffered() int {
+	return r.r.Buffered()
+}
+
+//readLine returns a line without \r\n, the line is valid until next read
+func (r *Reader) readLine() ([]byte, error) {
+	line, err := r.r.ReadSlice('\n')
+	if err != nil {
+		if err == bufio.ErrBufferFull {
+			return nil, ErrProtocol
+		}
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, ErrProtocol
+	}
+	return line[:len(line)-2], nil
+}
+
+func parseInt(b []byte) (int64, error) {
+	n, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return 0, ErrProtocol
+	}
+	return n, nil
+}
+
+//readBulk reads n bytes and \r\n, memory grows with data so a large length alone takes nothing
+func (r *Reader) readBulk(n int64) ([]byte, error) {
+	if n > r.maxBulk {
+		return nil, ErrBulkSize
+	}
+	var b []byte
+	var err error
+	if n <= MaxLineSize {
+		b = make([]byte, n)
+		_, err = io.ReadFull(r.r, b)
+	} else {
+		b, err = ioutil.ReadAll(io.LimitReader(r.r, n))
+		if err == nil && int64(len(b)) < n {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var crlf [2]byte
+	if _, err := io.ReadFull(r.r, crlf[:]); err != nil {
+		return nil, err
+	}
+	if crlf[0] != '\r' || crlf[1] != '\n' {
+		return nil, ErrProtocol
+	}
+	return b, nil
+}
+
+//ReadValue reads a value
+func (r *Reader) ReadValue() (*Value, error) {
+	return r.readValue(0)
+}
+
+func (r *Reader) readValue(depth int) (*Value, error) {
+	if depth > MaxDepth {
+		return nil, ErrProtocol
+	}
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, ErrProtocol
+	}
+	v := &Value{Type: line[0]}
+	switch v.Type {
+	case SimpleString, Error:
+		v.Str = append([]byte(nil), line[1:]...)
+	case Integer:
+		v.Int, err = parseInt(line[1:])
+	case BulkString:
+		var n int64
+		if n, err = parseInt(line[1:]); err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			v.Null = true
+			return v, nil
+		}
+		v.Str, err = r.readBulk(n)
+	case Array:
+		var n int64
+		if n, err = parseInt(line[1:]); err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			v.Null = true
+			return v, nil
+		}
+		//every element takes at least 3 bytes, so capacity is not taken from wire
+		v.Array = make([]*Value, 0, minInt64(n, 1024))
+		for i := int64(0); i < n; i++ {
+			e, err := r.readValue(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			v.Array = append(v.Array, e)
+		}
+	default:
+		return nil, ErrProtocol
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+//ReadCommand reads a command, it is an array of bulk strings or an inline command
+func (r *Reader) ReadCommand() (*Command, error) {
+	for {
+		b, err := r.r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if b[0] != Array {
+			line, err := r.readLine()
+			if err != nil {
+				return nil, err
+			}
+			fields := bytes.Fields(line)
+			if len(fields) == 0 {
+				//empty line is skipped like redis
+				continue
+			}
+			c := &Command{}
+			for _, f := range fields {
+				c.Args = append(c.Args, append([]byte(nil), f...))
+			}
+			return c, nil
+		}
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		n, err := parseInt(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n <= 0 {
+			continue
+		}
+		c := &Command{Args: make([][]byte, 0, minInt64(n, 1024))}
+		for i := int64(0); i < n; i++ {
+			line, err := r.readLine()
+			if err != nil {
+				return nil, err
+			}
+			if len(line) == 0 || line[0] != BulkString {
+				return nil, ErrProtocol
+			}
+			l, err := parseInt(line[1:])
+			if err != nil || l < 0 {
+				return nil, ErrProtocol
+			}
+			a, err := r.readBulk(l)
+			if err != nil {
+				return nil, err
+			}
+			c.Args = append(c.Args, a)
+		}
+		return c, nil
+	}
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,93 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redis
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReader_ReadValue(t *testing.T) {
+	raw := "+OK\r\n-ERR wrong\r\n:42\r\n$5\r\nhello\r\n$-1\r\n*-1\r\n*2\r\n*1\r\n:1\r\n$0\r\n\r\n"
+	r := NewReader(strings.NewReader(raw), 0)
+	var out []byte
+	for i := 0; i < 7; i++ {
+		v, err := r.ReadValue()
+		assert.NoError(t, err)
+		out = v.Append(out)
+	}
+	assert.Equal(t, raw, string(out))
+
+	r = NewReader(strings.NewReader(raw), 0)
+	v, _ := r.ReadValue()
+	assert.Equal(t, byte(SimpleString), v.Type)
+	assert.Equal(t, "OK", string(v.Str))
+	v, _ = r.ReadValue()
+	assert.True(t, v.IsError())
+	v, _ = r.ReadValue()
+	assert.Equal(t, int64(42), v.Int)
+	v, _ = r.ReadValue()
+	assert.Equal(t, "hello", string(v.Str))
+	v, _ = r.ReadValue()
+	assert.True(t, v.Null)
+}
+
+func TestReader_Invalid(t *testing.T) {
+	for _, raw := range []string{
+		"OK\r\n",
+		"+OK\n",
+		":abc\r\n",
+		"$3\r\nabcd\r\n",
+		"$x\r\n",
+		"#t\r\n",
+		strings.Repeat("*1\r\n", MaxDepth+2) + ":1\r\n",
+		"+" + strings.Repeat("a", MaxLineSize) + "\r\n",
+	} {
+		_, err := NewReader(strings.NewReader(raw), 0).ReadValue()
+		assert.Equal(t, ErrProtocol, err, raw)
+	}
+	_, err := NewReader(strings.NewReader("$100\r\n"), 10).ReadValue()
+	assert.Equal(t, ErrBulkSize, err)
+	//a large length without data does not allocate it
+	_, err = NewReader(strings.NewReader("$100000000\r\nabc"), 0).ReadValue()
+	assert.Error(t, err)
+	_, err = NewReader(strings.NewReader("*100000000\r\n:1\r\n"), 0).ReadValue()
+	assert.Error(t, err)
+}
+
+func TestReader_ReadCommand(t *testing.T) {
+	raw := "*3\r\n$3\r\nset\r\n$1\r\nk\r\n$2\r\nv1\r\n\r\nPING  hello\r\n*0\r\n*1\r\n$4\r\nPING\r\n"
+	r := NewReader(strings.NewReader(raw), 0)
+	c, err := r.ReadCommand()
+	assert.NoError(t, err)
+	assert.Equal(t, "SET", c.Name())
+	assert.Equal(t, "*3\r\n$3\r\nset\r\n$1\r\nk\r\n$2\r\nv1\r\n", string(c.Append(nil)))
+	c, err = r.ReadCommand()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("PING"), []byte("hello")}, c.Args)
+	c, err = r.ReadCommand()
+	assert.NoError(t, err)
+	assert.Equal(t, "PING", c.Name())
+
+	_, err = NewReader(strings.NewReader("*1\r\n:1\r\n"), 0).ReadCommand()
+	assert.Equal(t, ErrProtocol, err)
+	assert.True(t, bytes.Equal(NewCommand("GET", "k").Append(nil), []byte("*2\r\n$3\r\nGET\r\n$1\r\nk\r\n")))
+}
@@ -0,0 +1,141 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redis
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/apache/servicecomb-mesher/proxy/common"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/runtime"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	chassisCom "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/server"
+	chassisTLS "github.com/go-chassis/go-chassis/v2/core/tls"
+	chassisRuntime "github.com/go-chassis/go-chassis/v2/pkg/runtime"
+	"github.com/go-chassis/openlog"
+)
+
+func init() {
+	server.InstallPlugin(Name, newServer)
+}
+
+func newServer(opts server.Options) server.ProtocolServer {
+	return &redisServer{
+		ConnServer: protocol.ConnServer{Protocol: Name},
+		opts:       opts,
+	}
+}
+
+//handleFunc handles a command and returns the reply
+type handleFunc func(remoteAddr string, src *resolver.Source, cmd *Command) *Value
+
+type redisServer struct {
+	protocol.ConnServer
+	opts    server.Options
+	maxBulk int64
+}
+
+func (s *redisServer) Register(schema interface{}, options ...server.RegisterOption) (string, error) {
+	return "", nil
+}
+
+//Start listens like http, in sidecar mode calls from local application come to 127.0.0.1 and
+//calls from other meshers come to external ip, in other mode all calls are sent to remote services
+func (s *redisServer) Start() error {
+	s.maxBulk = maxBulkSize()
+	host, port, err := net.SplitHostPort(s.opts.Address)
+	if err != nil {
+		return err
+	}
+	if runtime.Role != common.RoleSidecar {
+		return s.listen(s.opts.Address, nil, HandleLocal)
+	}
+	if err := s.listen("127.0.0.1:"+port, nil, HandleLocal); err != nil {
+		return err
+	}
+	switch host {
+	case "0.0.0.0":
+		return errors.New("in sidecar mode, forbidden to listen on 0.0.0.0")
+	case "127.0.0.1":
+		openlog.Warn("redis listen on 127.0.0.1, it can only proxy for consumer. " +
+			"for provider, mesher must listen on external ip.")
+		return nil
+	}
+	tlsConfig, sslConfig, err := chassisTLS.GetTLSConfigByService(
+		chassisRuntime.ServiceName, Name, chassisCom.Provider)
+	if err != nil {
+		if !chassisTLS.IsSSLConfigNotExist(err) {
+			return err
+		}
+	} else {
+		openlog.Warn(fmt.Sprintf("%s.%s.%s TLS mode, verify peer: %t, cipher plugin: %s.",
+			chassisRuntime.ServiceName, Name, chassisCom.Provider, sslConfig.VerifyPeer, sslConfig.CipherPlugin))
+	}
+	return s.listen(s.opts.Address, tlsConfig, HandleRemote)
+}
+
+func (s *redisServer) listen(addr string, t *tls.Config, h handleFunc) error {
+	return s.Listen(addr, t, func(conn net.Conn, listener string) {
+		s.serve(conn, h)
+	})
+}
+
+//serve reads commands of a connection and handles them one by one, so that replies are in order,
+//replies of pipelined commands are flushed together
+func (s *redisServer) serve(conn net.Conn, h handleFunc) {
+	defer conn.Close()
+	r := NewReader(conn, s.maxBulk)
+	w := bufio.NewWriter(conn)
+	remoteAddr := conn.RemoteAddr().String()
+	src := resolver.ConnSource(conn)
+	var buf []byte
+	for {
+		cmd, err := r.ReadCommand()
+		if err != nil {
+			if err == ErrProtocol || err == ErrBulkSize {
+				w.Write(NewError("ERR " + err.Error()).Append(nil))
+				w.Flush()
+			} else if err != io.EOF {
+				openlog.Warn(fmt.Sprintf("read redis command from %s failed: %s", remoteAddr, err))
+			}
+			return
+		}
+		if cmd.Name() == "QUIT" {
+			w.WriteString("+OK\r\n")
+			w.Flush()
+			return
+		}
+		buf = h(remoteAddr, src, cmd).Append(buf[:0])
+		if _, err := w.Write(buf); err != nil {
+			return
+		}
+		if r.Buffered() > 0 {
+			continue
+		}
+		if err := w.Flush(); err != nil {
+			openlog.Warn(fmt.Sprintf("write redis reply to %s failed: %s", remoteAddr, err))
+			return
+		}
+	}
+}
@@ -0,0 +1,44 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package protocol
+
+import (
+	"net/http"
+
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+)
+
+//AddResponseHeader adds headers to the response of a passed request, gateways write them to client,
+//so handlers in provider chain can set cookies or quota headers
+func AddResponseHeader(r *invocation.Response, h http.Header) {
+	if r.Err != nil {
+		return
+	}
+	if r.Result == nil {
+		r.Result = http.Header{}
+	}
+	dst, ok := r.Result.(http.Header)
+	if !ok {
+		return
+	}
+	for k, vs := range h {
+		for _, v := range vs {
+			dst.Add(k, v)
+		}
+	}
+}
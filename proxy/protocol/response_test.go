@@ -0,0 +1,38 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package protocol
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddResponseHeader(t *testing.T) {
+	r := &invocation.Response{}
+	AddResponseHeader(r, http.Header{"Set-Cookie": []string{"a=1"}})
+	AddResponseHeader(r, http.Header{"Set-Cookie": []string{"b=2"}})
+	assert.Equal(t, []string{"a=1", "b=2"}, r.Result.(http.Header)["Set-Cookie"])
+
+	failed := &invocation.Response{Err: errors.New("failed")}
+	AddResponseHeader(failed, http.Header{"Set-Cookie": []string{"a=1"}})
+	assert.Nil(t, failed.Result)
+}
@@ -0,0 +1,99 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+)
+
+func init() {
+	client.InstallPlugin(Name, NewClient)
+}
+
+//Client is a tcp client, a call only opens a connection to the address,
+//if TLS is configured the connection is wrapped with TLS, it is how meshers talk with mTLS
+type Client struct {
+	opts client.Options
+}
+
+//NewClient return a new client of tcp
+func NewClient(opts client.Options) (client.ProtocolClient, error) {
+	return &Client{
+		opts: opts,
+	}, nil
+}
+
+//Call is a method which opens a connection to addr and sets it to rsp, rsp must be *Response,
+//timeout of client is used as timeout of connecting and TLS handshake
+func (c *Client) Call(ctx context.Context, addr string, inv *invocation.Invocation, rsp interface{}) error {
+	resp, ok := rsp.(*Response)
+	if !ok {
+		return protocol.ErrInvalidResp
+	}
+	d := &net.Dialer{Timeout: c.opts.Timeout}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if c.opts.TLSConfig != nil {
+		tlsConn := tls.Client(conn, c.opts.TLSConfig)
+		if c.opts.Timeout > 0 {
+			tlsConn.SetDeadline(time.Now().Add(c.opts.Timeout))
+		}
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return err
+		}
+		tlsConn.SetDeadline(time.Time{})
+		conn = tlsConn
+	}
+	resp.Conn = conn
+	return nil
+}
+
+//Status returns 0, tcp has no status
+func (c *Client) Status(rsp interface{}) (status int, err error) {
+	return 0, nil
+}
+
+//String return name
+func (c *Client) String() string {
+	return Name
+}
+
+//Close does nothing, connections are closed by server after proxying
+func (c *Client) Close() error {
+	return nil
+}
+
+//ReloadConfigs reload config
+func (c *Client) ReloadConfigs(opts client.Options) {
+	c.opts = client.EqualOpts(c.opts, opts)
+}
+
+//GetOptions return opts
+func (c *Client) GetOptions() client.Options {
+	return c.opts
+}
@@ -0,0 +1,119 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/common"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/apache/servicecomb-mesher/proxy/util"
+	chassisCommon "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/pkg/runtime"
+	utiltags "github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/go-chassis/openlog"
+)
+
+//serveLocal proxies a connection from local application to a remote service
+func (s *tcpServer) serveLocal(conn net.Conn, service, listener string) {
+	inv := newInvocation(conn)
+	inv.MicroServiceName = service
+	inv.SourceServiceID = runtime.ServiceID
+	inv.SourceMicroService = runtime.ServiceName
+	s.proxy(conn, inv, chassisCommon.Consumer, common.ChainConsumerOutgoing, listener)
+}
+
+//serveRemote proxies a connection from other mesher to local service, the port of local service is
+//set by --service-ports, like tcp:3306
+func (s *tcpServer) serveRemote(conn net.Conn, listener string) {
+	inv := newInvocation(conn)
+	inv.MicroServiceName = runtime.ServiceName
+	inv.RouteTags = utiltags.NewDefaultTag(runtime.Version, runtime.App)
+	if si := resolver.ResolveSource(resolver.ConnSource(conn)); si != nil {
+		inv.SourceMicroService = si.Name
+	}
+	if err := util.SetLocalServiceAddress(inv, ""); err != nil {
+		openlog.Error(err.Error())
+		conn.Close()
+		metrics.RecordConnectionFailure(Name, labelValues(inv, listener))
+		return
+	}
+	s.proxy(conn, inv, chassisCommon.Provider, common.ChainProviderIncoming, listener)
+}
+
+func newInvocation(conn net.Conn) *invocation.Invocation {
+	return &invocation.Invocation{
+		Protocol: Name,
+		Args:     conn,
+		Reply:    &Response{},
+		Ctx:      context.WithValue(context.Background(), chassisCommon.ContextHeaderKey{}, map[string]string{}),
+	}
+}
+
+func labelValues(inv *invocation.Invocation, listener string) map[string]string {
+	return map[string]string{metrics.LServiceName: inv.MicroServiceName, metrics.LListener: listener}
+}
+
+func (s *tcpServer) proxy(conn net.Conn, inv *invocation.Invocation, chainType, chainName, listener string) {
+	labels := labelValues(inv, listener)
+	up, err := Dial(inv, chainType, chainName)
+	if err != nil {
+		openlog.Error(fmt.Sprintf("can not connect to [%s] for %s: %s", inv.MicroServiceName,
+			conn.RemoteAddr(), err))
+		conn.Close()
+		metrics.RecordConnectionFailure(Name, labels)
+		return
+	}
+	begin := time.Now()
+	received, sent := Pipe(conn, up, s.idle)
+	metrics.RecordConnection(Name, labels, received, sent, time.Since(begin).Seconds())
+}
+
+//Dial runs the invocation through a handler chain, the transport handler at the end of chain opens
+//the upstream connection, so that a connection is rejected by rate limiter or circuit breaker in chain
+func Dial(inv *invocation.Invocation, chainType, chainName string) (net.Conn, error) {
+	c, err := handler.GetChain(chainType, chainName)
+	if err != nil {
+		return nil, err
+	}
+	var ir *invocation.Response
+	c.Next(inv, func(r *invocation.Response) {
+		ir = r
+	})
+	resp, _ := inv.Reply.(*Response)
+	if ir == nil {
+		return nil, protocol.ErrUnExpectedHandlerChainResponse
+	}
+	if ir.Err != nil {
+		if resp != nil && resp.Conn != nil {
+			resp.Conn.Close()
+		}
+		return nil, ir.Err
+	}
+	if resp == nil || resp.Conn == nil {
+		return nil, protocol.ErrUnknown
+	}
+	return resp.Conn, nil
+}
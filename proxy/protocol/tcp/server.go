@@ -0,0 +1,101 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/common"
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/runtime"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	chassisCom "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/server"
+	chassisTLS "github.com/go-chassis/go-chassis/v2/core/tls"
+	chassisRuntime "github.com/go-chassis/go-chassis/v2/pkg/runtime"
+	"github.com/go-chassis/openlog"
+)
+
+func init() {
+	server.InstallPlugin(Name, newServer)
+}
+
+func newServer(opts server.Options) server.ProtocolServer {
+	return &tcpServer{
+		ConnServer: protocol.ConnServer{Protocol: Name},
+		opts:       opts,
+	}
+}
+
+type tcpServer struct {
+	protocol.ConnServer
+	opts server.Options
+	idle time.Duration
+}
+
+func (s *tcpServer) Register(schema interface{}, options ...server.RegisterOption) (string, error) {
+	return "", nil
+}
+
+//Start listens on local addresses of mesher.tcp.listeners to proxy for consumers,
+//in sidecar mode it also listens on the protocol address to proxy for local service
+func (s *tcpServer) Start() error {
+	s.idle = config.GetDuration(KeyIdleTimeout, DefaultIdleTimeout)
+	if c := config.GetConfig(); c != nil {
+		for _, l := range c.Mesher.TCP.Listeners {
+			if l == nil || l.ListenAddress == "" || l.Service == "" {
+				return errors.New("tcp listener must have listenAddress and service")
+			}
+			service := l.Service
+			if err := s.Listen(l.ListenAddress, nil, func(conn net.Conn, listener string) {
+				s.serveLocal(conn, service, listener)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	if runtime.Role != common.RoleSidecar {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(s.opts.Address)
+	if err != nil {
+		return err
+	}
+	switch host {
+	case "0.0.0.0":
+		return errors.New("in sidecar mode, forbidden to listen on 0.0.0.0")
+	case "127.0.0.1":
+		openlog.Warn("tcp listen on 127.0.0.1, it can only proxy for consumer. " +
+			"for provider, mesher must listen on external ip.")
+		return nil
+	}
+	tlsConfig, sslConfig, err := chassisTLS.GetTLSConfigByService(
+		chassisRuntime.ServiceName, Name, chassisCom.Provider)
+	if err != nil {
+		if !chassisTLS.IsSSLConfigNotExist(err) {
+			return err
+		}
+	} else {
+		openlog.Warn(fmt.Sprintf("%s.%s.%s TLS mode, verify peer: %t, cipher plugin: %s.",
+			chassisRuntime.ServiceName, Name, chassisCom.Provider, sslConfig.VerifyPeer, sslConfig.CipherPlugin))
+	}
+	return s.Listen(s.opts.Address, tlsConfig, s.serveRemote)
+}
@@ -0,0 +1,126 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+//Package tcp is a raw tcp (l4) proxy, it proxies any protocol on top of tcp, like databases and MQTT brokers.
+//Handler chains run once when a connection is opened, so load balance, rate limit and circuit breaker
+//work on connections, bytes are copied without parsing after upstream is connected
+package tcp
+
+import (
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//Name is the protocol name
+const Name = "tcp"
+
+//Config keys of tcp proxy, durations are in format like "60s"
+const (
+	KeyIdleTimeout = "mesher.tcp.idleTimeout"
+)
+
+//DefaultIdleTimeout closes a connection which has no data in both directions for this long
+const DefaultIdleTimeout = time.Hour
+
+//Response holds the upstream connection opened by client
+type Response struct {
+	Conn net.Conn
+}
+
+//closeWriter is a connection which can shut down its writing side, like *net.TCPConn and *tls.Conn
+type closeWriter interface {
+	CloseWrite() error
+}
+
+type pipe struct {
+	idle time.Duration
+	last int64
+	once sync.Once
+	down net.Conn
+	up   net.Conn
+}
+
+func (p *pipe) touch() {
+	atomic.StoreInt64(&p.last, time.Now().UnixNano())
+}
+
+func (p *pipe) idleFor() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&p.last)))
+}
+
+func (p *pipe) close() {
+	p.once.Do(func() {
+		p.down.Close()
+		p.up.Close()
+	})
+}
+
+//Pipe copies data between downstream and upstream until both directions are finished,
+//or there is no data in both directions longer than idle, zero idle means no limit.
+//Both connections are closed when it returns,
+//it returns bytes received from downstream and bytes sent to downstream
+func Pipe(down, up net.Conn, idle time.Duration) (received, sent int64) {
+	p := &pipe{idle: idle, down: down, up: up}
+	p.touch()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sent = p.copy(down, up)
+	}()
+	received = p.copy(up, down)
+	wg.Wait()
+	p.close()
+	return received, sent
+}
+
+//copy copies src to dst, when src reaches EOF the writing side of dst is shut down,
+//so the peer knows it and the other direction goes on, other errors close both connections
+func (p *pipe) copy(dst, src net.Conn) int64 {
+	buf := make([]byte, 32*1024)
+	var n int64
+	for {
+		if p.idle > 0 {
+			src.SetReadDeadline(time.Now().Add(p.idle))
+		}
+		nr, err := src.Read(buf)
+		if nr > 0 {
+			p.touch()
+			nw, werr := dst.Write(buf[:nr])
+			n += int64(nw)
+			if werr != nil {
+				p.close()
+				return n
+			}
+		}
+		if err == nil {
+			continue
+		}
+		if ne, ok := err.(net.Error); ok && ne.Timeout() && p.idleFor() < p.idle {
+			//the other direction is still active
+			continue
+		}
+		cw, ok := dst.(closeWriter)
+		if err != io.EOF || !ok || cw.CloseWrite() != nil {
+			p.close()
+		}
+		return n
+	}
+}
@@ -0,0 +1,212 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tcp
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/go-chassis/go-chassis/v2/client/rest"
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	chassisCommon "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/stretchr/testify/assert"
+)
+
+//echo serves a listener which writes back everything and closes after EOF
+func echo(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				b, _ := ioutil.ReadAll(conn)
+				conn.Write(b)
+				conn.Close()
+			}()
+		}
+	}()
+	return ln
+}
+
+type result struct {
+	received int64
+	sent     int64
+}
+
+//proxy pipes every connection of a new listener to addr
+func proxy(t *testing.T, addr string, idle time.Duration) (net.Listener, chan result) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ch := make(chan result, 1)
+	go func() {
+		down, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		up, err := net.Dial("tcp", addr)
+		if err != nil {
+			down.Close()
+			return
+		}
+		r, s := Pipe(down, up, idle)
+		ch <- result{received: r, sent: s}
+	}()
+	return ln, ch
+}
+
+func TestPipe(t *testing.T) {
+	up := echo(t)
+	defer up.Close()
+	ln, ch := proxy(t, up.Addr().String(), time.Minute)
+	defer ln.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	assert.NoError(t, err)
+	_, err = conn.Write([]byte("hello"))
+	assert.NoError(t, err)
+	//half close, the reply still comes back
+	assert.NoError(t, conn.(*net.TCPConn).CloseWrite())
+	b, err := ioutil.ReadAll(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+	conn.Close()
+
+	select {
+	case r := <-ch:
+		assert.Equal(t, int64(5), r.received)
+		assert.Equal(t, int64(5), r.sent)
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipe is not finished")
+	}
+}
+
+func TestPipe_Idle(t *testing.T) {
+	up := echo(t)
+	defer up.Close()
+	ln, ch := proxy(t, up.Addr().String(), 100*time.Millisecond)
+	defer ln.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+	select {
+	case r := <-ch:
+		assert.Equal(t, int64(0), r.received)
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle connection is not closed")
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
+
+func TestClient_Call(t *testing.T) {
+	up := echo(t)
+	defer up.Close()
+	c, err := NewClient(clientOptions())
+	assert.NoError(t, err)
+	assert.Equal(t, Name, c.String())
+
+	resp := &Response{}
+	err = c.Call(context.Background(), up.Addr().String(), &invocation.Invocation{}, resp)
+	assert.NoError(t, err)
+	assert.NotNil(t, resp.Conn)
+	resp.Conn.Close()
+
+	err = c.Call(context.Background(), up.Addr().String(), &invocation.Invocation{}, rest.NewResponse())
+	assert.Equal(t, protocol.ErrInvalidResp, err)
+
+	//nothing listens on a closed listener
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+	err = c.Call(context.Background(), addr, &invocation.Invocation{}, &Response{})
+	assert.Error(t, err)
+}
+
+var errReject = errors.New("too many connections")
+
+type rejectHandler struct{}
+
+func (h *rejectHandler) Handle(chain *handler.Chain, inv *invocation.Invocation, cb invocation.ResponseCallBack) {
+	cb(&invocation.Response{Err: errReject})
+}
+
+func (h *rejectHandler) Name() string {
+	return "tcp-test-reject"
+}
+
+//dialHandler works like transport handler
+type dialHandler struct{}
+
+func (h *dialHandler) Handle(chain *handler.Chain, inv *invocation.Invocation, cb invocation.ResponseCallBack) {
+	c, _ := NewClient(clientOptions())
+	err := c.Call(inv.Ctx, inv.Endpoint, inv, inv.Reply)
+	cb(&invocation.Response{Err: err, Result: inv.Reply})
+}
+
+func (h *dialHandler) Name() string {
+	return "tcp-test-dial"
+}
+
+func init() {
+	handler.RegisterHandler("tcp-test-reject", func() handler.Handler { return &rejectHandler{} })
+	handler.RegisterHandler("tcp-test-dial", func() handler.Handler { return &dialHandler{} })
+}
+
+func TestDial(t *testing.T) {
+	up := echo(t)
+	defer up.Close()
+	assert.NoError(t, handler.CreateChains(chassisCommon.Consumer, map[string]string{
+		"tcp-reject": "tcp-test-reject,tcp-test-dial",
+		"tcp-dial":   "tcp-test-dial",
+	}))
+
+	inv := newInvocation(nil)
+	inv.Endpoint = up.Addr().String()
+	_, err := Dial(inv, chassisCommon.Consumer, "tcp-reject")
+	assert.Equal(t, errReject, err)
+
+	inv = newInvocation(nil)
+	inv.Endpoint = up.Addr().String()
+	conn, err := Dial(inv, chassisCommon.Consumer, "tcp-dial")
+	assert.NoError(t, err)
+	_, err = conn.Write([]byte("ping"))
+	assert.NoError(t, err)
+	conn.(*net.TCPConn).CloseWrite()
+	b, err := ioutil.ReadAll(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(b))
+	conn.Close()
+}
+
+func clientOptions() client.Options {
+	return client.Options{Timeout: time.Second}
+}
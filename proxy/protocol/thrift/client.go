@@ -0,0 +1,266 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/openlog"
+)
+
+var (
+	//ErrTimeout means reply does not come back in time
+	ErrTimeout = errors.New("thrift call timeout")
+	//ErrConnClosed means connection is closed before reply comes back
+	ErrConnClosed = errors.New("thrift connection closed")
+)
+
+func init() {
+	client.InstallPlugin(Name, NewClient)
+}
+
+//Response holds the reply of a call, it is nil for oneway call
+type Response struct {
+	Msg *Message
+}
+
+//Client is a thrift client, calls to an endpoint share one connection,
+//each call gets a new sequence ID on the connection and reply gets back the original one
+type Client struct {
+	opts client.Options
+	mu   sync.Mutex
+	conn *clientConn
+}
+
+//NewClient return a new client of thrift
+func NewClient(opts client.Options) (client.ProtocolClient, error) {
+	return &Client{
+		opts: opts,
+	}, nil
+}
+
+//Call sends the message in args of invocation to addr and waits for the reply
+func (c *Client) Call(ctx context.Context, addr string, inv *invocation.Invocation, rsp interface{}) error {
+	req, ok := inv.Args.(*Message)
+	if !ok {
+		return protocol.ErrInvalidReq
+	}
+	resp, ok := rsp.(*Response)
+	if !ok {
+		return protocol.ErrInvalidResp
+	}
+	cc, err := c.getConn(addr)
+	if err != nil {
+		return err
+	}
+	timeout := c.opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	m, err := cc.send(ctx, req, timeout)
+	if err != nil {
+		return err
+	}
+	resp.Msg = m
+	return nil
+}
+
+func (c *Client) getConn(addr string) (*clientConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil && !c.conn.isClosed() {
+		return c.conn, nil
+	}
+	timeout := c.opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.TLSConfig != nil {
+		tlsConn := tls.Client(conn, c.opts.TLSConfig)
+		tlsConn.SetDeadline(time.Now().Add(timeout))
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConn.SetDeadline(time.Time{})
+		conn = tlsConn
+	}
+	c.conn = newClientConn(conn, maxFrameSize())
+	return c.conn, nil
+}
+
+//Status returns 0, thrift has no status
+func (c *Client) Status(rsp interface{}) (status int, err error) {
+	return 0, nil
+}
+
+//String return name
+func (c *Client) String() string {
+	return Name
+}
+
+//Close closes the connection
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		c.conn.close(ErrConnClosed)
+		c.conn = nil
+	}
+	return nil
+}
+
+//ReloadConfigs reload config
+func (c *Client) ReloadConfigs(opts client.Options) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.opts = client.EqualOpts(c.opts, opts)
+}
+
+//GetOptions return opts
+func (c *Client) GetOptions() client.Options {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.opts
+}
+
+//clientConn is an upstream connection shared by calls
+type clientConn struct {
+	conn     net.Conn
+	maxFrame int
+	wmu      sync.Mutex
+	mu       sync.Mutex
+	seq      int32
+	pending  map[int32]chan *Message
+	closed   bool
+}
+
+func newClientConn(conn net.Conn, maxFrame int) *clientConn {
+	c := &clientConn{
+		conn:     conn,
+		maxFrame: maxFrame,
+		pending:  make(map[int32]chan *Message),
+	}
+	go c.readLoop()
+	return c
+}
+
+func (c *clientConn) readLoop() {
+	for {
+		frame, err := ReadFrame(c.conn, c.maxFrame)
+		if err != nil {
+			c.close(err)
+			return
+		}
+		m, err := Decode(frame)
+		if err != nil {
+			c.close(err)
+			return
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[m.SeqID]
+		delete(c.pending, m.SeqID)
+		c.mu.Unlock()
+		if !ok {
+			openlog.Warn("drop thrift reply of unknown sequence ID " + m.Name)
+			continue
+		}
+		ch <- m
+	}
+}
+
+//send writes req with a new sequence ID, the reply has the sequence ID of req
+func (c *clientConn) send(ctx context.Context, req *Message, timeout time.Duration) (*Message, error) {
+	fwd := *req
+	var ch chan *Message
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrConnClosed
+	}
+	c.seq++
+	fwd.SeqID = c.seq
+	if req.Type != Oneway {
+		ch = make(chan *Message, 1)
+		c.pending[fwd.SeqID] = ch
+	}
+	c.mu.Unlock()
+
+	c.wmu.Lock()
+	_, err := c.conn.Write(fwd.Encode())
+	c.wmu.Unlock()
+	if err != nil {
+		c.close(err)
+		return nil, err
+	}
+	if ch == nil {
+		return nil, nil
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case m, ok := <-ch:
+		if !ok {
+			return nil, ErrConnClosed
+		}
+		m.SeqID = req.SeqID
+		return m, nil
+	case <-timer.C:
+		err = ErrTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	c.mu.Lock()
+	delete(c.pending, fwd.SeqID)
+	c.mu.Unlock()
+	return nil, err
+}
+
+func (c *clientConn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+//close closes connection and wakes up all waiting calls
+func (c *clientConn) close(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.conn.Close()
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	openlog.Debug("thrift connection closed: " + err.Error())
+}
@@ -0,0 +1,137 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/stretchr/testify/assert"
+)
+
+//upstream replies every call with its name as body, except "slow" and oneway calls,
+//sequence IDs it sees are sent to seqs
+func upstream(t *testing.T, seqs chan int32) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				for {
+					frame, err := ReadFrame(conn, DefaultMaxFrameSize)
+					if err != nil {
+						return
+					}
+					m, err := Decode(frame)
+					if err != nil {
+						return
+					}
+					seqs <- m.SeqID
+					if m.Name == "slow" || m.Type == Oneway {
+						continue
+					}
+					m.Type = Reply
+					m.Body = []byte(m.Name)
+					conn.Write(m.Encode())
+				}
+			}()
+		}
+	}()
+	return ln
+}
+
+func call(c client.ProtocolClient, addr, name string, typ MessageType) (*Message, error) {
+	inv := &invocation.Invocation{Args: &Message{Name: name, Type: typ, SeqID: 1, Strict: true}}
+	resp := &Response{}
+	err := c.Call(context.Background(), addr, inv, resp)
+	return resp.Msg, err
+}
+
+func TestClient_Call(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	seqs := make(chan int32, 100)
+	ln := upstream(t, seqs)
+	defer ln.Close()
+	addr := ln.Addr().String()
+	c, err := NewClient(client.Options{Timeout: time.Second})
+	assert.NoError(t, err)
+	defer c.Close()
+
+	//calls with same sequence ID share one connection
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b", "c", "d"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			m, err := call(c, addr, name, Call)
+			assert.NoError(t, err)
+			assert.Equal(t, Reply, m.Type)
+			assert.Equal(t, int32(1), m.SeqID)
+			assert.Equal(t, name, string(m.Body))
+		}(name)
+	}
+	wg.Wait()
+	seen := map[int32]bool{}
+	for i := 0; i < 4; i++ {
+		seen[<-seqs] = true
+	}
+	assert.Equal(t, 4, len(seen))
+
+	m, err := call(c, addr, "notify", Oneway)
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+	<-seqs
+
+	_, err = call(c, addr, "slow", Call)
+	assert.Equal(t, ErrTimeout, err)
+
+	err = c.Call(context.Background(), addr, &invocation.Invocation{}, &Response{})
+	assert.Equal(t, protocol.ErrInvalidReq, err)
+	err = c.Call(context.Background(), addr, &invocation.Invocation{Args: &Message{}}, nil)
+	assert.Equal(t, protocol.ErrInvalidResp, err)
+}
+
+func TestClient_Reconnect(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	seqs := make(chan int32, 100)
+	ln := upstream(t, seqs)
+	defer ln.Close()
+	addr := ln.Addr().String()
+	c, err := NewClient(client.Options{Timeout: time.Second})
+	assert.NoError(t, err)
+
+	_, err = call(c, addr, "a", Call)
+	assert.NoError(t, err)
+	//connection is dialed again after it is closed
+	c.(*Client).conn.close(ErrConnClosed)
+	m, err := call(c, addr, "b", Call)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", string(m.Body))
+}
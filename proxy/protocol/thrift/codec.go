@@ -0,0 +1,290 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+//MessageType is the type of a thrift message
+type MessageType byte
+
+//Message types of thrift
+const (
+	Call      MessageType = 1
+	Reply     MessageType = 2
+	Exception MessageType = 3
+	Oneway    MessageType = 4
+)
+
+//String returns name of message type
+func (t MessageType) String() string {
+	switch t {
+	case Call:
+		return "call"
+	case Reply:
+		return "reply"
+	case Exception:
+		return "exception"
+	case Oneway:
+		return "oneway"
+	}
+	return "unknown"
+}
+
+//Encoding is the protocol of a thrift message
+type Encoding int
+
+//Encodings supported
+const (
+	Binary Encoding = iota
+	Compact
+)
+
+//Types of TApplicationException
+const (
+	ExceptionUnknown       int32 = 0
+	ExceptionUnknownMethod int32 = 1
+	ExceptionInternalError int32 = 6
+	ExceptionProtocolError int32 = 7
+)
+
+const (
+	binaryVersion1     = 0x80010000
+	binaryVersionMask  = 0xffff0000
+	compactProtocolID  = 0x82
+	compactVersion     = 1
+	compactVersionMask = 0x1f
+	compactTypeShift   = 5
+)
+
+//MultiplexedSeparator separates service name and method name of TMultiplexedProtocol
+const MultiplexedSeparator = ":"
+
+//DefaultMaxFrameSize is the max frame size, same as thrift
+const DefaultMaxFrameSize = 16384000
+
+var (
+	//ErrFrameSize means frame is larger than the limit
+	ErrFrameSize = errors.New("thrift frame size exceeds the limit")
+	//ErrInvalidMessage means message header can not be decoded
+	ErrInvalidMessage = errors.New("invalid thrift message header")
+)
+
+//Message is a thrift message, only header is decoded, body is kept as it is
+type Message struct {
+	//Name is method name, with service name in front for TMultiplexedProtocol, like "Calculator:add"
+	Name     string
+	Type     MessageType
+	SeqID    int32
+	Encoding Encoding
+	//Strict means header of a binary message has a version
+	Strict bool
+	Body   []byte
+}
+
+//Service returns service name of TMultiplexedProtocol, it is empty if message is not multiplexed
+func (m *Message) Service() string {
+	if i := strings.Index(m.Name, MultiplexedSeparator); i >= 0 {
+		return m.Name[:i]
+	}
+	return ""
+}
+
+//Method returns method name without service name
+func (m *Message) Method() string {
+	if i := strings.Index(m.Name, MultiplexedSeparator); i >= 0 {
+		return m.Name[i+1:]
+	}
+	return m.Name
+}
+
+//ReadFrame reads a frame of framed transport, zero maxSize means no limit
+func ReadFrame(r io.Reader, maxSize int) ([]byte, error) {
+	var h [4]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(h[:])
+	if maxSize > 0 && int64(n) > int64(maxSize) {
+		return nil, ErrFrameSize
+	}
+	frame := make([]byte, n)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+//Decode decodes message header of a frame, binary and compact protocols are detected by first byte
+func Decode(frame []byte) (*Message, error) {
+	var m *Message
+	var err error
+	if len(frame) > 0 && frame[0] == compactProtocolID {
+		m, err = decodeCompact(frame)
+	} else {
+		m, err = decodeBinary(frame)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.Type < Call || m.Type > Oneway {
+		return nil, ErrInvalidMessage
+	}
+	return m, nil
+}
+
+func decodeBinary(b []byte) (*Message, error) {
+	if len(b) < 4 {
+		return nil, ErrInvalidMessage
+	}
+	m := &Message{Encoding: Binary}
+	v := binary.BigEndian.Uint32(b)
+	off := 4
+	if v&binaryVersionMask == binaryVersion1 {
+		m.Strict = true
+		m.Type = MessageType(v & 0xff)
+		if len(b) < off+4 {
+			return nil, ErrInvalidMessage
+		}
+		n := binary.BigEndian.Uint32(b[off:])
+		off += 4
+		if uint64(n) > uint64(len(b)-off) {
+			return nil, ErrInvalidMessage
+		}
+		m.Name = string(b[off : off+int(n)])
+		off += int(n)
+	} else {
+		if int32(v) < 0 || uint64(v) > uint64(len(b)-off) {
+			return nil, ErrInvalidMessage
+		}
+		m.Name = string(b[off : off+int(v)])
+		off += int(v)
+		if len(b) < off+1 {
+			return nil, ErrInvalidMessage
+		}
+		m.Type = MessageType(b[off])
+		off++
+	}
+	if len(b) < off+4 {
+		return nil, ErrInvalidMessage
+	}
+	m.SeqID = int32(binary.BigEndian.Uint32(b[off:]))
+	m.Body = b[off+4:]
+	return m, nil
+}
+
+func decodeCompact(b []byte) (*Message, error) {
+	if len(b) < 2 || b[1]&compactVersionMask != compactVersion {
+		return nil, ErrInvalidMessage
+	}
+	m := &Message{Encoding: Compact, Type: MessageType(b[1] >> compactTypeShift)}
+	off := 2
+	seq, n := binary.Uvarint(b[off:])
+	if n <= 0 || seq > 0xffffffff {
+		return nil, ErrInvalidMessage
+	}
+	m.SeqID = int32(uint32(seq))
+	off += n
+	l, n := binary.Uvarint(b[off:])
+	if n <= 0 {
+		return nil, ErrInvalidMessage
+	}
+	off += n
+	if l > uint64(len(b)-off) {
+		return nil, ErrInvalidMessage
+	}
+	m.Name = string(b[off : off+int(l)])
+	m.Body = b[off+int(l):]
+	return m, nil
+}
+
+//Encode encodes message into a frame with the length in front
+func (m *Message) Encode() []byte {
+	b := make([]byte, 4, 4+len(m.Name)+len(m.Body)+16)
+	switch {
+	case m.Encoding == Compact:
+		b = append(b, compactProtocolID, byte(m.Type)<<compactTypeShift|compactVersion)
+		b = appendUvarint(b, uint64(uint32(m.SeqID)))
+		b = appendUvarint(b, uint64(len(m.Name)))
+		b = append(b, m.Name...)
+	case m.Strict:
+		b = appendUint32(b, binaryVersion1|uint32(m.Type))
+		b = appendUint32(b, uint32(len(m.Name)))
+		b = append(b, m.Name...)
+		b = appendUint32(b, uint32(m.SeqID))
+	default:
+		b = appendUint32(b, uint32(len(m.Name)))
+		b = append(b, m.Name...)
+		b = append(b, byte(m.Type))
+		b = appendUint32(b, uint32(m.SeqID))
+	}
+	b = append(b, m.Body...)
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	return b
+}
+
+//NewException returns a TApplicationException reply of a call, it is encoded in the same protocol of call
+func NewException(req *Message, typ int32, msg string) *Message {
+	m := &Message{
+		Name:     req.Name,
+		Type:     Exception,
+		SeqID:    req.SeqID,
+		Encoding: req.Encoding,
+		Strict:   req.Strict,
+	}
+	//struct TApplicationException {1: string message, 2: i32 type}
+	var b []byte
+	if req.Encoding == Compact {
+		b = append(b, 1<<4|compactTypeBinary)
+		b = appendUvarint(b, uint64(len(msg)))
+		b = append(b, msg...)
+		b = append(b, 1<<4|compactTypeI32)
+		b = appendUvarint(b, uint64(uint32(typ<<1)^uint32(typ>>31)))
+	} else {
+		b = append(b, binaryTypeString, 0, 1)
+		b = appendUint32(b, uint32(len(msg)))
+		b = append(b, msg...)
+		b = append(b, binaryTypeI32, 0, 2)
+		b = appendUint32(b, uint32(typ))
+	}
+	m.Body = append(b, typeStop)
+	return m
+}
+
+//field types used by TApplicationException
+const (
+	typeStop          = 0
+	binaryTypeI32     = 8
+	binaryTypeString  = 11
+	compactTypeI32    = 5
+	compactTypeBinary = 8
+)
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
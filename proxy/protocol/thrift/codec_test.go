@@ -0,0 +1,121 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	//call "Calculator:add" with seq 7 and an empty struct
+	strictCall = []byte{0x80, 0x01, 0x00, 0x01, 0, 0, 0, 14,
+		'C', 'a', 'l', 'c', 'u', 'l', 'a', 't', 'o', 'r', ':', 'a', 'd', 'd', 0, 0, 0, 7, 0}
+	oldCall     = []byte{0, 0, 0, 3, 'a', 'd', 'd', 1, 0, 0, 0, 7, 0}
+	compactCall = []byte{0x82, 0x21, 7, 3, 'a', 'd', 'd', 0}
+)
+
+func TestDecode(t *testing.T) {
+	m, err := Decode(strictCall)
+	assert.NoError(t, err)
+	assert.Equal(t, "Calculator:add", m.Name)
+	assert.Equal(t, "Calculator", m.Service())
+	assert.Equal(t, "add", m.Method())
+	assert.Equal(t, Call, m.Type)
+	assert.Equal(t, int32(7), m.SeqID)
+	assert.Equal(t, Binary, m.Encoding)
+	assert.True(t, m.Strict)
+	assert.Equal(t, []byte{0}, m.Body)
+	assert.Equal(t, strictCall, m.Encode()[4:])
+
+	m, err = Decode(oldCall)
+	assert.NoError(t, err)
+	assert.Equal(t, "", m.Service())
+	assert.Equal(t, "add", m.Method())
+	assert.False(t, m.Strict)
+	assert.Equal(t, int32(7), m.SeqID)
+	assert.Equal(t, oldCall, m.Encode()[4:])
+
+	m, err = Decode(compactCall)
+	assert.NoError(t, err)
+	assert.Equal(t, Compact, m.Encoding)
+	assert.Equal(t, "add", m.Name)
+	assert.Equal(t, Call, m.Type)
+	assert.Equal(t, int32(7), m.SeqID)
+	assert.Equal(t, compactCall, m.Encode()[4:])
+
+	//sequence ID changes the length of compact header
+	m.SeqID = 300
+	m2, err := Decode(m.Encode()[4:])
+	assert.NoError(t, err)
+	assert.Equal(t, int32(300), m2.SeqID)
+	assert.Equal(t, "add", m2.Name)
+	assert.Equal(t, []byte{0}, m2.Body)
+
+	m.SeqID = -1
+	m2, err = Decode(m.Encode()[4:])
+	assert.NoError(t, err)
+	assert.Equal(t, int32(-1), m2.SeqID)
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	for _, b := range [][]byte{
+		nil,
+		{0x80, 0x01, 0x00},
+		{0x80, 0x01, 0x00, 0x01, 0, 0, 0, 100, 'a'},
+		{0x80, 0x02, 0x00, 0x01, 0, 0, 0, 0, 0, 0, 0, 1},
+		{0x80, 0x01, 0x00, 0x09, 0, 0, 0, 0, 0, 0, 0, 1},
+		{0, 0, 0, 3, 'a', 'd'},
+		{0x82, 0x22},
+		{0x82, 0x21, 7, 10, 'a'},
+	} {
+		_, err := Decode(b)
+		assert.Equal(t, ErrInvalidMessage, err, "%v", b)
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	m, _ := Decode(strictCall)
+	frame, err := ReadFrame(bytes.NewReader(m.Encode()), DefaultMaxFrameSize)
+	assert.NoError(t, err)
+	assert.Equal(t, strictCall, frame)
+
+	_, err = ReadFrame(bytes.NewReader(m.Encode()), 10)
+	assert.Equal(t, ErrFrameSize, err)
+}
+
+func TestNewException(t *testing.T) {
+	for _, b := range [][]byte{strictCall, compactCall} {
+		req, _ := Decode(b)
+		e := NewException(req, ExceptionInternalError, "boom")
+		m, err := Decode(e.Encode()[4:])
+		assert.NoError(t, err)
+		assert.Equal(t, Exception, m.Type)
+		assert.Equal(t, req.Name, m.Name)
+		assert.Equal(t, req.SeqID, m.SeqID)
+		assert.True(t, bytes.Contains(m.Body, []byte("boom")))
+	}
+	req, _ := Decode(strictCall)
+	e := NewException(req, ExceptionInternalError, "boom")
+	assert.Equal(t, []byte{11, 0, 1, 0, 0, 0, 4, 'b', 'o', 'o', 'm', 8, 0, 2, 0, 0, 0, 6, 0}, e.Body)
+	req, _ = Decode(compactCall)
+	e = NewException(req, ExceptionInternalError, "boom")
+	assert.Equal(t, []byte{0x18, 4, 'b', 'o', 'o', 'm', 0x15, 12, 0}, e.Body)
+}
@@ -0,0 +1,144 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"context"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/common"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/accesslog"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/apache/servicecomb-mesher/proxy/util"
+	chassisCommon "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/pkg/runtime"
+	utiltags "github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+)
+
+//HandleLocal sends a call from local application to the service resolved by destination resolver
+func HandleLocal(remoteAddr string, src *resolver.Source, req *Message) *Message {
+	inv := newInvocation(req)
+	inv.SourceServiceID = runtime.ServiceID
+	inv.SourceMicroService = runtime.ServiceName
+	service, _, err := resolver.GetDestinationResolver(Name).Resolve(src.IP, req.Service(), "", nil)
+	if err != nil {
+		return finish(inv, req, remoteAddr, time.Now(), nil, err)
+	}
+	inv.MicroServiceName = service
+	if inv.SchemaID == "" {
+		inv.SchemaID = service
+	}
+	return invoke(inv, req, remoteAddr, chassisCommon.Consumer, common.ChainConsumerOutgoing)
+}
+
+//HandleRemote sends a call from other mesher to local service, the port of local service is
+//set by --service-ports, like thrift:9090
+func HandleRemote(remoteAddr string, src *resolver.Source, req *Message) *Message {
+	inv := newInvocation(req)
+	inv.MicroServiceName = runtime.ServiceName
+	inv.RouteTags = utiltags.NewDefaultTag(runtime.Version, runtime.App)
+	if inv.SchemaID == "" {
+		inv.SchemaID = runtime.ServiceName
+	}
+	if si := resolver.ResolveSource(src); si != nil {
+		inv.SourceMicroService = si.Name
+	}
+	if err := util.SetLocalServiceAddress(inv, ""); err != nil {
+		return finish(inv, req, remoteAddr, time.Now(), nil, err)
+	}
+	return invoke(inv, req, remoteAddr, chassisCommon.Provider, common.ChainProviderIncoming)
+}
+
+//newInvocation uses thrift service name as schema and method name as operation,
+//so that rate limit can be set on each method
+func newInvocation(req *Message) *invocation.Invocation {
+	return &invocation.Invocation{
+		Protocol:    Name,
+		SchemaID:    req.Service(),
+		OperationID: req.Method(),
+		Args:        req,
+		Reply:       &Response{},
+		Ctx:         context.WithValue(context.Background(), chassisCommon.ContextHeaderKey{}, map[string]string{}),
+	}
+}
+
+func invoke(inv *invocation.Invocation, req *Message, remoteAddr, chainType, chainName string) *Message {
+	begin := time.Now()
+	c, err := handler.GetChain(chainType, chainName)
+	if err != nil {
+		return finish(inv, req, remoteAddr, begin, nil, err)
+	}
+	var ir *invocation.Response
+	c.Next(inv, func(r *invocation.Response) {
+		ir = r
+	})
+	if ir == nil {
+		return finish(inv, req, remoteAddr, begin, nil, protocol.ErrUnExpectedHandlerChainResponse)
+	}
+	if ir.Err != nil {
+		return finish(inv, req, remoteAddr, begin, nil, ir.Err)
+	}
+	resp, _ := inv.Reply.(*Response)
+	if resp == nil {
+		return finish(inv, req, remoteAddr, begin, nil, protocol.ErrNilResult)
+	}
+	return finish(inv, req, remoteAddr, begin, resp.Msg, nil)
+}
+
+//finish records metrics and access log of a call and returns the reply,
+//an error is returned to caller as TApplicationException
+func finish(inv *invocation.Invocation, req *Message, remoteAddr string, begin time.Time,
+	rsp *Message, err error) *Message {
+	if err != nil && req.Type != Oneway {
+		rsp = NewException(req, ExceptionInternalError, err.Error())
+	}
+	status := Reply.String()
+	switch {
+	case err != nil:
+		status = "error"
+	case rsp != nil:
+		status = rsp.Type.String()
+	case req.Type == Oneway:
+		status = Oneway.String()
+	}
+	latency := time.Since(begin)
+	labelValues := map[string]string{
+		metrics.LServiceName: inv.MicroServiceName,
+		metrics.LApp:         inv.RouteTags.AppID(),
+		metrics.LVersion:     inv.RouteTags.Version(),
+		metrics.LInterface:   inv.SchemaID,
+		metrics.LMethod:      inv.OperationID,
+		metrics.LStatus:      status,
+	}
+	metrics.RecordRPCLatency(Name, labelValues, latency.Seconds())
+	metrics.RecordRPCStatus(Name, labelValues, status != Reply.String() && status != Oneway.String())
+	accesslog.Log(&accesslog.Record{
+		Protocol:  Name,
+		Source:    inv.SourceMicroService,
+		Service:   inv.MicroServiceName,
+		Operation: req.Name,
+		Status:    status,
+		Latency:   latency,
+		Tags:      map[string]string{"remote": remoteAddr},
+	})
+	return rsp
+}
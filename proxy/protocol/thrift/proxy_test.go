@@ -0,0 +1,60 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/go-chassis/go-archaius"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceNameResolver(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	r := &ServiceNameResolver{}
+	_, _, err := r.Resolve("", "", "", nil)
+	assert.Equal(t, ErrNoDestination, err)
+
+	archaius.Set(KeyDestination, "calculator")
+	archaius.Set(KeyServices+".Calculator", "calc")
+	defer archaius.Delete(KeyDestination)
+	defer archaius.Delete(KeyServices + ".Calculator")
+	s, _, err := r.Resolve("", "", "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "calculator", s)
+	s, _, err = r.Resolve("", "Calculator", "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "calc", s)
+	s, _, err = r.Resolve("", "Echo", "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Echo", s)
+}
+
+func TestHandleLocal_NoDestination(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	req, _ := Decode(oldCall)
+	rsp := HandleLocal("127.0.0.1:5000", &resolver.Source{IP: "127.0.0.1"}, req)
+	assert.Equal(t, Exception, rsp.Type)
+	assert.Equal(t, req.SeqID, rsp.SeqID)
+	assert.True(t, bytes.Contains(rsp.Body, []byte(ErrNoDestination.Error())))
+
+	req.Type = Oneway
+	assert.Nil(t, HandleLocal("127.0.0.1:5000", &resolver.Source{IP: "127.0.0.1"}, req))
+}
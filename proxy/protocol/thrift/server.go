@@ -0,0 +1,135 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/apache/servicecomb-mesher/proxy/common"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/runtime"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	chassisCom "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/server"
+	chassisTLS "github.com/go-chassis/go-chassis/v2/core/tls"
+	chassisRuntime "github.com/go-chassis/go-chassis/v2/pkg/runtime"
+	"github.com/go-chassis/openlog"
+)
+
+func init() {
+	server.InstallPlugin(Name, newServer)
+}
+
+func newServer(opts server.Options) server.ProtocolServer {
+	return &thriftServer{
+		ConnServer: protocol.ConnServer{Protocol: Name},
+		opts:       opts,
+	}
+}
+
+//handleFunc handles a call and returns the reply, it returns nil for oneway call
+type handleFunc func(remoteAddr string, src *resolver.Source, req *Message) *Message
+
+type thriftServer struct {
+	protocol.ConnServer
+	opts     server.Options
+	maxFrame int
+}
+
+func (s *thriftServer) Register(schema interface{}, options ...server.RegisterOption) (string, error) {
+	return "", nil
+}
+
+//Start listens like http, in sidecar mode calls from local application come to 127.0.0.1 and
+//calls from other meshers come to external ip, in other mode all calls are sent to remote services
+func (s *thriftServer) Start() error {
+	s.maxFrame = maxFrameSize()
+	host, port, err := net.SplitHostPort(s.opts.Address)
+	if err != nil {
+		return err
+	}
+	if runtime.Role != common.RoleSidecar {
+		return s.listen(s.opts.Address, nil, HandleLocal)
+	}
+	if err := s.listen("127.0.0.1:"+port, nil, HandleLocal); err != nil {
+		return err
+	}
+	switch host {
+	case "0.0.0.0":
+		return errors.New("in sidecar mode, forbidden to listen on 0.0.0.0")
+	case "127.0.0.1":
+		openlog.Warn("thrift listen on 127.0.0.1, it can only proxy for consumer. " +
+			"for provider, mesher must listen on external ip.")
+		return nil
+	}
+	tlsConfig, sslConfig, err := chassisTLS.GetTLSConfigByService(
+		chassisRuntime.ServiceName, Name, chassisCom.Provider)
+	if err != nil {
+		if !chassisTLS.IsSSLConfigNotExist(err) {
+			return err
+		}
+	} else {
+		openlog.Warn(fmt.Sprintf("%s.%s.%s TLS mode, verify peer: %t, cipher plugin: %s.",
+			chassisRuntime.ServiceName, Name, chassisCom.Provider, sslConfig.VerifyPeer, sslConfig.CipherPlugin))
+	}
+	return s.listen(s.opts.Address, tlsConfig, HandleRemote)
+}
+
+func (s *thriftServer) listen(addr string, t *tls.Config, h handleFunc) error {
+	return s.Listen(addr, t, func(conn net.Conn, listener string) {
+		s.serve(conn, h)
+	})
+}
+
+//serve reads calls of a connection and handles them concurrently, replies are written in the order they finish
+func (s *thriftServer) serve(conn net.Conn, h handleFunc) {
+	defer conn.Close()
+	var wmu sync.Mutex
+	remoteAddr := conn.RemoteAddr().String()
+	src := resolver.ConnSource(conn)
+	for {
+		frame, err := ReadFrame(conn, s.maxFrame)
+		if err != nil {
+			if err != io.EOF {
+				openlog.Warn(fmt.Sprintf("read thrift frame from %s failed: %s", remoteAddr, err))
+			}
+			return
+		}
+		req, err := Decode(frame)
+		if err != nil {
+			openlog.Warn(fmt.Sprintf("decode thrift message from %s failed: %s", remoteAddr, err))
+			return
+		}
+		go func() {
+			rsp := h(remoteAddr, src, req)
+			if rsp == nil {
+				return
+			}
+			wmu.Lock()
+			defer wmu.Unlock()
+			if _, err := conn.Write(rsp.Encode()); err != nil {
+				openlog.Warn(fmt.Sprintf("write thrift reply to %s failed: %s", remoteAddr, err))
+			}
+		}()
+	}
+}
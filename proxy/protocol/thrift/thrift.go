@@ -0,0 +1,82 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+//Package thrift proxies Apache Thrift binary and compact protocols over framed transport.
+//Only message header is decoded, method name is used for routing, metrics and rate limiting,
+//sequence IDs are remapped because calls of many connections share one upstream connection
+package thrift
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/openlog"
+)
+
+//Name is the protocol name
+const Name = "thrift"
+
+//Config keys of thrift protocol
+const (
+	//KeyDestination is the target service of calls which are not multiplexed
+	KeyDestination = "mesher.thrift.destination"
+	//KeyServices is the prefix of mapping from thrift service name of TMultiplexedProtocol to micro service name,
+	//like mesher.thrift.services.Calculator: calculator
+	KeyServices     = "mesher.thrift.services"
+	KeyMaxFrameSize = "mesher.thrift.maxFrameSize"
+)
+
+//DefaultTimeout is used when client has no timeout
+const DefaultTimeout = 30 * time.Second
+
+//ErrNoDestination means target service of a call is unknown
+var ErrNoDestination = errors.New("can not resolve destination of thrift call, set " + KeyDestination +
+	" or use TMultiplexedProtocol")
+
+func init() {
+	resolver.SetDefaultDestinationResolver(Name, &ServiceNameResolver{})
+}
+
+//ServiceNameResolver resolves thrift service name of TMultiplexedProtocol to micro service name,
+//if there is no mapping, thrift service name is the micro service name,
+//calls without service name go to the default destination
+type ServiceNameResolver struct {
+}
+
+//Resolve returns the micro service name, host is the thrift service name
+func (r *ServiceNameResolver) Resolve(remoteIP, host, rawURI string, header map[string]string) (string, string, error) {
+	if host == "" {
+		d := archaius.GetString(KeyDestination, "")
+		if d == "" {
+			return "", "", ErrNoDestination
+		}
+		return d, "", nil
+	}
+	return archaius.GetString(KeyServices+"."+host, host), "", nil
+}
+
+func maxFrameSize() int {
+	n := archaius.GetInt(KeyMaxFrameSize, DefaultMaxFrameSize)
+	if n <= 0 {
+		openlog.Warn(fmt.Sprintf("invalid %s %d, use default %d", KeyMaxFrameSize, n, DefaultMaxFrameSize))
+		return DefaultMaxFrameSize
+	}
+	return n
+}
@@ -0,0 +1,46 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package triple
+
+import (
+	"github.com/apache/servicecomb-mesher/proxy/protocol/grpc"
+	"github.com/go-chassis/go-chassis/v2/core/client"
+)
+
+func init() {
+	client.InstallPlugin(Name, NewClient)
+}
+
+//Client is a Triple client, it reuses the http2 transport of grpc client
+type Client struct {
+	*grpc.Client
+}
+
+//NewClient return a new client of Triple
+func NewClient(opts client.Options) (client.ProtocolClient, error) {
+	c, err := grpc.NewClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{Client: c.(*grpc.Client)}, nil
+}
+
+//String return name
+func (c *Client) String() string {
+	return Name
+}
@@ -0,0 +1,307 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package triple
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/common"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/accesslog"
+	"github.com/apache/servicecomb-mesher/proxy/pkg/metrics"
+	"github.com/apache/servicecomb-mesher/proxy/protocol"
+	dubboproxy "github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/proxy"
+	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/schema"
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/apache/servicecomb-mesher/proxy/util"
+	"github.com/go-chassis/foundation/stringutil"
+	"github.com/go-chassis/go-chassis/v2/client/rest"
+	chassisCommon "github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/go-chassis/go-chassis/v2/pkg/runtime"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/go-chassis/go-chassis/v2/third_party/forked/afex/hystrix-go/hystrix"
+	"github.com/go-chassis/openlog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//constants for headers
+const (
+	XForwardedPort = "X-Forwarded-Port"
+	XForwardedHost = "X-Forwarded-Host"
+	HeaderStatus   = "Grpc-Status"
+	HeaderMessage  = "Grpc-Message"
+)
+
+//ErrNilResponse means handler chain gives no response
+var ErrNilResponse = errors.New("triple response is nil")
+
+func preHandler(req *http.Request) *invocation.Invocation {
+	inv := &invocation.Invocation{}
+	inv.Args = req
+	inv.Protocol = Name
+	inv.Reply = rest.NewResponse()
+	inv.URLPath = req.URL.Path
+	inv.SchemaID, inv.OperationID, _ = ParsePath(req.URL.Path)
+	inv.Ctx = context.TODO()
+	return inv
+}
+
+//LocalRequestHandler is for request from local
+func LocalRequestHandler(w http.ResponseWriter, r *http.Request) {
+	prepareRequest(r)
+	inv := preHandler(r)
+	inv.SourceServiceID = runtime.ServiceID
+	inv.SourceMicroService = runtime.ServiceName
+	r.Header.Set(chassisCommon.HeaderSourceName, runtime.ServiceName)
+	code := codes.OK
+	defer func(begin time.Time) {
+		RecordMetrics(inv, r, code, time.Since(begin))
+	}(time.Now())
+
+	h := Attachments(r.Header)
+	if err := resolveDestination(inv, r, h); err != nil {
+		code = WriteErrorResponse(w, status.Error(codes.Unavailable, err.Error()))
+		return
+	}
+	//transfer attachments into ctx
+	inv.Ctx = context.WithValue(inv.Ctx, chassisCommon.ContextHeaderKey{}, h)
+	c, err := handler.GetChain(chassisCommon.Consumer, common.ChainConsumerOutgoing)
+	if err != nil {
+		openlog.Error("Get chain failed: " + err.Error())
+		code = WriteErrorResponse(w, err)
+		return
+	}
+	var invRsp *invocation.Response
+	c.Next(inv, func(ir *invocation.Response) {
+		invRsp = ir
+	})
+	code = handleResponse(w, invRsp)
+}
+
+//RemoteRequestHandler is for request from remote
+func RemoteRequestHandler(w http.ResponseWriter, r *http.Request) {
+	prepareRequest(r)
+	inv := preHandler(r)
+	inv.MicroServiceName = runtime.ServiceName
+	inv.RouteTags = utiltags.NewDefaultTag(runtime.Version, runtime.App)
+	inv.SourceMicroService = r.Header.Get(chassisCommon.HeaderSourceName)
+	if inv.SourceMicroService == "" {
+		//Resolve Source
+		if si := resolver.ResolveSource(resolver.RequestSource(r)); si != nil {
+			inv.SourceMicroService = si.Name
+		}
+	}
+	code := codes.OK
+	defer func(begin time.Time) {
+		RecordMetrics(inv, r, code, time.Since(begin))
+	}(time.Now())
+
+	inv.Ctx = context.WithValue(inv.Ctx, chassisCommon.ContextHeaderKey{}, Attachments(r.Header))
+	c, err := handler.GetChain(chassisCommon.Provider, common.ChainProviderIncoming)
+	if err != nil {
+		openlog.Error("Get chain failed: " + err.Error())
+		code = WriteErrorResponse(w, err)
+		return
+	}
+	if err = util.SetLocalServiceAddress(inv, r.Header.Get(XForwardedPort)); err != nil {
+		code = WriteErrorResponse(w, status.Error(codes.Unavailable, err.Error()))
+		return
+	}
+	if r.Header.Get(XForwardedHost) == "" {
+		r.Header.Set(XForwardedHost, r.Host)
+	}
+	var invRsp *invocation.Response
+	c.Next(inv, func(ir *invocation.Response) {
+		invRsp = ir
+	})
+	code = handleResponse(w, invRsp)
+}
+
+//resolveDestination finds the service which provides the interface,
+//if the interface is not registered, it falls back to resolve the authority of request.
+//Group and version headers select instances like dubbo group and version
+func resolveDestination(inv *invocation.Invocation, r *http.Request, h map[string]string) error {
+	if inv.SchemaID != "" && registry.DefaultContractDiscoveryService != nil {
+		if svc := schema.GetSvcByInterface(inv.SchemaID); svc != nil {
+			inv.MicroServiceName = svc.ServiceName
+			//Triple headers are dubbo attachments, group and version are routed like dubbo
+			dubboproxy.RouteAttachments(inv, h, h[HeaderServiceGroup], h[HeaderServiceVersion], svc)
+			return nil
+		}
+	}
+	if r.URL.Scheme == "" {
+		r.URL.Scheme = "http"
+	}
+	if r.URL.Host == "" {
+		r.URL.Host = r.Host
+	}
+	source := stringutil.SplitFirstSep(r.RemoteAddr, ":")
+	dr := resolver.GetDestinationResolver("http")
+	serviceName, port, err := dr.Resolve(source, "", r.URL.String(), h)
+	if err != nil {
+		return err
+	}
+	inv.MicroServiceName = serviceName
+	inv.RouteTags = dubboproxy.GroupVersionTags(inv.RouteTags, h[HeaderServiceGroup], h[HeaderServiceVersion])
+	if port != "" {
+		h[XForwardedPort] = port
+	}
+	return nil
+}
+
+func handleResponse(w http.ResponseWriter, ir *invocation.Response) codes.Code {
+	if ir == nil {
+		return WriteErrorResponse(w, protocol.ErrUnExpectedHandlerChainResponse)
+	}
+	if ir.Err != nil {
+		return WriteErrorResponse(w, ir.Err)
+	}
+	resp, ok := ir.Result.(*http.Response)
+	if !ok || resp == nil || resp.StatusCode == 0 {
+		return WriteErrorResponse(w, ErrNilResponse)
+	}
+	return CopyResponse(w, resp)
+}
+
+//CopyResponse writes the response of unary or streaming call back,
+//every message is flushed at once and trailers of real service are kept,
+//it returns the gRPC status code of the call
+func CopyResponse(w http.ResponseWriter, resp *http.Response) codes.Code {
+	defer resp.Body.Close()
+	for k, vs := range resp.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if err := flushCopy(w, resp.Body); err != nil {
+		openlog.Error("can not copy resp: " + err.Error())
+	}
+	//trailers are available after body is read
+	for k, vs := range resp.Trailer {
+		for _, v := range vs {
+			w.Header().Add(http.TrailerPrefix+k, v)
+		}
+	}
+	s := resp.Trailer.Get(HeaderStatus)
+	if s == "" {
+		//trailers-only response
+		s = resp.Header.Get(HeaderStatus)
+	}
+	if s == "" {
+		if resp.StatusCode == http.StatusOK {
+			return codes.OK
+		}
+		return codes.Unknown
+	}
+	code, err := strconv.Atoi(s)
+	if err != nil {
+		return codes.Unknown
+	}
+	return codes.Code(code)
+}
+
+func flushCopy(w http.ResponseWriter, r io.Reader) error {
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+//WriteErrorResponse returns proxy errors as a trailers-only response, not err from real service
+func WriteErrorResponse(w http.ResponseWriter, err error) codes.Code {
+	stat, ok := status.FromError(err)
+	if !ok {
+		switch err.(type) {
+		case loadbalancer.LBError, hystrix.CircuitError:
+			stat = status.New(codes.Unavailable, err.Error())
+		default:
+			stat = status.New(codes.Unknown, err.Error())
+		}
+	}
+	openlog.Error(fmt.Sprintf("triple error: [%s]: [%s]", stat.Code().String(), stat.Message()))
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set(HeaderStatus, strconv.Itoa(int(stat.Code())))
+	if m := stat.Message(); m != "" {
+		w.Header().Set(HeaderMessage, m)
+	}
+	w.WriteHeader(http.StatusOK)
+	return stat.Code()
+}
+
+//RecordMetrics record metrics and access log of a call, a status code other than OK is a failure
+func RecordMetrics(inv *invocation.Invocation, r *http.Request, code codes.Code, latency time.Duration) {
+	labelValues := map[string]string{
+		metrics.LServiceName: inv.MicroServiceName,
+		metrics.LApp:         inv.RouteTags.AppID(),
+		metrics.LVersion:     inv.RouteTags.Version(),
+		metrics.LInterface:   inv.SchemaID,
+		metrics.LMethod:      inv.OperationID,
+		metrics.LStatus:      strconv.Itoa(int(code)),
+	}
+	metrics.RecordRPCLatency(Name, labelValues, latency.Seconds())
+	metrics.RecordRPCStatus(Name, labelValues, code != codes.OK)
+	accesslog.Log(&accesslog.Record{
+		Protocol:  Name,
+		Source:    inv.SourceMicroService,
+		Service:   inv.MicroServiceName,
+		Operation: inv.SchemaID + "." + inv.OperationID,
+		Status:    labelValues[metrics.LStatus],
+		Latency:   latency,
+		Tags: map[string]string{
+			"remote":  r.RemoteAddr,
+			"group":   r.Header.Get(HeaderServiceGroup),
+			"version": r.Header.Get(HeaderServiceVersion),
+		},
+	})
+}
+
+func prepareRequest(req *http.Request) {
+	if req.ContentLength == 0 {
+		req.Body = nil
+	}
+	req.RequestURI = "" // client is forbidden to set RequestURI
+	req.Close = false
+
+	req.Header.Del("Connection")
+}
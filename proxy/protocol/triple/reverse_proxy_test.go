@@ -0,0 +1,135 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package triple_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	dubboproxy "github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/proxy"
+	"github.com/apache/servicecomb-mesher/proxy/protocol/triple"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	handler.RegisterHandler("triple-echo", func() handler.Handler { return &echoHandler{} })
+}
+
+//echoHandler keeps the last invocation and streams request messages back like a real service
+type echoHandler struct{}
+
+var captured *invocation.Invocation
+
+func (h *echoHandler) Handle(chain *handler.Chain, inv *invocation.Invocation, cb invocation.ResponseCallBack) {
+	captured = inv
+	req := inv.Args.(*http.Request)
+	pr, pw := io.Pipe()
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/grpc"}},
+		Body:       pr,
+		Trailer:    http.Header{},
+	}
+	go func() {
+		if req.Body != nil {
+			io.Copy(pw, req.Body)
+		}
+		resp.Trailer.Set(triple.HeaderStatus, "0")
+		pw.Close()
+	}()
+	cb(&invocation.Response{Result: resp})
+}
+
+func (h *echoHandler) Name() string {
+	return "triple-echo"
+}
+
+//greeterDiscovery registers the interface Greeter in service greeter
+type greeterDiscovery struct{}
+
+func (d *greeterDiscovery) GetMicroServicesByInterface(interfaceName string) []*registry.MicroService {
+	if interfaceName != "org.apache.dubbo.Greeter" {
+		return nil
+	}
+	return []*registry.MicroService{{ServiceName: "greeter", Version: "1.0", AppID: "shop"}}
+}
+
+func (d *greeterDiscovery) GetSchemaContentByInterface(string) registry.SchemaContent {
+	return registry.SchemaContent{}
+}
+
+func (d *greeterDiscovery) GetSchemaContentByServiceName(string, string, string, string) []*registry.SchemaContent {
+	return nil
+}
+
+func (d *greeterDiscovery) Close() error { return nil }
+
+func TestLocalRequestHandler(t *testing.T) {
+	assert.NoError(t, archaius.Init(archaius.WithMemorySource()))
+	assert.NoError(t, archaius.Set(dubboproxy.KeyRouteByGroupVersion, true))
+	defer archaius.Delete(dubboproxy.KeyRouteByGroupVersion)
+	assert.NoError(t, handler.CreateChains(common.Consumer, map[string]string{"outgoing": "triple-echo"}))
+	defer func(d registry.ContractDiscovery) { registry.DefaultContractDiscoveryService = d }(registry.DefaultContractDiscoveryService)
+	registry.DefaultContractDiscoveryService = &greeterDiscovery{}
+
+	t.Run("unary call of registered interface", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:20000/org.apache.dubbo.Greeter/sayHello",
+			strings.NewReader("hello"))
+		r.Header.Set("Content-Type", "application/grpc")
+		r.Header.Set(triple.HeaderServiceGroup, "g1")
+		r.Header.Set(triple.HeaderServiceVersion, "1.2.0")
+		w := httptest.NewRecorder()
+		triple.LocalRequestHandler(w, r)
+
+		assert.Equal(t, "greeter", captured.MicroServiceName)
+		assert.Equal(t, "sayHello", captured.OperationID)
+		assert.Equal(t, "1.0", captured.RouteTags.Version())
+		assert.Equal(t, "shop", captured.RouteTags.AppID())
+		assert.Equal(t, "g1", captured.RouteTags.KV[dubboproxy.TagGroup])
+		assert.Equal(t, "1.2.0", captured.RouteTags.KV[dubboproxy.TagVersion])
+		assert.Equal(t, "hello", w.Body.String())
+		assert.Equal(t, "0", w.Result().Trailer.Get(triple.HeaderStatus))
+	})
+	t.Run("streaming call resolved by authority", func(t *testing.T) {
+		body, send := io.Pipe()
+		go func() {
+			send.Write([]byte("msg1"))
+			send.Write([]byte("msg2"))
+			send.Close()
+		}()
+		r := httptest.NewRequest(http.MethodPost, "http://store:20000/org.apache.dubbo.Store/watch", body)
+		r.Header.Set("Content-Type", "application/grpc")
+		r.Header.Set(triple.HeaderServiceGroup, "g2")
+		w := httptest.NewRecorder()
+		triple.LocalRequestHandler(w, r)
+
+		assert.Equal(t, "store", captured.MicroServiceName)
+		assert.Equal(t, "g2", captured.RouteTags.KV[dubboproxy.TagGroup])
+		assert.Equal(t, "msg1msg2", w.Body.String())
+		assert.True(t, w.Flushed)
+		assert.Equal(t, "0", w.Result().Trailer.Get(triple.HeaderStatus))
+	})
+}
@@ -0,0 +1,72 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+//Package triple supports Triple protocol of dubbo 3, it is compatible with gRPC,
+//so requests are proxied over the http2 transport of grpc protocol,
+//and Triple headers are treated as dubbo attachments
+package triple
+
+import (
+	"net/http"
+	"strings"
+)
+
+//Name is the protocol name of Triple
+const Name = "triple"
+
+//Triple headers
+const (
+	HeaderServiceVersion  = "tri-service-version"
+	HeaderServiceGroup    = "tri-service-group"
+	HeaderReqID           = "tri-req-id"
+	HeaderConsumerAppName = "tri-consumer-appname"
+)
+
+//reserved headers belong to http2 and gRPC, they are not attachments
+var reserved = map[string]bool{
+	"content-type":         true,
+	"content-length":       true,
+	"te":                   true,
+	"user-agent":           true,
+	"grpc-timeout":         true,
+	"grpc-encoding":        true,
+	"grpc-accept-encoding": true,
+}
+
+//ParsePath splits the request path "/{interface}/{method}" of a call
+func ParsePath(path string) (string, string, bool) {
+	path = strings.TrimPrefix(path, "/")
+	i := strings.LastIndex(path, "/")
+	if i <= 0 || i == len(path)-1 {
+		return "", "", false
+	}
+	return path[:i], path[i+1:], true
+}
+
+//Attachments returns attachments carried in headers, keys are in lower case like they are on the wire,
+//it includes Triple headers like group and version
+func Attachments(header http.Header) map[string]string {
+	m := make(map[string]string, len(header))
+	for k := range header {
+		key := strings.ToLower(k)
+		if reserved[key] {
+			continue
+		}
+		m[key] = header.Get(k)
+	}
+	return m
+}
@@ -0,0 +1,100 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package triple_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol/triple"
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestParsePath(t *testing.T) {
+	i, m, ok := triple.ParsePath("/org.apache.dubbo.Greeter/sayHello")
+	assert.True(t, ok)
+	assert.Equal(t, "org.apache.dubbo.Greeter", i)
+	assert.Equal(t, "sayHello", m)
+
+	for _, p := range []string{"", "/", "/Greeter", "/Greeter/", "//sayHello"} {
+		_, _, ok = triple.ParsePath(p)
+		assert.False(t, ok, p)
+	}
+}
+
+func TestAttachments(t *testing.T) {
+	h := http.Header{}
+	h.Set("Content-Type", "application/grpc+proto")
+	h.Set("TE", "trailers")
+	h.Set(triple.HeaderServiceGroup, "g1")
+	h.Set(triple.HeaderServiceVersion, "1.0.0")
+	h.Set("sw8", "trace")
+	a := triple.Attachments(h)
+	assert.Equal(t, map[string]string{
+		triple.HeaderServiceGroup:   "g1",
+		triple.HeaderServiceVersion: "1.0.0",
+		"sw8":                       "trace",
+	}, a)
+}
+
+func TestCopyResponse(t *testing.T) {
+	t.Run("trailers of service are kept", func(t *testing.T) {
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/grpc"}},
+			Body:       ioutil.NopCloser(strings.NewReader("message")),
+			Trailer:    http.Header{"Grpc-Status": []string{"5"}, "Grpc-Message": []string{"not found"}},
+		}
+		w := httptest.NewRecorder()
+		code := triple.CopyResponse(w, resp)
+		assert.Equal(t, codes.NotFound, code)
+		result := w.Result()
+		assert.Equal(t, "message", w.Body.String())
+		assert.Equal(t, "5", result.Trailer.Get("Grpc-Status"))
+		assert.Equal(t, "not found", result.Trailer.Get("Grpc-Message"))
+		assert.True(t, w.Flushed)
+	})
+	t.Run("trailers-only response", func(t *testing.T) {
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Grpc-Status": []string{"12"}},
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}
+		w := httptest.NewRecorder()
+		assert.Equal(t, codes.Unimplemented, triple.CopyResponse(w, resp))
+		assert.Equal(t, "12", w.Header().Get("Grpc-Status"))
+	})
+}
+
+func TestWriteErrorResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	code := triple.WriteErrorResponse(w, loadbalancer.LBError{Message: "no instance"})
+	assert.Equal(t, codes.Unavailable, code)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "14", w.Header().Get(triple.HeaderStatus))
+	assert.Equal(t, "lb: no instance", w.Header().Get(triple.HeaderMessage))
+
+	w = httptest.NewRecorder()
+	assert.Equal(t, codes.Unknown, triple.WriteErrorResponse(w, errors.New("x")))
+}
@@ -24,6 +24,7 @@ import (
 
 	"fmt"
 	"github.com/apache/servicecomb-mesher/proxy/config"
+	utiltags "github.com/go-chassis/go-chassis/v2/pkg/util/tags"
 	"github.com/go-chassis/openlog"
 )
 
@@ -46,6 +47,18 @@ type DestinationResolver interface {
 	Resolve(remoteIP, host, rawURI string, header map[string]string) (string, string, error)
 }
 
+//PathRewriter is implemented by destination resolvers which take service name from request path,
+//RewritePath returns the path sent to the service, it returns false if path is not changed
+type PathRewriter interface {
+	RewritePath(rawURI string) (string, bool)
+}
+
+//TagsResolver is implemented by destination resolvers which also decide route tags of target service,
+//like app and version, it returns false if there is no tag
+type TagsResolver interface {
+	ResolveTags(remoteIP, host, rawURI string, header map[string]string) (utiltags.Tags, bool)
+}
+
 //DefaultDestinationResolver is a struct
 //mesher as sidecar must use DefaultDestinationResolver
 type DefaultDestinationResolver struct {
@@ -115,5 +128,5 @@ func Init() error {
 		}
 	}
 
-	return nil
+	return initSourceResolver()
 }
@@ -0,0 +1,62 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package header
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/go-chassis/go-archaius"
+)
+
+//Name is the name of resolver plugin
+const Name = "header"
+
+//KeyHeader is the config key of the header which holds the target service
+const KeyHeader = "mesher.destinationResolver.header"
+
+//DefaultHeader is the default header which holds the target service
+const DefaultHeader = "X-Service-Name"
+
+//DestinationResolver reads target service from a header, the value can be service or service:port,
+//requests without the header are resolved by default resolver, so that http_proxy still works
+type DestinationResolver struct {
+	fallback resolver.DestinationResolver
+}
+
+//Resolve returns the service name and port in header
+func (dr *DestinationResolver) Resolve(remoteIP, host, rawURI string, header map[string]string) (string, string, error) {
+	name := http.CanonicalHeaderKey(archaius.GetString(KeyHeader, DefaultHeader))
+	v := header[name]
+	if v == "" {
+		return dr.fallback.Resolve(remoteIP, host, rawURI, header)
+	}
+	if service, port, err := net.SplitHostPort(v); err == nil {
+		return service, port, nil
+	}
+	return v, "", nil
+}
+
+//New returns a header destination resolver
+func New() resolver.DestinationResolver {
+	return &DestinationResolver{fallback: resolver.New()}
+}
+
+func init() {
+	resolver.InstallDestinationResolverPlugin(Name, New)
+}
@@ -0,0 +1,55 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package header_test
+
+import (
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/apache/servicecomb-mesher/proxy/resolver/header"
+	"github.com/go-chassis/go-archaius"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	d := resolver.DestinationResolverPlugins[header.Name]()
+
+	s, p, err := d.Resolve("127.0.0.1", "", "/api/v1", map[string]string{"X-Service-Name": "orders"})
+	assert.NoError(t, err)
+	assert.Equal(t, "orders", s)
+	assert.Equal(t, "", p)
+
+	s, p, err = d.Resolve("127.0.0.1", "", "/api/v1", map[string]string{"X-Service-Name": "orders:8080"})
+	assert.NoError(t, err)
+	assert.Equal(t, "orders", s)
+	assert.Equal(t, "8080", p)
+
+	//no header, same as http_proxy
+	s, p, err = d.Resolve("127.0.0.1", "", "http://users:9090/api", map[string]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "users", s)
+	assert.Equal(t, "9090", p)
+	_, _, err = d.Resolve("127.0.0.1", "", "/api", map[string]string{})
+	assert.Error(t, err)
+
+	archaius.Set(header.KeyHeader, "x-target")
+	defer archaius.Delete(header.KeyHeader)
+	s, _, err = d.Resolve("127.0.0.1", "", "/api", map[string]string{"X-Target": "carts"})
+	assert.NoError(t, err)
+	assert.Equal(t, "carts", s)
+}
@@ -0,0 +1,201 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+//Package kubernetes resolves kubernetes service host names and aliases to micro service names,
+//so that clients calling services by DNS names can move onto mesh without changing URLs
+package kubernetes
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-archaius/event"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	utiltags "github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/go-chassis/openlog"
+)
+
+//Name is the name of resolver plugin
+const Name = "kubernetes"
+
+//Config keys of kubernetes resolver, suffixes and namespaces are separated by comma
+const (
+	KeyDNSSuffixes = "mesher.destinationResolver.kubernetes.dnsSuffixes"
+	KeyNamespaces  = "mesher.destinationResolver.kubernetes.namespaces"
+	//KeyAliases holds a yaml map from host name to service, app and version
+	KeyAliases = "mesher.destinationResolver.aliases"
+)
+
+//DefaultDNSSuffixes are removed from host name if no suffix is set
+const DefaultDNSSuffixes = "svc.cluster.local,svc"
+
+var (
+	mu       sync.RWMutex
+	aliases  map[string]*config.Alias
+	initOnce sync.Once
+)
+
+//DestinationResolver resolves host name of request URI like host resolver, then
+//an alias of host name is used if there is one, otherwise DNS suffix and namespace are removed,
+//like orders.shop.svc.cluster.local to orders
+type DestinationResolver struct {
+	host resolver.DestinationResolver
+}
+
+//Resolve returns micro service name and port of request URI
+func (dr *DestinationResolver) Resolve(remoteIP, host, rawURI string, header map[string]string) (string, string, error) {
+	name, port, err := dr.host.Resolve(remoteIP, host, rawURI, header)
+	if err != nil {
+		return "", "", err
+	}
+	if a := getAlias(name); a != nil {
+		return a.Service, port, nil
+	}
+	return ServiceName(name), port, nil
+}
+
+//ResolveTags returns app and version of the alias of host name
+func (dr *DestinationResolver) ResolveTags(remoteIP, host, rawURI string, header map[string]string) (utiltags.Tags, bool) {
+	name, _, err := dr.host.Resolve(remoteIP, host, rawURI, header)
+	if err != nil {
+		return utiltags.Tags{}, false
+	}
+	a := getAlias(name)
+	if a == nil || (a.App == "" && a.Version == "") {
+		return utiltags.Tags{}, false
+	}
+	t := utiltags.Tags{KV: make(map[string]string)}
+	var labels []string
+	if a.App != "" {
+		t.KV[common.BuildinTagApp] = a.App
+		labels = append(labels, common.BuildinTagApp+":"+a.App)
+	}
+	if a.Version != "" {
+		t.KV[common.BuildinTagVersion] = a.Version
+		labels = append(labels, common.BuildinTagVersion+":"+a.Version)
+	}
+	t.Label = strings.Join(labels, "|")
+	return t, true
+}
+
+//ServiceName removes DNS suffix and namespace from a kubernetes host name,
+//host name having a DNS suffix must be service.namespace before the suffix,
+//host name without DNS suffix is service.namespace only if namespace is configured,
+//other host names, like external domains, are not changed
+func ServiceName(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, s := range split(archaius.GetString(KeyDNSSuffixes, DefaultDNSSuffixes)) {
+		if rest := strings.TrimSuffix(host, "."+s); rest != host {
+			if i := strings.Index(rest, "."); i != -1 {
+				return rest[:i]
+			}
+			return rest
+		}
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) != 2 {
+		return host
+	}
+	for _, ns := range split(archaius.GetString(KeyNamespaces, "")) {
+		if labels[1] == ns {
+			return labels[0]
+		}
+	}
+	return host
+}
+
+func split(s string) []string {
+	var r []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.Trim(strings.TrimSpace(v), ".")
+		if v != "" {
+			r = append(r, strings.ToLower(v))
+		}
+	}
+	return r
+}
+
+func getAlias(host string) *config.Alias {
+	mu.RLock()
+	defer mu.RUnlock()
+	a := aliases[strings.ToLower(host)]
+	if a == nil || a.Service == "" {
+		return nil
+	}
+	return a
+}
+
+//saveAliases updates aliases, aliases are not changed if raw is invalid
+func saveAliases(raw string) {
+	m, err := config.NewAliases(raw)
+	if err != nil {
+		openlog.Error("invalid aliases: " + err.Error())
+		return
+	}
+	lower := make(map[string]*config.Alias, len(m))
+	for h, a := range m {
+		if a != nil {
+			lower[strings.ToLower(h)] = a
+		}
+	}
+	mu.Lock()
+	aliases = lower
+	mu.Unlock()
+	openlog.Info("update destination aliases", openlog.WithTags(openlog.Tags{
+		"count": len(lower),
+	}))
+}
+
+type aliasEventListener struct{}
+
+//Event reloads aliases
+func (l *aliasEventListener) Event(e *event.Event) {
+	if e.EventType == common.Delete {
+		mu.Lock()
+		aliases = nil
+		mu.Unlock()
+		openlog.Info("destination aliases are removed")
+		return
+	}
+	raw, ok := e.Value.(string)
+	if !ok {
+		openlog.Error("invalid aliases, value must be yaml string")
+		return
+	}
+	saveAliases(raw)
+}
+
+//New returns a kubernetes destination resolver, aliases are loaded and watched once
+func New() resolver.DestinationResolver {
+	initOnce.Do(func() {
+		saveAliases(archaius.GetString(KeyAliases, ""))
+		if err := archaius.RegisterListener(&aliasEventListener{}, KeyAliases); err != nil {
+			openlog.Error("can not watch destination aliases: " + err.Error())
+		}
+	})
+	return &DestinationResolver{host: resolver.New()}
+}
+
+func init() {
+	resolver.InstallDestinationResolverPlugin(Name, New)
+}
@@ -0,0 +1,108 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/go-chassis/go-archaius"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceName(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	cases := map[string]string{
+		"orders.shop.svc.cluster.local":  "orders",
+		"orders.shop.svc.cluster.local.": "orders",
+		"Orders.Shop.svc":                "orders",
+		"orders.svc.cluster.local":       "orders",
+		"orders":                         "orders",
+		"orders.shop":                    "orders.shop",
+		"www.example.com":                "www.example.com",
+		"10.0.0.1":                       "10.0.0.1",
+	}
+	for host, service := range cases {
+		assert.Equal(t, service, ServiceName(host), host)
+	}
+
+	archaius.Set(KeyNamespaces, "shop, default")
+	archaius.Set(KeyDNSSuffixes, "svc.corp.local")
+	defer archaius.Delete(KeyNamespaces)
+	defer archaius.Delete(KeyDNSSuffixes)
+	assert.Equal(t, "orders", ServiceName("orders.shop"))
+	assert.Equal(t, "orders", ServiceName("orders.default"))
+	assert.Equal(t, "orders.other", ServiceName("orders.other"))
+	assert.Equal(t, "orders", ServiceName("orders.other.svc.corp.local"))
+	assert.Equal(t, "orders.shop.svc.cluster.local", ServiceName("orders.shop.svc.cluster.local"))
+}
+
+func TestResolve(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	archaius.Set(KeyAliases, `
+legacy-orders.example.com:
+  service: orders
+  app: shop
+  version: 1.0.0
+Users.Example.com:
+  service: users
+`)
+	defer archaius.Delete(KeyAliases)
+	dr := resolver.DestinationResolverPlugins[Name]()
+	tr := dr.(resolver.TagsResolver)
+
+	s, p, err := dr.Resolve("", "", "http://orders.shop.svc.cluster.local:8080/api", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "orders", s)
+	assert.Equal(t, "8080", p)
+	_, ok := tr.ResolveTags("", "", "http://orders.shop.svc.cluster.local:8080/api", nil)
+	assert.False(t, ok)
+
+	s, _, err = dr.Resolve("", "", "http://legacy-orders.example.com/api", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "orders", s)
+	tags, ok := tr.ResolveTags("", "", "http://legacy-orders.example.com/api", nil)
+	assert.True(t, ok)
+	assert.Equal(t, "shop", tags.AppID())
+	assert.Equal(t, "1.0.0", tags.Version())
+	assert.Equal(t, "app:shop|version:1.0.0", tags.String())
+
+	s, _, err = dr.Resolve("", "", "http://users.example.com/api", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "users", s)
+	_, ok = tr.ResolveTags("", "", "http://users.example.com/api", nil)
+	assert.False(t, ok)
+
+	_, _, err = dr.Resolve("", "", "/api", nil)
+	assert.Error(t, err)
+
+	//aliases are reloaded
+	archaius.Set(KeyAliases, `
+legacy-orders.example.com:
+  service: orders-v2
+`)
+	assert.Eventually(t, func() bool {
+		s, _, _ = dr.Resolve("", "", "http://legacy-orders.example.com/api", nil)
+		return s == "orders-v2"
+	}, 3*time.Second, 10*time.Millisecond)
+	//invalid aliases are ignored
+	archaius.Set(KeyAliases, "- a")
+	time.Sleep(50 * time.Millisecond)
+	s, _, _ = dr.Resolve("", "", "http://legacy-orders.example.com/api", nil)
+	assert.Equal(t, "orders-v2", s)
+}
@@ -0,0 +1,91 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+//Package pathprefix resolves target service from the first segment of request path
+package pathprefix
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+)
+
+//Name is the name of resolver plugin
+const Name = "path"
+
+//ErrNoService means request path has no segment for service name
+var ErrNoService = errors.New("can not resolve service from path, path must be like /serviceName/api")
+
+//DestinationResolver takes target service from the first path segment, like /orders/api/v1 to service orders,
+//the path sent to service is /api/v1. Request URI in absolute form comes from http_proxy,
+//it is resolved by default resolver and path is not changed
+type DestinationResolver struct {
+	fallback resolver.DestinationResolver
+}
+
+//Resolve returns the first segment of path as service name
+func (dr *DestinationResolver) Resolve(remoteIP, host, rawURI string, header map[string]string) (string, string, error) {
+	u, err := url.Parse(rawURI)
+	if err != nil {
+		return "", "", err
+	}
+	if isProxyRequest(u) {
+		return dr.fallback.Resolve(remoteIP, host, rawURI, header)
+	}
+	service, _ := split(u.Path)
+	if service == "" {
+		return "", "", ErrNoService
+	}
+	return service, "", nil
+}
+
+//RewritePath removes the service segment from path
+func (dr *DestinationResolver) RewritePath(rawURI string) (string, bool) {
+	u, err := url.Parse(rawURI)
+	if err != nil || isProxyRequest(u) {
+		return "", false
+	}
+	service, rest := split(u.Path)
+	if service == "" {
+		return "", false
+	}
+	return rest, true
+}
+
+func isProxyRequest(u *url.URL) bool {
+	return u.Host != "" && u.Host != resolver.SelfEndpoint
+}
+
+//split returns the first segment of path and the rest path
+func split(p string) (string, string) {
+	p = strings.TrimPrefix(p, "/")
+	i := strings.Index(p, "/")
+	if i == -1 {
+		return p, "/"
+	}
+	return p[:i], p[i:]
+}
+
+//New returns a path destination resolver
+func New() resolver.DestinationResolver {
+	return &DestinationResolver{fallback: resolver.New()}
+}
+
+func init() {
+	resolver.InstallDestinationResolverPlugin(Name, New)
+}
@@ -0,0 +1,58 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package pathprefix_test
+
+import (
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/apache/servicecomb-mesher/proxy/resolver/pathprefix"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	d := resolver.DestinationResolverPlugins[pathprefix.Name]()
+	pr := d.(resolver.PathRewriter)
+
+	s, p, err := d.Resolve("127.0.0.1", "", "/orders/api/v1?id=1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "orders", s)
+	assert.Equal(t, "", p)
+	path, ok := pr.RewritePath("/orders/api/v1?id=1")
+	assert.True(t, ok)
+	assert.Equal(t, "/api/v1", path)
+
+	s, _, err = d.Resolve("127.0.0.1", "", "/orders", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "orders", s)
+	path, ok = pr.RewritePath("/orders")
+	assert.True(t, ok)
+	assert.Equal(t, "/", path)
+
+	_, _, err = d.Resolve("127.0.0.1", "", "/", nil)
+	assert.Equal(t, pathprefix.ErrNoService, err)
+	_, ok = pr.RewritePath("/")
+	assert.False(t, ok)
+
+	//absolute form from http_proxy
+	s, p, err = d.Resolve("127.0.0.1", "", "http://users:9090/api", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "users", s)
+	assert.Equal(t, "9090", p)
+	_, ok = pr.RewritePath("http://users:9090/api")
+	assert.False(t, ok)
+}
@@ -0,0 +1,86 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+//Package peercert resolves the source service from the certificate of a mutual TLS peer
+package peercert
+
+import (
+	"crypto/x509"
+	"strings"
+
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+)
+
+//Name is the name of resolver plugin
+const Name = "cert"
+
+//SchemeSPIFFE is the uri scheme of SPIFFE ID
+const SchemeSPIFFE = "spiffe"
+
+//SourceResolver takes the service name from the peer certificate,
+//the SPIFFE ID in URI SAN is used first, like spiffe://cluster.local/ns/default/sa/orders,
+//then the first DNS SAN, then the common name.
+//only verified peer certificate is used, other requests are resolved by remote ip
+type SourceResolver struct {
+	fallback resolver.SourceResolver
+}
+
+//Resolve resolves source by remote ip
+func (r *SourceResolver) Resolve(source string) *registry.SourceInfo {
+	return r.fallback.Resolve(source)
+}
+
+//ResolveSource resolves source by peer certificate
+func (r *SourceResolver) ResolveSource(s *resolver.Source) *registry.SourceInfo {
+	//certificate is verified only when client auth is required
+	if s.TLS != nil && len(s.TLS.VerifiedChains) != 0 {
+		if name := ServiceName(s.TLS.VerifiedChains[0][0]); name != "" {
+			return &registry.SourceInfo{Name: name, Tags: map[string]string{}}
+		}
+	}
+	return r.fallback.Resolve(s.IP)
+}
+
+//ServiceName returns the service name in certificate, it returns empty string if nothing found
+func ServiceName(cert *x509.Certificate) string {
+	for _, u := range cert.URIs {
+		if u.Scheme != SchemeSPIFFE {
+			continue
+		}
+		path := strings.Trim(u.Path, "/")
+		if path == "" {
+			continue
+		}
+		return path[strings.LastIndex(path, "/")+1:]
+	}
+	for _, dns := range cert.DNSNames {
+		if name := strings.SplitN(dns, ".", 2)[0]; name != "" && name != "*" {
+			return name
+		}
+	}
+	return cert.Subject.CommonName
+}
+
+//New returns a peer certificate source resolver
+func New() resolver.SourceResolver {
+	return &SourceResolver{fallback: resolver.NewDefaultSourceResolver()}
+}
+
+func init() {
+	resolver.InstallSourceResolverPlugin(Name, New)
+}
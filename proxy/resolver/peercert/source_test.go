@@ -0,0 +1,66 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package peercert_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/apache/servicecomb-mesher/proxy/resolver/peercert"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceName(t *testing.T) {
+	id, _ := url.Parse("spiffe://cluster.local/ns/shop/sa/orders")
+	c := &x509.Certificate{
+		URIs:     []*url.URL{id},
+		DNSNames: []string{"payment.shop.svc"},
+		Subject:  pkix.Name{CommonName: "cart"},
+	}
+	assert.Equal(t, "orders", peercert.ServiceName(c))
+	c.URIs = nil
+	assert.Equal(t, "payment", peercert.ServiceName(c))
+	c.DNSNames = []string{"*.shop.svc"}
+	assert.Equal(t, "cart", peercert.ServiceName(c))
+	assert.Equal(t, "", peercert.ServiceName(&x509.Certificate{}))
+}
+
+func TestSourceResolver_ResolveSource(t *testing.T) {
+	registry.EnableRegistryCache()
+	r := peercert.New().(resolver.RequestSourceResolver)
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "cart"}}
+	s := &resolver.Source{
+		IP:  "127.0.0.1",
+		TLS: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+	}
+	//certificate is not verified
+	assert.Nil(t, r.ResolveSource(s))
+
+	s.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+	si := r.ResolveSource(s)
+	assert.NotNil(t, si)
+	assert.Equal(t, "cart", si.Name)
+
+	s.TLS = nil
+	assert.Nil(t, r.ResolveSource(s))
+}
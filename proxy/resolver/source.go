@@ -18,7 +18,15 @@
 package resolver
 
 import (
+	"crypto/tls"
 	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/go-chassis/foundation/stringutil"
 	"github.com/go-chassis/go-chassis/v2/core/registry"
 )
 
@@ -32,6 +40,28 @@ type SourceResolver interface {
 	Resolve(source string) *registry.SourceInfo
 }
 
+//Source describes the peer of a request
+type Source struct {
+	//IP is the remote ip of the connection
+	IP string
+	//Header is the request header, keys are canonical, it is nil for protocols without header
+	Header map[string]string
+	//TLS is the state of the connection, it is nil if the connection is not TLS
+	TLS *tls.ConnectionState
+}
+
+//RequestSourceResolver is implemented by source resolvers which need more than the remote ip,
+//like the peer certificate or a header
+type RequestSourceResolver interface {
+	ResolveSource(s *Source) *registry.SourceInfo
+}
+
+//DefaultSourcePlugin is the name of default source resolver plugin
+const DefaultSourcePlugin = "ip"
+
+//SourceResolverPlugins is a map
+var SourceResolverPlugins = make(map[string]func() SourceResolver)
+
 var sr SourceResolver = &DefaultSourceResolver{}
 
 //DefaultSourceResolver is a struct
@@ -48,7 +78,71 @@ func (sr *DefaultSourceResolver) Resolve(source string) *registry.SourceInfo {
 	return si
 }
 
+//NewDefaultSourceResolver returns a source resolver which looks up the ip index of registry
+func NewDefaultSourceResolver() SourceResolver {
+	return &DefaultSourceResolver{}
+}
+
 //GetSourceResolver returns interface object
 func GetSourceResolver() SourceResolver {
 	return sr
 }
+
+//SetSourceResolver replaces the source resolver
+func SetSourceResolver(r SourceResolver) {
+	sr = r
+}
+
+//ResolveSource resolves the source with the source resolver,
+//only remote ip is used if the resolver is not a RequestSourceResolver
+func ResolveSource(s *Source) *registry.SourceInfo {
+	if r, ok := sr.(RequestSourceResolver); ok {
+		return r.ResolveSource(s)
+	}
+	return sr.Resolve(s.IP)
+}
+
+//InstallSourceResolverPlugin function installs new plugin
+func InstallSourceResolverPlugin(name string, newFunc func() SourceResolver) {
+	SourceResolverPlugins[name] = newFunc
+	log.Printf("Installed SourceResolver Plugin, name=%s", name)
+}
+
+func init() {
+	InstallSourceResolverPlugin(DefaultSourcePlugin, NewDefaultSourceResolver)
+}
+
+func initSourceResolver() error {
+	name := DefaultSourcePlugin
+	if config.GetConfig().Plugin != nil && config.GetConfig().Plugin.SourceResolver != "" {
+		name = config.GetConfig().Plugin.SourceResolver
+	}
+	f, ok := SourceResolverPlugins[name]
+	if !ok {
+		return fmt.Errorf("unknown source resolver [%s]", name)
+	}
+	sr = f()
+	return nil
+}
+
+//ConnSource returns the source of a connection, the TLS handshake is completed if it is a TLS connection
+func ConnSource(conn net.Conn) *Source {
+	s := &Source{}
+	s.IP, _, _ = net.SplitHostPort(conn.RemoteAddr().String())
+	if c, ok := conn.(*tls.Conn); ok {
+		if err := c.Handshake(); err == nil {
+			state := c.ConnectionState()
+			s.TLS = &state
+		}
+	}
+	return s
+}
+
+//RequestSource returns the source of a http request
+func RequestSource(r *http.Request) *Source {
+	h := make(map[string]string, len(r.Header))
+	for k := range r.Header {
+		h[k] = r.Header.Get(k)
+	}
+	return &Source{IP: stringutil.SplitFirstSep(r.RemoteAddr, ":"), Header: h, TLS: r.TLS}
+}
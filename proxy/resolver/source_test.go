@@ -0,0 +1,57 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolver_test
+
+import (
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedSource struct{}
+
+func (f *fixedSource) Resolve(source string) *registry.SourceInfo {
+	return &registry.SourceInfo{Name: "ip"}
+}
+
+func (f *fixedSource) ResolveSource(s *resolver.Source) *registry.SourceInfo {
+	return &registry.SourceInfo{Name: s.Header["X-Name"]}
+}
+
+func TestInit_SourceResolver(t *testing.T) {
+	registry.EnableRegistryCache()
+	resolver.InstallSourceResolverPlugin("fixed", func() resolver.SourceResolver { return &fixedSource{} })
+	defer resolver.SetSourceResolver(resolver.NewDefaultSourceResolver())
+
+	config.SetConfig(&config.MesherConfig{Plugin: &config.Plugin{SourceResolver: "fixed"}})
+	assert.NoError(t, resolver.Init())
+	si := resolver.ResolveSource(&resolver.Source{IP: "10.0.0.1", Header: map[string]string{"X-Name": "cart"}})
+	assert.Equal(t, "cart", si.Name)
+
+	config.SetConfig(&config.MesherConfig{Plugin: &config.Plugin{SourceResolver: "none"}})
+	assert.Error(t, resolver.Init())
+
+	config.SetConfig(&config.MesherConfig{Plugin: &config.Plugin{}})
+	assert.NoError(t, resolver.Init())
+	_, ok := resolver.GetSourceResolver().(*resolver.DefaultSourceResolver)
+	assert.True(t, ok)
+	assert.Nil(t, resolver.ResolveSource(&resolver.Source{IP: "127.0.0.1"}))
+}
@@ -0,0 +1,107 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+//Package trustedheader resolves the source service from a header set by a trusted peer,
+//like a gateway or a mesher which has already authenticated the caller
+package trustedheader
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/go-chassis/openlog"
+)
+
+//Name is the name of resolver plugin
+const Name = "header"
+
+const (
+	//KeyHeader is the config key of the header which holds the source service
+	KeyHeader = "mesher.sourceResolver.header"
+	//KeyTrustedCIDRs is the config key of peers allowed to set the header, separated by comma
+	KeyTrustedCIDRs = "mesher.sourceResolver.trustedCIDRs"
+	//KeyTrustMTLS is the config key to trust peers which passed mutual TLS verification
+	KeyTrustMTLS = "mesher.sourceResolver.trustMTLS"
+)
+
+//DefaultHeader is the default header which holds the source service
+const DefaultHeader = "X-Source-Service"
+
+//SourceResolver reads source service from a header, the header is only trusted when
+//remote ip is in trusted CIDRs or the peer certificate is verified,
+//nobody is trusted by default. other requests are resolved by remote ip
+type SourceResolver struct {
+	fallback resolver.SourceResolver
+}
+
+//Resolve resolves source by remote ip
+func (r *SourceResolver) Resolve(source string) *registry.SourceInfo {
+	return r.fallback.Resolve(source)
+}
+
+//ResolveSource resolves source by header from trusted peer
+func (r *SourceResolver) ResolveSource(s *resolver.Source) *registry.SourceInfo {
+	name := s.Header[http.CanonicalHeaderKey(archaius.GetString(KeyHeader, DefaultHeader))]
+	if name != "" && trusted(s) {
+		return &registry.SourceInfo{Name: name, Tags: map[string]string{}}
+	}
+	return r.fallback.Resolve(s.IP)
+}
+
+func trusted(s *resolver.Source) bool {
+	if archaius.GetBool(KeyTrustMTLS, false) && s.TLS != nil && len(s.TLS.VerifiedChains) != 0 {
+		return true
+	}
+	ip := net.ParseIP(s.IP)
+	if ip == nil {
+		return false
+	}
+	for _, c := range strings.Split(archaius.GetString(KeyTrustedCIDRs, ""), ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !strings.Contains(c, "/") {
+			if t := net.ParseIP(c); t != nil && t.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			openlog.Warn("invalid trusted CIDR: " + c)
+			continue
+		}
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+//New returns a trusted header source resolver
+func New() resolver.SourceResolver {
+	return &SourceResolver{fallback: resolver.NewDefaultSourceResolver()}
+}
+
+func init() {
+	resolver.InstallSourceResolverPlugin(Name, New)
+}
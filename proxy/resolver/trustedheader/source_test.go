@@ -0,0 +1,72 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package trustedheader_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/resolver"
+	"github.com/apache/servicecomb-mesher/proxy/resolver/trustedheader"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceResolver_ResolveSource(t *testing.T) {
+	assert.NoError(t, archaius.Init(archaius.WithMemorySource()))
+	registry.EnableRegistryCache()
+	r := trustedheader.New().(resolver.RequestSourceResolver)
+	s := &resolver.Source{
+		IP:     "127.0.0.1",
+		Header: map[string]string{trustedheader.DefaultHeader: "cart"},
+	}
+	t.Run("nobody is trusted by default", func(t *testing.T) {
+		assert.Nil(t, r.ResolveSource(s))
+	})
+	t.Run("trusted cidr", func(t *testing.T) {
+		archaius.Set(trustedheader.KeyTrustedCIDRs, "10.0.0.0/8, 127.0.0.1")
+		defer archaius.Delete(trustedheader.KeyTrustedCIDRs)
+		si := r.ResolveSource(s)
+		assert.NotNil(t, si)
+		assert.Equal(t, "cart", si.Name)
+		assert.Nil(t, r.ResolveSource(&resolver.Source{IP: "192.168.0.1", Header: s.Header}))
+	})
+	t.Run("custom header", func(t *testing.T) {
+		archaius.Set(trustedheader.KeyTrustedCIDRs, "127.0.0.0/8")
+		archaius.Set(trustedheader.KeyHeader, "x-caller")
+		defer archaius.Delete(trustedheader.KeyTrustedCIDRs)
+		defer archaius.Delete(trustedheader.KeyHeader)
+		assert.Nil(t, r.ResolveSource(s))
+		si := r.ResolveSource(&resolver.Source{IP: "127.0.0.1", Header: map[string]string{"X-Caller": "orders"}})
+		assert.NotNil(t, si)
+		assert.Equal(t, "orders", si.Name)
+	})
+	t.Run("verified mtls peer", func(t *testing.T) {
+		archaius.Set(trustedheader.KeyTrustMTLS, true)
+		defer archaius.Delete(trustedheader.KeyTrustMTLS)
+		si := r.ResolveSource(&resolver.Source{
+			IP:     "192.168.0.1",
+			Header: s.Header,
+			TLS:    &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}},
+		})
+		assert.NotNil(t, si)
+		assert.Equal(t, "cart", si.Name)
+	})
+}
@@ -0,0 +1,97 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	"github.com/apache/servicecomb-mesher/proxy/handler/apikey"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/server/restful"
+	"github.com/go-chassis/openlog"
+)
+
+//APIKeyResource is rest api to manage api keys of consumers
+type APIKeyResource struct{}
+
+//List returns all keys, plain keys are not returned
+func (a *APIKeyResource) List(context *restful.Context) {
+	s, err := apikey.DefaultStore()
+	if err != nil {
+		writeJSON(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(context, http.StatusOK, s.List())
+}
+
+//Create saves a key, the plain key is only returned in response of this api
+func (a *APIKeyResource) Create(context *restful.Context) {
+	s, err := apikey.DefaultStore()
+	if err != nil {
+		writeJSON(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var e config.APIKeyEntry
+	if err := context.ReadEntity(&e); err != nil {
+		writeJSON(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	created, err := s.Create(e)
+	switch err {
+	case nil:
+		writeJSON(context, http.StatusCreated, created)
+	case apikey.ErrConflict:
+		writeJSON(context, http.StatusConflict, err.Error())
+	default:
+		writeJSON(context, http.StatusBadRequest, err.Error())
+	}
+}
+
+//Revoke removes a key
+func (a *APIKeyResource) Revoke(context *restful.Context) {
+	s, err := apikey.DefaultStore()
+	if err != nil {
+		writeJSON(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	switch err := s.Revoke(context.ReadPathParameter("id")); err {
+	case nil:
+		context.WriteHeader(http.StatusNoContent)
+	case apikey.ErrNotFound:
+		writeJSON(context, http.StatusNotFound, err.Error())
+	default:
+		writeJSON(context, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func writeJSON(context *restful.Context, status int, v interface{}) {
+	if err := context.WriteHeaderAndJSON(status, v, common.JSON); err != nil {
+		openlog.Error(fmt.Sprintf("Write HeaderAndJSON error %s: ", err.Error()))
+	}
+}
+
+//URLPatterns helps to respond for  Admin API calls
+func (a *APIKeyResource) URLPatterns() []restful.Route {
+	return []restful.Route{
+		{Method: http.MethodGet, Path: "/v1/mesher/apikeys", ResourceFuncName: "List"},
+		{Method: http.MethodPost, Path: "/v1/mesher/apikeys", ResourceFuncName: "Create"},
+		{Method: http.MethodDelete, Path: "/v1/mesher/apikeys/{id}", ResourceFuncName: "Revoke"},
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/config"
+	rf "github.com/emicklei/go-restful"
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/server/restful"
+	"github.com/stretchr/testify/assert"
+)
+
+func newContext(method, path, body string, params map[string]string) (*restful.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	resp := rf.NewResponse(w)
+	resp.SetRequestAccepts("application/json")
+	r := rf.NewRequest(req)
+	for k, v := range params {
+		r.PathParameters()[k] = v
+	}
+	return &restful.Context{Req: r, Resp: resp}, w
+}
+
+func TestAPIKeyResource(t *testing.T) {
+	assert.NoError(t, archaius.Init(archaius.WithMemorySource()))
+	r := &APIKeyResource{}
+	assert.Len(t, r.URLPatterns(), 3)
+
+	ctx, w := newContext(http.MethodPost, "/v1/mesher/apikeys", `{"consumer":"shop","quota":{"limit":10}}`, nil)
+	r.Create(ctx)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created config.APIKeyEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.ID)
+	assert.NotEmpty(t, created.Key)
+	assert.Equal(t, 10, created.Quota.Limit)
+
+	ctx, w = newContext(http.MethodPost, "/v1/mesher/apikeys", `{"id":"`+created.ID+`","consumer":"shop"}`, nil)
+	r.Create(ctx)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	ctx, w = newContext(http.MethodPost, "/v1/mesher/apikeys", `{}`, nil)
+	r.Create(ctx)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	ctx, w = newContext(http.MethodGet, "/v1/mesher/apikeys", "", nil)
+	r.List(ctx)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), created.Key)
+	var keys []config.APIKeyEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &keys))
+	assert.Equal(t, []config.APIKeyEntry{{ID: created.ID, Consumer: "shop", Quota: config.APIKeyQuota{Limit: 10}}}, keys)
+
+	ctx, w = newContext(http.MethodDelete, "/v1/mesher/apikeys/"+created.ID, "", map[string]string{"id": created.ID})
+	r.Revoke(ctx)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	ctx, w = newContext(http.MethodDelete, "/v1/mesher/apikeys/"+created.ID, "", map[string]string{"id": created.ID})
+	r.Revoke(ctx)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
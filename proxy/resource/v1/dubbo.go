@@ -0,0 +1,46 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/schema"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/server/restful"
+	"github.com/go-chassis/openlog"
+)
+
+//DubboResource is rest api to inspect dubbo proxy
+type DubboResource struct{}
+
+//Interfaces returns the interface to service table of dubbo
+func (a *DubboResource) Interfaces(context *restful.Context) {
+	err := context.WriteHeaderAndJSON(http.StatusOK, schema.InterfaceTable(), common.JSON)
+	if err != nil {
+		openlog.Error(fmt.Sprintf("Write HeaderAndJSON error %s: ", err.Error()))
+	}
+}
+
+//URLPatterns helps to respond for  Admin API calls
+func (a *DubboResource) URLPatterns() []restful.Route {
+	return []restful.Route{
+		{Method: http.MethodGet, Path: "/v1/mesher/dubbo/interfaces", ResourceFuncName: "Interfaces"},
+	}
+}
@@ -0,0 +1,46 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apache/servicecomb-mesher/proxy/protocol/dubbo/schema"
+	rf "github.com/emicklei/go-restful"
+	"github.com/go-chassis/go-chassis/v2/server/restful"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDubboResource_Interfaces(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/mesher/dubbo/interfaces", nil)
+	w := httptest.NewRecorder()
+	resp := rf.NewResponse(w)
+	resp.SetRequestAccepts("application/json")
+	ctx := &restful.Context{Req: rf.NewRequest(req), Resp: resp}
+
+	r := &DubboResource{}
+	assert.Len(t, r.URLPatterns(), 1)
+	r.Interfaces(ctx)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var table []schema.InterfaceEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &table))
+	assert.Equal(t, schema.InterfaceTable(), table)
+}
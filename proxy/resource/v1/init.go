@@ -27,6 +27,8 @@ import (
 func RegisterWebService() {
 	chassis.RegisterSchema("rest-admin", &RouteResource{})
 	chassis.RegisterSchema("rest-admin", &StatusResource{})
+	chassis.RegisterSchema("rest-admin", &DubboResource{})
+	chassis.RegisterSchema("rest-admin", &APIKeyResource{})
 }
 
 //Init function initiates admin API